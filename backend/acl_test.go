@@ -0,0 +1,130 @@
+package backend
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/anxiousmodernman/co-chair/proto/server"
+)
+
+func TestRouteAllowed(t *testing.T) {
+	cases := []struct {
+		allow, deny []string
+		ip          string
+		expected    bool
+	}{
+		{nil, nil, "203.0.113.7", true},
+		{[]string{"10.0.0.0/8"}, nil, "10.1.2.3", true},
+		{[]string{"10.0.0.0/8"}, nil, "203.0.113.7", false},
+		{[]string{"10.0.0.0/8"}, []string{"10.1.0.0/16"}, "10.1.2.3", false},
+		{nil, []string{"203.0.113.7"}, "203.0.113.7", false},
+		{nil, []string{"203.0.113.7"}, "203.0.113.8", true},
+		{[]string{"2001:db8::/32"}, nil, "2001:db8::1", true},
+		{[]string{"not a cidr"}, nil, "10.1.2.3", false},
+	}
+	for _, c := range cases {
+		r := compileRoute(BackendData{Domain: "admin", AllowCIDRs: c.allow, DenyCIDRs: c.deny})
+		if got := r.Allowed(net.ParseIP(c.ip)); got != c.expected {
+			t.Errorf("allow %v deny %v: %s allowed %v, expected %v", c.allow, c.deny, c.ip, got, c.expected)
+		}
+	}
+}
+
+func TestReadProxyHeader(t *testing.T) {
+	v2 := append([]byte{}, proxyV2Sig...)
+	v2 = append(v2, 0x21, 0x11, 0, 12) // PROXY, TCP over IPv4
+	v2 = append(v2, 192, 0, 2, 1, 198, 51, 100, 1, 0xdc, 0x04, 0x01, 0xbb)
+
+	cases := []struct {
+		name     string
+		input    []byte
+		expected string
+	}{
+		{"v1", []byte("PROXY TCP4 192.0.2.1 198.51.100.1 56324 443\r\nrest"), "192.0.2.1:56324"},
+		{"v1 unknown", []byte("PROXY UNKNOWN\r\nrest"), ""},
+		{"v2", append(v2, []byte("rest")...), "192.0.2.1:56324"},
+		{"none", []byte("rest"), ""},
+	}
+	for _, c := range cases {
+		r := bufio.NewReader(bytes.NewReader(c.input))
+		addr, err := readProxyHeader(r)
+		if err != nil {
+			t.Errorf("%s: %v", c.name, err)
+			continue
+		}
+		var got string
+		if addr != nil {
+			got = addr.String()
+		}
+		if got != c.expected {
+			t.Errorf("%s: got %q, expected %q", c.name, got, c.expected)
+		}
+		rest, _ := r.ReadString(0)
+		if rest != "rest" {
+			t.Errorf("%s: expected header to be consumed, %q left", c.name, rest)
+		}
+	}
+
+	r := bufio.NewReader(strings.NewReader("PROXY TCP4 garbage\r\n"))
+	if _, err := readProxyHeader(r); err == nil {
+		t.Errorf("expected error for malformed v1 header")
+	}
+}
+
+func TestKeepUnset(t *testing.T) {
+	bd := BackendData{Domain: "admin.example.com", MaxConns: 10,
+		AllowCIDRs: []string{"10.0.0.0/8"}, DenyCIDRs: []string{"10.9.0.0/16"}}
+	got := keepUnset(&server.Backend{Domain: "admin.example.com", DenyCidrs: []string{"10.8.0.0/16"}}, bd)
+	if !reflect.DeepEqual(got.AllowCidrs, bd.AllowCIDRs) || got.MaxConns != 10 {
+		t.Errorf("expected unset allow list and limit kept, got %v", got)
+	}
+	if want := []string{"10.8.0.0/16"}; !reflect.DeepEqual(got.DenyCidrs, want) {
+		t.Errorf("expected deny list %v as passed, got %v", want, got.DenyCidrs)
+	}
+}
+
+func TestPutKeepsACL(t *testing.T) {
+	p, cleanup, err := NewTestProxyCleanup()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cleanup()
+	b := &server.Backend{Domain: "admin.example.com", Ips: []string{"10.0.0.5:8443"},
+		AllowCidrs: []string{"10.0.0.0/8", "192.0.2.0/24"}}
+	if _, err := p.Put(context.TODO(), b); err != nil {
+		t.Fatal(err)
+	}
+	// A second put, like co-chair put without --allow.
+	b = &server.Backend{Domain: "admin.example.com", Ips: []string{"10.0.0.6:8443"}}
+	if _, err := p.Put(context.TODO(), b); err != nil {
+		t.Fatal(err)
+	}
+	var bd BackendData
+	if err := p.DB.One("Domain", "admin.example.com", &bd); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"10.0.0.0/8", "192.0.2.0/24"}; !reflect.DeepEqual(bd.AllowCIDRs, want) {
+		t.Errorf("expected allow list %v kept, got %v", want, bd.AllowCIDRs)
+	}
+	if r := compileRoute(bd); r.Allowed(net.ParseIP("203.0.113.7")) {
+		t.Error("expected clients outside the allow list refused after the second put")
+	}
+
+	// Patch can still clear it.
+	_, err = p.PatchBackend(context.TODO(), &server.PatchRequest{
+		Domain: "admin.example.com", UpdateMask: []string{"allow_cidrs"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := p.DB.One("Domain", "admin.example.com", &bd); err != nil {
+		t.Fatal(err)
+	}
+	if len(bd.AllowCIDRs) != 0 {
+		t.Errorf("expected patch to clear the allow list, got %v", bd.AllowCIDRs)
+	}
+}
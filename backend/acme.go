@@ -0,0 +1,181 @@
+package backend
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/anxiousmodernman/co-chair/proto/server"
+	"github.com/asdine/storm"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/crypto/acme"
+	"golang.org/x/crypto/acme/autocert"
+)
+
+// ACMEConfig configures certificate issuance over ACME.
+type ACMEConfig struct {
+	// DirectoryURL is the CA's directory. Blank means Let's Encrypt.
+	DirectoryURL string
+	// CACert is the path to a PEM bundle to trust for the directory's own
+	// TLS, e.g. the minica certificate of a local Pebble instance.
+	CACert string
+	// Email is our account's contact address, if any.
+	Email string
+	// RenewBefore is how long before expiry we renew. Zero means 30 days.
+	RenewBefore time.Duration
+}
+
+// ACME obtains and renews certificates for the Routes that ask for them.
+// Its account key and certificates are kept in bolt. TLS-ALPN-01 challenges
+// are answered by GetCertificate on the proxy port; HTTP-01 challenges by
+// HTTPHandler on the plaintext port.
+type ACME struct {
+	*autocert.Manager
+	routes *TableRouter
+	logger *logrus.Logger
+	stop   chan struct{}
+}
+
+// NewACME returns an ACME issuing for the ACME Routes in routes. It does not
+// contact the CA until a certificate is needed.
+func NewACME(db *storm.DB, routes *TableRouter, conf ACMEConfig, logger *logrus.Logger) (*ACME, error) {
+	a := &ACME{routes: routes, logger: logger, stop: make(chan struct{})}
+	client := &acme.Client{DirectoryURL: conf.DirectoryURL}
+	if conf.DirectoryURL == "" {
+		client.DirectoryURL = autocert.DefaultACMEDirectory
+	}
+	if conf.CACert != "" {
+		data, err := ioutil.ReadFile(conf.CACert)
+		if err != nil {
+			return nil, fmt.Errorf("acme ca cert: %v", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(data) {
+			return nil, fmt.Errorf("acme ca cert: no certificates in %s", conf.CACert)
+		}
+		client.HTTPClient = &http.Client{
+			Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: pool}},
+		}
+	}
+	a.Manager = &autocert.Manager{
+		Prompt:      autocert.AcceptTOS,
+		Cache:       &StormCache{DB: db},
+		HostPolicy:  a.hostPolicy,
+		RenewBefore: conf.RenewBefore,
+		Client:      client,
+		Email:       conf.Email,
+	}
+	return a, nil
+}
+
+// hostPolicy lets us issue only for domains routed with ACME set.
+func (a *ACME) hostPolicy(_ context.Context, host string) error {
+	route, err := a.routes.Route(host)
+	if err != nil {
+		return err
+	}
+	if !route.ACME || !strings.EqualFold(route.Domain, host) {
+		return fmt.Errorf("acme: %s is not configured for ACME", host)
+	}
+	return nil
+}
+
+// Start loads or obtains a certificate for every ACME Route now, and again
+// every interval to pick up new Routes. The Manager renews each certificate
+// it has loaded ahead of expiry.
+func (a *ACME) Start(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			a.warm()
+			select {
+			case <-a.stop:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Stop ends background issuance. Scheduled renewals keep running.
+func (a *ACME) Stop() {
+	close(a.stop)
+}
+
+func (a *ACME) warm() {
+	for _, r := range a.routes.Table().Routes() {
+		if !r.ACME {
+			continue
+		}
+		// Ask like a modern client would, so we get an ECDSA cert.
+		hello := &tls.ClientHelloInfo{
+			ServerName:       r.Domain,
+			CipherSuites:     []uint16{tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256},
+			SignatureSchemes: []tls.SignatureScheme{tls.ECDSAWithP256AndSHA256},
+			SupportedCurves:  []tls.CurveID{tls.CurveP256},
+		}
+		if _, err := a.GetCertificate(hello); err != nil {
+			a.logger.Errorf("acme: %s: %v", r.Domain, err)
+		}
+	}
+}
+
+// splitACMECert splits a certificate from autocert's cache, a private key
+// followed by the chain, into a cert and key.
+func splitACMECert(data []byte) *server.X509Cert {
+	var c server.X509Cert
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			return &c
+		}
+		if block.Type == "CERTIFICATE" {
+			c.Cert = append(c.Cert, pem.EncodeToMemory(block)...)
+		} else {
+			c.Key = append(c.Key, pem.EncodeToMemory(block)...)
+		}
+	}
+}
+
+// isACMEChallenge reports whether a handshake is a TLS-ALPN-01 challenge.
+func isACMEChallenge(hi *tls.ClientHelloInfo) bool {
+	return len(hi.SupportedProtos) == 1 && hi.SupportedProtos[0] == acme.ALPNProto
+}
+
+// StormCache is an autocert.Cache backed by bolt.
+type StormCache struct {
+	DB *storm.DB
+}
+
+const acmeBucket = "acme"
+
+// Get returns autocert.ErrCacheMiss for unknown keys.
+func (c *StormCache) Get(_ context.Context, key string) ([]byte, error) {
+	data, err := getSecret(c.DB, acmeBucket, key)
+	if err == storm.ErrNotFound {
+		return nil, autocert.ErrCacheMiss
+	}
+	return data, err
+}
+
+// Put ...
+func (c *StormCache) Put(_ context.Context, key string, data []byte) error {
+	return setSecret(c.DB, acmeBucket, key, data)
+}
+
+// Delete ...
+func (c *StormCache) Delete(_ context.Context, key string) error {
+	err := c.DB.Delete(acmeBucket, key)
+	if err == storm.ErrNotFound {
+		return nil
+	}
+	return err
+}
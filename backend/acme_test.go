@@ -0,0 +1,155 @@
+package backend
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"testing"
+
+	"github.com/anxiousmodernman/co-chair/proto/server"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/crypto/acme/autocert"
+)
+
+func TestStormCache(t *testing.T) {
+	p, cleanup, err := NewTestProxyCleanup()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cleanup()
+
+	c := &StormCache{DB: p.DB}
+	ctx := context.TODO()
+	if _, err := c.Get(ctx, "example.com"); err != autocert.ErrCacheMiss {
+		t.Errorf("expected ErrCacheMiss, got %v", err)
+	}
+	if err := c.Put(ctx, "example.com", []byte("pem")); err != nil {
+		t.Fatal(err)
+	}
+	data, err := c.Get(ctx, "example.com")
+	if err != nil || string(data) != "pem" {
+		t.Errorf("expected cached data, got %q %v", data, err)
+	}
+	if err := c.Delete(ctx, "example.com"); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Delete(ctx, "example.com"); err != nil {
+		t.Errorf("expected deleting a missing key to succeed, got %v", err)
+	}
+	if _, err := c.Get(ctx, "example.com"); err != autocert.ErrCacheMiss {
+		t.Errorf("expected ErrCacheMiss after delete, got %v", err)
+	}
+}
+
+func TestACMEHostPolicy(t *testing.T) {
+	p, cleanup, err := NewTestProxyCleanup()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cleanup()
+
+	for _, b := range []*server.Backend{
+		{Domain: "auto.example.com", Ips: []string{"127.0.0.1:9999"}, Acme: true},
+		{Domain: "manual.example.com", Ips: []string{"127.0.0.1:9999"}},
+	} {
+		if _, err := p.Put(context.TODO(), b); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := p.Put(context.TODO(), &server.Backend{Domain: "*.example.com", Acme: true}); err == nil {
+		t.Errorf("expected error putting a wildcard domain with acme")
+	}
+
+	a, err := NewACME(p.DB, p.Router(), ACMEConfig{}, logrus.New())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.TODO()
+	if err := a.hostPolicy(ctx, "auto.example.com"); err != nil {
+		t.Errorf("expected acme domain to be allowed: %v", err)
+	}
+	for _, host := range []string{"manual.example.com", "unknown.example.com"} {
+		if err := a.hostPolicy(ctx, host); err == nil {
+			t.Errorf("expected %s to be refused", host)
+		}
+	}
+}
+
+func TestRoutesShipACMECert(t *testing.T) {
+	p, cleanup, err := NewTestProxyCleanup()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cleanup()
+
+	const domain = "auto.example.com"
+	ctx := context.TODO()
+	if _, err := p.Put(ctx, &server.Backend{Domain: domain, Ips: []string{"127.0.0.1:9999"}, Acme: true}); err != nil {
+		t.Fatal(err)
+	}
+	rt, err := p.Routes(ctx, &server.RoutesRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c := rt.Backends[0].BackendCert; c != nil && len(c.Cert) > 0 {
+		t.Errorf("expected no cert before one is issued, got %s", c.Cert)
+	}
+
+	// autocert caches the key, then the chain.
+	pair := testRSACert(t, domain)
+	c := &StormCache{DB: p.DB}
+	if err := c.Put(ctx, domain, append(pair.Key, pair.Cert...)); err != nil {
+		t.Fatal(err)
+	}
+	rt, err = p.Routes(ctx, &server.RoutesRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := rt.Backends[0].BackendCert
+	if got == nil || !bytes.Equal(got.Cert, pair.Cert) || !bytes.Equal(got.Key, pair.Key) {
+		t.Fatalf("expected the cached cert and key, got %v", got)
+	}
+	if _, err := tls.X509KeyPair(got.Cert, got.Key); err != nil {
+		t.Errorf("expected a usable pair: %v", err)
+	}
+}
+
+func TestPutKeepsACME(t *testing.T) {
+	p, cleanup, err := NewTestProxyCleanup()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cleanup()
+
+	const domain = "auto.example.com"
+	ctx := context.TODO()
+	if _, err := p.Put(ctx, &server.Backend{Domain: domain, Ips: []string{"10.0.0.1:443"}, Acme: true}); err != nil {
+		t.Fatal(err)
+	}
+	// Adding an upstream, like co-chair put without --acme.
+	if _, err := p.Put(ctx, &server.Backend{Domain: domain, Ips: []string{"10.0.0.2:443"}}); err != nil {
+		t.Fatal(err)
+	}
+	var bd BackendData
+	if err := p.DB.One("Domain", domain, &bd); err != nil {
+		t.Fatal(err)
+	}
+	if !bd.ACME || bd.CAIssued || len(bd.BackendCert) > 0 {
+		t.Errorf("expected ACME kept and no CA cert, got acme=%v caIssued=%v", bd.ACME, bd.CAIssued)
+	}
+	if len(bd.IPs) != 2 {
+		t.Errorf("expected both upstreams, got %v", bd.IPs)
+	}
+
+	// Patch can still turn it off.
+	_, err = p.PatchBackend(ctx, &server.PatchRequest{Domain: domain, UpdateMask: []string{"acme"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := p.DB.One("Domain", domain, &bd); err != nil {
+		t.Fatal(err)
+	}
+	if bd.ACME {
+		t.Error("expected patch to turn ACME off")
+	}
+}
@@ -0,0 +1,116 @@
+package backend
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/anxiousmodernman/co-chair/proto/server"
+)
+
+// Apply sets our routing configuration to the backends in req, in one
+// transaction. Listed backends replace what we have for their domains,
+// IPs included. Unlisted backends are kept, unless req.Prune is set.
+// Principals limited to some domains only prune those.
+func (p *Proxy) Apply(ctx context.Context, req *server.ApplyRequest) (*server.ApplyResult, error) {
+	var fe fieldErrors
+	listed := make(map[string]bool)
+	for i, b := range req.Backends {
+		prefix := fmt.Sprintf("backends[%d].", i)
+		fe = append(fe, validateBackend(prefix, b)...)
+		fe.checkIPs(prefix+"ips", b.Ips)
+		if listed[b.Domain] {
+			fe.add(prefix+"domain", fmt.Errorf("%s is listed more than once", b.Domain))
+		}
+		listed[b.Domain] = true
+	}
+	if err := fe.err(); err != nil {
+		return nil, err
+	}
+
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
+	var current []BackendData
+	if err := p.DB.All(&current); err != nil {
+		return nil, fmt.Errorf("db error: %v", err)
+	}
+	existing := make(map[string]BackendData)
+	for _, bd := range current {
+		existing[bd.Domain] = bd
+	}
+
+	var next []BackendData
+	for _, b := range req.Backends {
+		bd := existing[b.Domain]
+		bd.IPs = b.Ips
+		updateBackend(&bd, b)
+		if !req.DryRun {
+			if err := p.issueCACert(&bd); err != nil {
+				return nil, fmt.Errorf("%s: %v", b.Domain, err)
+			}
+		}
+		next = append(next, bd)
+	}
+	pr, _ := PrincipalFrom(ctx)
+	var removed []BackendData
+	for _, bd := range current {
+		if listed[bd.Domain] {
+			continue
+		}
+		if req.Prune && pr.inScope(bd.Domain) {
+			removed = append(removed, bd)
+			continue
+		}
+		next = append(next, bd)
+	}
+
+	diff := diffConfigs(current, next)
+	result := &server.ApplyResult{Changes: diff.Domains}
+	if req.DryRun || len(diff.Domains) == 0 {
+		return result, nil
+	}
+	changed := make(map[string]bool)
+	for _, dd := range diff.Domains {
+		changed[dd.Domain] = true
+	}
+
+	tx, err := p.DB.Begin(true)
+	if err != nil {
+		return nil, fmt.Errorf("db error: %v", err)
+	}
+	defer tx.Rollback()
+	for i := range next {
+		bd := &next[i]
+		if !changed[bd.Domain] {
+			continue
+		}
+		bd.Revision++
+		if err := tx.Save(bd); err != nil {
+			return nil, fmt.Errorf("save %s: %v", bd.Domain, err)
+		}
+	}
+	for _, bd := range removed {
+		if err := tx.DeleteStruct(&bd); err != nil {
+			return nil, fmt.Errorf("delete %s: %v", bd.Domain, err)
+		}
+	}
+	var events []AuditEvent
+	for _, dd := range diff.Domains {
+		e, err := p.audit(ctx, tx, "Apply", dd.Domain, fieldChanges(dd))
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, e)
+	}
+	rev, err := p.saveRevision(ctx, tx, "Apply", fmt.Sprintf("%d domains", len(diff.Domains)))
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("commit: %v", err)
+	}
+	p.routes.Swap(NewTable(next))
+	p.mirror(events...)
+	result.Revision = int64(rev)
+	return result, nil
+}
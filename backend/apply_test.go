@@ -0,0 +1,76 @@
+package backend
+
+import (
+	"context"
+	"testing"
+
+	"github.com/anxiousmodernman/co-chair/proto/server"
+)
+
+func TestApply(t *testing.T) {
+	p, cleanup, err := NewTestProxyCleanup()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cleanup()
+	for _, domain := range []string{"a.example.com", "b.example.com"} {
+		b := &server.Backend{Domain: domain, Ips: []string{"10.0.0.1:443"}}
+		if _, err := p.Put(context.TODO(), b); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	req := &server.ApplyRequest{
+		Backends: []*server.Backend{
+			{Domain: "a.example.com", Ips: []string{"10.0.0.2:443"}},
+			{Domain: "c.example.com", Ips: []string{"10.0.0.3:443"}},
+		},
+		Prune:  true,
+		DryRun: true,
+	}
+	result, err := p.Apply(context.TODO(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Changes) != 3 || result.Revision != 0 {
+		t.Fatalf("expected a, b and c to change, and no revision; got %v", result)
+	}
+	var bd BackendData
+	if err := p.DB.One("Domain", "c.example.com", &bd); err == nil {
+		t.Fatalf("expected a dry run to commit nothing")
+	}
+
+	req.DryRun = false
+	if result, err = p.Apply(context.TODO(), req); err != nil {
+		t.Fatal(err)
+	}
+	if result.Revision == 0 {
+		t.Errorf("expected a revision")
+	}
+	var backends []BackendData
+	if err := p.DB.All(&backends); err != nil {
+		t.Fatal(err)
+	}
+	if len(backends) != 2 {
+		t.Errorf("expected b to be pruned, got %d backends", len(backends))
+	}
+	if err := p.DB.One("Domain", "a.example.com", &bd); err != nil {
+		t.Fatal(err)
+	}
+	if len(bd.IPs) != 1 || bd.IPs[0] != "10.0.0.2:443" {
+		t.Errorf("expected a's IPs to be replaced, got %v", bd.IPs)
+	}
+	if _, err := p.routes.Route("b.example.com"); err != ErrNoRoute {
+		t.Errorf("expected no route to b, got %v", err)
+	}
+
+	// Applying the same routes again changes nothing.
+	if result, err = p.Apply(context.TODO(), req); err != nil || len(result.Changes) != 0 {
+		t.Errorf("expected no changes, got %v: %v", result, err)
+	}
+
+	req.Backends = append(req.Backends, &server.Backend{Domain: "c.example.com"})
+	if _, err := p.Apply(context.TODO(), req); err == nil {
+		t.Errorf("expected a domain listed twice to fail")
+	}
+}
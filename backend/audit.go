@@ -0,0 +1,286 @@
+package backend
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/anxiousmodernman/co-chair/proto/server"
+	"github.com/asdine/storm"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc/peer"
+)
+
+// AuditEvent is a change to our configuration. Events are only ever
+// appended.
+type AuditEvent struct {
+	ID      int `storm:"id,increment"`
+	Time    time.Time
+	Actor   string
+	Role    Role
+	Method  string
+	Target  string
+	Source  string
+	Changes []FieldChange
+}
+
+// FieldChange is one field's value before and after a change.
+type FieldChange struct {
+	Field, Before, After string
+}
+
+// AuditLog mirrors audit events to a writer, one JSON object per line.
+// Events are mirrored once they are saved in the DB, so failed writes are
+// only logged.
+type AuditLog struct {
+	mtx    sync.Mutex
+	w      io.Writer
+	logger *logrus.Logger
+}
+
+// NewAuditLog returns an AuditLog that writes to w.
+func NewAuditLog(w io.Writer, logger *logrus.Logger) *AuditLog {
+	return &AuditLog{w: w, logger: logger}
+}
+
+func (l *AuditLog) write(e AuditEvent) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	_, err = l.w.Write(append(data, '\n'))
+	return err
+}
+
+// audit records a change made by the caller in ctx. tx is the transaction
+// that makes the change, so the two commit together or not at all. Pass
+// the event to mirror once tx commits.
+func (p *Proxy) audit(ctx context.Context, tx storm.Node, method, target string, changes []FieldChange) (AuditEvent, error) {
+	e := AuditEvent{Time: time.Now(), Method: method, Target: target, Changes: changes}
+	e.Actor, e.Role = actor(ctx)
+	if pr, ok := peer.FromContext(ctx); ok && pr.Addr != nil {
+		e.Source = pr.Addr.String()
+	}
+	if err := tx.Save(&e); err != nil {
+		return e, fmt.Errorf("audit: %v", err)
+	}
+	return e, nil
+}
+
+// mirror writes committed audit events to our AuditLog, if we have one.
+func (p *Proxy) mirror(events ...AuditEvent) {
+	if p.AuditLog == nil {
+		return
+	}
+	for _, e := range events {
+		if err := p.AuditLog.write(e); err != nil && p.AuditLog.logger != nil {
+			p.AuditLog.logger.Errorf("audit log: event %d not mirrored: %v", e.ID, err)
+		}
+	}
+}
+
+// commitBackend saves bd, or deletes before if bd is nil, and records the
+// change in the audit log and our history, in one transaction. Callers
+// hold p.mtx, and swap in the new route once it returns.
+func (p *Proxy) commitBackend(ctx context.Context, method string, before, bd *BackendData) error {
+	tx, err := p.DB.Begin(true)
+	if err != nil {
+		return fmt.Errorf("db error: %v", err)
+	}
+	defer tx.Rollback()
+	target := before
+	if bd != nil {
+		target = bd
+		if err := tx.Save(bd); err != nil {
+			return fmt.Errorf("save: %v", err)
+		}
+	} else if err := tx.DeleteStruct(before); err != nil {
+		return fmt.Errorf("delete: %v", err)
+	}
+	e, err := p.audit(ctx, tx, method, target.Domain, diffBackends(before, bd))
+	if err != nil {
+		return err
+	}
+	if _, err := p.saveRevision(ctx, tx, method, target.Domain); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit: %v", err)
+	}
+	p.mirror(e)
+	return nil
+}
+
+// auditOne makes a change with save and records it, in one transaction.
+func (p *Proxy) auditOne(ctx context.Context, method, target string, save func(tx storm.Node) ([]FieldChange, error)) error {
+	tx, err := p.DB.Begin(true)
+	if err != nil {
+		return fmt.Errorf("db error: %v", err)
+	}
+	defer tx.Rollback()
+	changes, err := save(tx)
+	if err != nil {
+		return err
+	}
+	e, err := p.audit(ctx, tx, method, target, changes)
+	if err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit: %v", err)
+	}
+	p.mirror(e)
+	return nil
+}
+
+// actor names the caller in ctx, for the audit log and history.
+func actor(ctx context.Context) (string, Role) {
+	pr, ok := PrincipalFrom(ctx)
+	if !ok || pr.Name == "" {
+		return "anonymous", pr.Role
+	}
+	return pr.Name, pr.Role
+}
+
+// ListAuditEvents returns audit events, newest first.
+func (p *Proxy) ListAuditEvents(_ context.Context, req *server.AuditRequest) (*server.AuditEventList, error) {
+	limit := int(req.Limit)
+	if limit <= 0 {
+		limit = 100
+	}
+	var events []AuditEvent
+	err := p.DB.Select(auditFilter{req}).OrderBy("ID").Reverse().Limit(limit).Find(&events)
+	if err != nil && err != storm.ErrNotFound {
+		return nil, fmt.Errorf("db error: %v", err)
+	}
+	var list server.AuditEventList
+	for _, e := range events {
+		ae := &server.AuditEvent{
+			Id:     int64(e.ID),
+			Time:   e.Time.Unix(),
+			Actor:  e.Actor,
+			Role:   string(e.Role),
+			Method: e.Method,
+			Target: e.Target,
+			Source: e.Source,
+		}
+		for _, c := range e.Changes {
+			ae.Changes = append(ae.Changes, &server.FieldChange{Field: c.Field, Before: c.Before, After: c.After})
+		}
+		list.Events = append(list.Events, ae)
+	}
+	return &list, nil
+}
+
+// auditFilter is a storm q.Matcher for an AuditRequest.
+type auditFilter struct {
+	req *server.AuditRequest
+}
+
+func (f auditFilter) Match(i interface{}) (bool, error) {
+	var e AuditEvent
+	switch v := i.(type) {
+	case *AuditEvent:
+		e = *v
+	case AuditEvent:
+		e = v
+	default:
+		return false, fmt.Errorf("not an audit event: %T", i)
+	}
+	if f.req.Target != "" && e.Target != f.req.Target {
+		return false, nil
+	}
+	if f.req.Actor != "" && e.Actor != f.req.Actor {
+		return false, nil
+	}
+	return e.Time.Unix() >= f.req.Since, nil
+}
+
+// secretFields of BackendData hold private keys. We show only whether
+// they changed.
+var secretFields = map[string]bool{"BackendKey": true}
+
+// diffBackends lists the fields that differ between before and after,
+// either of which may be nil.
+func diffBackends(before, after *BackendData) []FieldChange {
+	var changes []FieldChange
+	t := reflect.TypeOf(BackendData{})
+	for i := 0; i < t.NumField(); i++ {
+		name := t.Field(i).Name
+		if name == "ID" || name == "Revision" {
+			continue
+		}
+		b, a := fieldValue(before, i), fieldValue(after, i)
+		if reflect.DeepEqual(b, a) {
+			continue
+		}
+		c := FieldChange{Field: name}
+		if secretFields[name] {
+			c.Before, c.After = redact(b), redact(a)
+		} else {
+			c.Before, c.After = describeField(b), describeField(a)
+		}
+		changes = append(changes, c)
+	}
+	return changes
+}
+
+// fieldValue is field i of bd, or nil if it is unset.
+func fieldValue(bd *BackendData, i int) interface{} {
+	if bd == nil {
+		return nil
+	}
+	v := reflect.ValueOf(*bd).Field(i)
+	if isEmpty(v) || reflect.DeepEqual(v.Interface(), reflect.Zero(v.Type()).Interface()) {
+		return nil
+	}
+	return v.Interface()
+}
+
+func isEmpty(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Slice, reflect.Map:
+		return v.Len() == 0
+	}
+	return false
+}
+
+func redact(v interface{}) string {
+	if v == nil {
+		return ""
+	}
+	return "[redacted]"
+}
+
+// describeField shows certificates by fingerprint, and keys not at all.
+func describeField(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case []byte:
+		return shortHash(v)
+	case []CertPair:
+		var certs []string
+		for _, c := range v {
+			certs = append(certs, shortHash(c.Cert))
+		}
+		return "[" + strings.Join(certs, " ") + "]"
+	}
+	return fmt.Sprint(v)
+}
+
+func shortHash(data []byte) string {
+	if len(bytes.TrimSpace(data)) == 0 {
+		return ""
+	}
+	return fmt.Sprintf("sha256:%x", sha256.Sum256(data))[:23]
+}
@@ -0,0 +1,84 @@
+package backend
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/anxiousmodernman/co-chair/proto/server"
+	"github.com/sirupsen/logrus"
+)
+
+func TestDiffBackends(t *testing.T) {
+	before := &BackendData{Domain: "www.example.com", IPs: []string{"10.0.0.1:443"},
+		BackendCert: []byte("cert"), BackendKey: []byte("secret key")}
+	after := *before
+	after.IPs = []string{"10.0.0.1:443", "10.0.0.2:443"}
+	after.BackendKey = []byte("another secret key")
+
+	changes := diffBackends(before, &after)
+	fields := make(map[string]FieldChange)
+	for _, c := range changes {
+		fields[c.Field] = c
+	}
+	if len(changes) != 2 {
+		t.Fatalf("expected IPs and BackendKey to change, got %v", changes)
+	}
+	if c := fields["IPs"]; c.Before != "[10.0.0.1:443]" || c.After != "[10.0.0.1:443 10.0.0.2:443]" {
+		t.Errorf("unexpected IPs change: %v", c)
+	}
+	if c := fields["BackendKey"]; strings.Contains(c.Before+c.After, "secret") {
+		t.Errorf("expected the private key to be redacted, got %v", c)
+	}
+
+	// A new backend shows every field that is set.
+	if changes := diffBackends(nil, before); len(changes) != 4 {
+		t.Errorf("expected 4 fields set, got %v", changes)
+	}
+}
+
+func TestAudit(t *testing.T) {
+	p, cleanup, err := NewTestProxyCleanup()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cleanup()
+	var mirror bytes.Buffer
+	p.AuditLog = NewAuditLog(&mirror, logrus.New())
+
+	ctx := context.WithValue(context.TODO(), principalKey{}, Principal{Name: "deploy", Role: RoleOperator})
+	b := &server.Backend{Domain: "www.example.com", Ips: []string{"10.0.0.1:443"}}
+	if _, err := p.Put(ctx, b); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.Remove(context.TODO(), b); err != nil {
+		t.Fatal(err)
+	}
+
+	list, err := p.ListAuditEvents(context.TODO(), &server.AuditRequest{Target: "www.example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list.Events) != 2 {
+		t.Fatalf("expected 2 events, got %d", len(list.Events))
+	}
+	if e := list.Events[0]; e.Method != "Remove" || e.Actor != "anonymous" {
+		t.Errorf("expected the anonymous Remove first, got %v", e)
+	}
+	if e := list.Events[1]; e.Method != "Put" || e.Actor != "deploy" || e.Role != "operator" {
+		t.Errorf("expected the operator's Put, got %v", e)
+	}
+
+	list, _ = p.ListAuditEvents(context.TODO(), &server.AuditRequest{Actor: "deploy"})
+	if len(list.Events) != 1 {
+		t.Errorf("expected 1 event by deploy, got %d", len(list.Events))
+	}
+
+	var e AuditEvent
+	line := strings.SplitN(mirror.String(), "\n", 2)[0]
+	if err := json.Unmarshal([]byte(line), &e); err != nil || e.Method != "Put" {
+		t.Errorf("expected the Put mirrored as JSON, got %q: %v", line, err)
+	}
+}
@@ -2,36 +2,44 @@ package backend
 
 import (
 	"context"
-	"crypto/tls"
-	"crypto/x509"
 	"fmt"
 	"io"
 	"sort"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/Rudd-O/curvetls"
-	"github.com/cloudflare/cfssl/cli/genkey"
-	"github.com/cloudflare/cfssl/csr"
-	"github.com/cloudflare/cfssl/helpers"
-	"github.com/cloudflare/cfssl/helpers/derhelpers"
-	"github.com/cloudflare/cfssl/initca"
-	"github.com/cloudflare/cfssl/signer"
-	"github.com/cloudflare/cfssl/signer/local"
 
 	"github.com/anxiousmodernman/co-chair/proto/server"
 	"github.com/asdine/storm"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 // Proxy is our server.ProxyServer implementation.
 type Proxy struct {
 	DB  *storm.DB
 	mtx *sync.Mutex
+	// routes is our compiled routing Table, kept in sync with the
+	// database by every write.
+	routes *TableRouter
+	// CA issues certs for domains that have none uploaded.
+	CA *CA
+	// TicketRotation is how often we make a new session ticket key.
+	// Zero means DefaultTicketRotation.
+	TicketRotation time.Duration
+	// AuditLog, if set, mirrors the audit events we keep in the DB.
+	AuditLog *AuditLog
+	// RevisionsKept is how many revisions of our routing configuration we
+	// keep. Zero means DefaultRevisionsKept.
+	RevisionsKept int
 }
 
 // NewProxy is our constructor for the server.ProxyServer implementation.
-func NewProxy(path string) (*Proxy, error) {
-	db, err := storm.Open(path)
+// If masterKey is set, secrets in the DB at path are encrypted with it.
+func NewProxy(path string, masterKey []byte) (*Proxy, error) {
+	db, err := OpenDB(path, masterKey)
 	if err != nil {
 		return nil, err
 	}
@@ -54,36 +62,56 @@ func NewProxy(path string) (*Proxy, error) {
 		}
 	}
 
-	return &Proxy{db, &sync.Mutex{}}, nil
-}
-
-// assert that Proxy is a server.ProxyServer at compile time.
-var _ server.ProxyServer = (*Proxy)(nil)
-
-// State returns the state of the proxy. The number of backends returned is
-// controlled by the domain field of the request. A blank domain returns all.
-func (p *Proxy) State(_ context.Context, req *server.StateRequest) (*server.ProxyState, error) {
-	var resp server.ProxyState
-	var backends []*BackendData
-	var err error
-	if req.Domain == "" {
-		err = p.DB.All(&backends)
-	} else {
-		err = p.DB.Find("Domain", req.Domain, &backends)
+	var backends []BackendData
+	if err := db.All(&backends); err != nil {
+		return nil, err
 	}
+
+	ca, err := LoadCA(db)
 	if err != nil {
-		return nil, fmt.Errorf("domain: %s; db error: %v", req.Domain, err)
+		return nil, err
 	}
-	for _, b := range backends {
-		// do not leak private keys here
-		resp.Backends = append(resp.Backends, b.AsBackend())
+
+	// Configurations from before we kept history get a first revision,
+	// so there is something to roll back to.
+	n, err := db.Count(&Revision{})
+	if err != nil && err != storm.ErrNotFound {
+		return nil, err
+	}
+	if n == 0 && len(backends) > 0 {
+		rev := Revision{Time: time.Now(), Actor: "co-chair", Method: "Baseline", Backends: backends}
+		if err := db.Save(&rev); err != nil {
+			return nil, err
+		}
 	}
 
-	return &resp, nil
+	return &Proxy{
+		DB:     db,
+		mtx:    &sync.Mutex{},
+		routes: NewTableRouter(NewTable(backends)),
+		CA:     ca,
+	}, nil
 }
 
+// Router returns a Router that serves from memory. Every Put and Remove
+// swaps in a new Table, so lookups never touch the database.
+func (p *Proxy) Router() *TableRouter {
+	return p.routes
+}
+
+// assert that Proxy is a server.ProxyServer at compile time.
+var _ server.ProxyServer = (*Proxy)(nil)
+
 // Put adds a backend to our pool of proxied Backends.
 func (p *Proxy) Put(ctx context.Context, b *server.Backend) (*server.OpResult, error) {
+	fe := validateBackend("", b)
+	fe.checkIPs("ips", b.Ips)
+	if err := fe.err(); err != nil {
+		return nil, err
+	}
+
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
 
 	var bd BackendData
 	err := p.DB.One("Domain", b.Domain, &bd)
@@ -91,73 +119,126 @@ func (p *Proxy) Put(ctx context.Context, b *server.Backend) (*server.OpResult, e
 	if err != nil && err != storm.ErrNotFound {
 		return &server.OpResult{}, fmt.Errorf("domain lookup: %v", err)
 	}
+	var before *BackendData
+	if err == nil {
+		old := bd
+		before = &old
+	}
+	if err := checkRevision(bd, b.Revision); err != nil {
+		return nil, err
+	}
 
-	bd.Domain = b.Domain
 	bd.IPs = combine(bd.IPs, b.Ips)
+	updateBackend(&bd, keepUnset(b, bd))
+	if err := p.issueCACert(&bd); err != nil {
+		return nil, err
+	}
+
+	bd.Revision++
+	if err := p.commitBackend(ctx, "Put", before, &bd); err != nil {
+		return nil, err
+	}
+	p.routes.Swap(p.routes.Table().With(bd))
+
+	resp := &server.OpResult{Code: 200, Status: "Ok"}
+
+	return resp, nil
+}
+
+// keepUnset is b, with the limits, CIDRs, headers, labels and ACME it
+// leaves unset taken from bd. Put only changes what it is passed, so a Put
+// that forgets --allow does not open a domain up, and one that forgets
+// --acme does not swap out its certificate; PatchBackend clears them.
+func keepUnset(b *server.Backend, bd BackendData) *server.Backend {
+	kept := *b
+	if len(kept.MatchHeaders) == 0 {
+		kept.MatchHeaders = bd.MatchHeaders
+	}
+	if kept.MaxConns == 0 {
+		kept.MaxConns = int32(bd.MaxConns)
+	}
+	if kept.ClientRate == 0 {
+		kept.ClientRate = bd.ClientRate
+	}
+	if kept.ClientBurst == 0 {
+		kept.ClientBurst = int32(bd.ClientBurst)
+	}
+	if len(kept.AllowCidrs) == 0 {
+		kept.AllowCidrs = bd.AllowCIDRs
+	}
+	if len(kept.DenyCidrs) == 0 {
+		kept.DenyCidrs = bd.DenyCIDRs
+	}
+	if len(kept.Labels) == 0 {
+		kept.Labels = bd.Labels
+	}
+	if !kept.Acme {
+		kept.Acme = bd.ACME
+	}
+	return &kept
+}
+
+// updateBackend sets the fields of b on bd, except IPs, which Put adds to
+// and Apply replaces. Callers check b with validateBackend first.
+func updateBackend(bd *BackendData, b *server.Backend) {
+	bd.Domain = b.Domain
 	bd.Protocol = b.Protocol
 	bd.MatchHeaders = b.MatchHeaders
+	bd.MaxConns = int(b.MaxConns)
+	bd.ClientRate = b.ClientRate
+	bd.ClientBurst = int(b.ClientBurst)
+	bd.AllowCIDRs = b.AllowCidrs
+	bd.DenyCIDRs = b.DenyCidrs
+	bd.ACME = b.Acme
+	bd.Labels = b.Labels
+
+	// Like certs, client auth is only changed if passed. An empty CA
+	// bundle turns it off.
+	if ca := b.ClientAuth; ca != nil {
+		bd.ClientCAs = ca.CaBundle
+		bd.ClientNames = ca.AllowedNames
+		bd.ForwardIdentity = ca.Forward
+	}
+
+	// TLS policy, too, is only changed if passed.
+	if pol := b.TlsPolicy; pol != nil {
+		bd.TLSMinVersion = pol.MinVersion
+		bd.TLSCipherSuites = pol.CipherSuites
+		bd.TLSCurves = pol.Curves
+		bd.HSTSMaxAge = pol.HstsMaxAge
+		bd.HSTSIncludeSubdomains = pol.HstsIncludeSubdomains
+	}
 
 	if b.BackendCert != nil {
 		bd.BackendCert = b.BackendCert.Cert
 		bd.BackendKey = b.BackendCert.Key
-	}
-
-	// Possible feature: generate BackendCerts if blank?
-	if false {
-		// generate cert
-		c := csr.New()
-		rootCACert, csrPEM, rootCAKey, err := initca.New(c)
-		if err != nil {
-			return nil, err
-		}
-		_ = csrPEM
-		crt, err := tls.X509KeyPair(rootCAKey, rootCACert)
-		if err != nil {
-			return nil, err
+		bd.ExtraCerts = nil
+		for _, c := range b.ExtraCerts {
+			bd.ExtraCerts = append(bd.ExtraCerts, CertPair{Cert: c.Cert, Key: c.Key})
 		}
+		bd.CAIssued = false
+	}
+}
 
-		var password string // blank for now
-		derredUp, err := helpers.GetKeyDERFromPEM(rootCAKey, []byte(password))
+// issueCACert gives bd a cert from our CA if it has none of its own, or
+// renews the one it has as it nears expiry.
+func (p *Proxy) issueCACert(bd *BackendData) error {
+	if !bd.ACME && p.CA != nil && (len(bd.BackendCert) == 0 || bd.CAIssued && caCertExpiring(bd.BackendCert)) {
+		var err error
+		bd.BackendCert, bd.BackendKey, err = p.CA.IssueServer(bd.Domain)
 		if err != nil {
-			return nil, err
-		}
-		priv, err := derhelpers.ParsePrivateKeyDER(derredUp)
-		if err != nil {
-			return nil, err
-		}
-		signr, err := local.NewSigner(priv, crt.Leaf, x509.ECDSAWithSHA512, nil)
-		if err != nil {
-			return nil, err
-		}
-		// our actual server cert and key?
-		req := csr.CertificateRequest{KeyRequest: csr.NewBasicKeyRequest()}
-		var key, csrBytes []byte
-		g := &csr.Generator{Validator: genkey.Validator}
-		csrBytes, key, err = g.ProcessRequest(&req)
-
-		var signReq signer.SignRequest
-		signReq.Hosts = []string{b.Domain}
-		signReq.Request = string(csrBytes)
-		newCert, err := signr.Sign(signReq)
-		if err != nil {
-			return nil, err
+			return err
 		}
-		_, _ = newCert, key
+		bd.CAIssued = true
 	}
-
-	err = p.DB.Save(&bd)
-	if err != nil {
-		return nil, fmt.Errorf("save: %v", err)
-	}
-
-	resp := &server.OpResult{Code: 200, Status: "Ok"}
-
-	return resp, nil
+	return nil
 }
 
 // PutKVStream lets us stream key-value pairs into our db.
 func (p *Proxy) PutKVStream(stream server.Proxy_PutKVStreamServer) error {
 
+	// Take the whole stream first, to write it in one transaction.
+	var kvs []*server.KV
 	for {
 		kv, err := stream.Recv()
 		if err != nil {
@@ -166,11 +247,18 @@ func (p *Proxy) PutKVStream(stream server.Proxy_PutKVStreamServer) error {
 			}
 			return err
 		}
-		if err := p.DB.SetBytes("streams", kv.Key, kv.Value); err != nil {
-			return fmt.Errorf("SetBytes: %v", err)
-		}
+		kvs = append(kvs, kv)
 	}
-	return nil
+	return p.auditOne(stream.Context(), "PutKVStream", "streams", func(tx storm.Node) ([]FieldChange, error) {
+		var changes []FieldChange
+		for _, kv := range kvs {
+			if err := tx.SetBytes("streams", kv.Key, kv.Value); err != nil {
+				return nil, fmt.Errorf("SetBytes: %v", err)
+			}
+			changes = append(changes, FieldChange{Field: string(kv.Key), After: fmt.Sprintf("%d bytes", len(kv.Value))})
+		}
+		return changes, nil
+	})
 }
 
 // GetKVStream scans a keyspace.
@@ -198,6 +286,146 @@ func (p *Proxy) GetKVStream(key *server.Key, stream server.Proxy_GetKVStreamServ
 	return nil
 }
 
+// Routes returns every backend, including TLS certificates and keys. This is
+// how forwarders running only the data plane sync their routing tables.
+// ACME domains get the certificate we obtained for them, as forwarders do
+// not run ACME themselves.
+func (p *Proxy) Routes(_ context.Context, _ *server.RoutesRequest) (*server.RouteTable, error) {
+	var backends []*BackendData
+	if err := p.DB.All(&backends); err != nil {
+		return nil, fmt.Errorf("db error: %v", err)
+	}
+	var rt server.RouteTable
+	for _, b := range backends {
+		route := b.AsRoute()
+		if b.ACME {
+			// Not issued yet; forwarders pick it up on a later sync.
+			if data, err := getSecret(p.DB, acmeBucket, b.Domain); err == nil {
+				route.BackendCert = splitACMECert(data)
+			}
+		}
+		rt.Backends = append(rt.Backends, route)
+	}
+	return &rt, nil
+}
+
+// GetCA returns our internal CA's bundle and revocation list.
+func (p *Proxy) GetCA(_ context.Context, _ *server.CARequest) (*server.CAInfo, error) {
+	crl, err := p.CA.CRL()
+	if err != nil {
+		return nil, err
+	}
+	return &server.CAInfo{Bundle: p.CA.Bundle(), Crl: crl}, nil
+}
+
+// IssueClientCert issues a client certificate from our internal CA, for
+// backends or clients that authenticate with mTLS.
+func (p *Proxy) IssueClientCert(ctx context.Context, req *server.IssueRequest) (*server.X509Cert, error) {
+	if req.Name == "" {
+		return nil, invalid("name", "is required")
+	}
+	var cert, key []byte
+	err := p.auditOne(ctx, "IssueClientCert", req.Name, func(tx storm.Node) ([]FieldChange, error) {
+		var err error
+		if cert, key, err = p.CA.issueClient(tx, req.Name); err != nil {
+			return nil, err
+		}
+		return []FieldChange{{Field: "Cert", After: shortHash(cert)}}, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &server.X509Cert{Cert: cert, Key: key}, nil
+}
+
+// Revoke revokes a certificate issued by our internal CA.
+func (p *Proxy) Revoke(ctx context.Context, req *server.RevokeRequest) (*server.OpResult, error) {
+	err := p.auditOne(ctx, "Revoke", req.Serial, func(tx storm.Node) ([]FieldChange, error) {
+		return nil, p.CA.revoke(tx, req.Serial)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &server.OpResult{Code: 200, Status: "Ok"}, nil
+}
+
+// StageCertificates stores certificates for a domain without serving them.
+// PromoteCertificates swaps them in.
+func (p *Proxy) StageCertificates(ctx context.Context, req *server.StageRequest) (*server.OpResult, error) {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
+	if len(req.Certs) == 0 {
+		return nil, invalid("certs", "no certificates to stage")
+	}
+	var bd BackendData
+	if err := p.DB.One("Domain", req.Domain, &bd); err != nil {
+		if err == storm.ErrNotFound {
+			return nil, status.Errorf(codes.NotFound, "domain not found: %s", req.Domain)
+		}
+		return nil, fmt.Errorf("db error: %v", err)
+	}
+	staged, err := certPairs(req.Domain, req.Certs)
+	if err != nil {
+		return nil, err
+	}
+	before := bd
+	bd.StagedCerts = staged
+	bd.Revision++
+	if err := p.commitBackend(ctx, "StageCertificates", &before, &bd); err != nil {
+		return nil, err
+	}
+	return &server.OpResult{Code: 200, Status: "Ok"}, nil
+}
+
+// PromoteCertificates makes a domain's staged certificates the ones we
+// serve. The route is swapped in one step, so no handshake sees a mix of
+// old and new.
+func (p *Proxy) PromoteCertificates(ctx context.Context, req *server.PromoteRequest) (*server.OpResult, error) {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
+	var bd BackendData
+	if err := p.DB.One("Domain", req.Domain, &bd); err != nil {
+		if err == storm.ErrNotFound {
+			return nil, status.Errorf(codes.NotFound, "domain not found: %s", req.Domain)
+		}
+		return nil, fmt.Errorf("db error: %v", err)
+	}
+	if len(bd.StagedCerts) == 0 {
+		return nil, status.Errorf(codes.FailedPrecondition, "no staged certificates for %s", req.Domain)
+	}
+	before := bd
+	bd.BackendCert = bd.StagedCerts[0].Cert
+	bd.BackendKey = bd.StagedCerts[0].Key
+	bd.ExtraCerts = bd.StagedCerts[1:]
+	bd.StagedCerts = nil
+	bd.CAIssued = false
+	bd.ACME = false
+	bd.Revision++
+	if err := p.commitBackend(ctx, "PromoteCertificates", &before, &bd); err != nil {
+		return nil, err
+	}
+	p.routes.Swap(p.routes.Table().With(bd))
+	return &server.OpResult{Code: 200, Status: "Ok"}, nil
+}
+
+// certPairs validates certs for domain.
+func certPairs(domain string, certs []*server.X509Cert) ([]CertPair, error) {
+	var pairs []CertPair
+	var fe fieldErrors
+	for i, c := range certs {
+		if err := validateCert(domain, c.Cert, c.Key); err != nil {
+			fe.add(fmt.Sprintf("certs[%d]", i), err)
+		}
+		pairs = append(pairs, CertPair{Cert: c.Cert, Key: c.Key})
+	}
+	if err := fe.err(); err != nil {
+		return nil, err
+	}
+	return pairs, nil
+}
+
 func combine(a, b []string) []string {
 	// let's pre-allocate enough space
 	both := make([]string, 0, len(a)+len(b))
@@ -216,16 +444,26 @@ func combine(a, b []string) []string {
 	return res
 }
 
-// Remove ...
-func (p *Proxy) Remove(_ context.Context, b *server.Backend) (*server.OpResult, error) {
+// Remove stops routing a domain and deletes its backend.
+func (p *Proxy) Remove(ctx context.Context, b *server.Backend) (*server.OpResult, error) {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
+	if b.Domain == "" {
+		return nil, invalid("domain", "is required")
+	}
 	// match on domain name exactly
 	var bd BackendData
 	if err := p.DB.One("Domain", b.Domain, &bd); err != nil {
-		return nil, err
+		if err == storm.ErrNotFound {
+			return nil, status.Errorf(codes.NotFound, "domain not found: %s", b.Domain)
+		}
+		return nil, fmt.Errorf("db error: %v", err)
 	}
-	if err := p.DB.DeleteStruct(&bd); err != nil {
+	if err := p.commitBackend(ctx, "Remove", &bd, nil); err != nil {
 		return nil, err
 	}
+	p.routes.Swap(p.routes.Table().Without(bd.Domain))
 
 	res := &server.OpResult{Code: 200, Status: fmt.Sprintf("removed: %s", bd.Domain)}
 	return res, nil
@@ -240,7 +478,10 @@ func (p *Proxy) Remove(_ context.Context, b *server.Backend) (*server.OpResult,
 type BackendData struct {
 	ID     int    `storm:"id,increment"`
 	Domain string `storm:"unique"`
-	IPs    []string
+	// Revision counts changes to this backend. Callers may pass the
+	// revision they read to Put or PatchBackend, to fail if it changed.
+	Revision int64
+	IPs      []string
 	// An optional endpoint we can call, expecting HTTP 200
 	HealthCheck string
 	// one of HTTP1, HTTP2, GRPC
@@ -250,17 +491,133 @@ type BackendData struct {
 	// Headers to match on during backend selection when we first
 	// get a connection.
 	MatchHeaders map[string]string
+	// Connection limits; zero values mean no limit. MaxConns caps
+	// concurrent connections. ClientRate is new connections per second
+	// per client IP, with bursts of up to ClientBurst.
+	MaxConns    int
+	ClientRate  float64
+	ClientBurst int
+	// Client address ranges. If AllowCIDRs is set, only clients in it may
+	// connect. Clients in DenyCIDRs never may.
+	AllowCIDRs []string
+	DenyCIDRs  []string
+	// If ClientCAs is set, clients must present a certificate that chains
+	// to it and, if ClientNames is set, names one of them.
+	// ForwardIdentity is how we pass the client's identity upstream.
+	ClientCAs       []byte
+	ClientNames     []string
+	ForwardIdentity server.ClientAuth_Forward
+	// If ACME is set, our certificate comes from an ACME CA instead of
+	// BackendCert. CAIssued is set if BackendCert is from our own CA.
+	ACME     bool
+	CAIssued bool
+	// ExtraCerts are served alongside BackendCert, to clients that do not
+	// support it. StagedCerts replace both when promoted.
+	ExtraCerts  []CertPair
+	StagedCerts []CertPair
+	// TLS policy for clients; empty fields use Go's defaults. If
+	// HSTSMaxAge is set, HTTP1 responses get a Strict-Transport-Security
+	// header.
+	TLSMinVersion         string
+	TLSCipherSuites       []string
+	TLSCurves             []string
+	HSTSMaxAge            int64
+	HSTSIncludeSubdomains bool
+	// Labels are free-form tags for finding backends with State.
+	Labels map[string]string
+}
+
+// CertPair is a PEM-encoded certificate and private key.
+type CertPair struct {
+	Cert, Key []byte
 }
 
 // AsBackend is a conversion method to a grpc-sendable type.
 func (bd BackendData) AsBackend() *server.Backend {
 	var b server.Backend
 	b.Domain = bd.Domain
+	b.Revision = bd.Revision
 	b.Ips = bd.IPs
 	b.Protocol = bd.Protocol
+	b.MaxConns = int32(bd.MaxConns)
+	b.ClientRate = bd.ClientRate
+	b.ClientBurst = int32(bd.ClientBurst)
+	b.AllowCidrs = bd.AllowCIDRs
+	b.DenyCidrs = bd.DenyCIDRs
+	b.Acme = bd.ACME
+	b.Labels = bd.Labels
+	if len(bd.ClientCAs) > 0 {
+		b.ClientAuth = &server.ClientAuth{
+			CaBundle:     bd.ClientCAs,
+			AllowedNames: bd.ClientNames,
+			Forward:      bd.ForwardIdentity,
+		}
+	}
+	if bd.TLSMinVersion != "" || len(bd.TLSCipherSuites) > 0 || len(bd.TLSCurves) > 0 || bd.HSTSMaxAge > 0 {
+		b.TlsPolicy = &server.TLSPolicy{
+			MinVersion:            bd.TLSMinVersion,
+			CipherSuites:          bd.TLSCipherSuites,
+			Curves:                bd.TLSCurves,
+			HstsMaxAge:            bd.HSTSMaxAge,
+			HstsIncludeSubdomains: bd.HSTSIncludeSubdomains,
+		}
+	}
 	return &b
 }
 
+// AsRoute is like AsBackend, but includes everything a forwarder needs to
+// serve the backend, TLS certificate and private key included.
+func (bd BackendData) AsRoute() *server.Backend {
+	b := bd.AsBackend()
+	b.HealthCheck = bd.HealthCheck
+	b.MatchHeaders = bd.MatchHeaders
+	if bd.BackendCert != nil {
+		b.BackendCert = &server.X509Cert{Cert: bd.BackendCert, Key: bd.BackendKey}
+	}
+	for _, c := range bd.ExtraCerts {
+		b.ExtraCerts = append(b.ExtraCerts, &server.X509Cert{Cert: c.Cert, Key: c.Key})
+	}
+	return b
+}
+
+// BackendDataFromRoute is the inverse of AsRoute.
+func BackendDataFromRoute(b *server.Backend) BackendData {
+	bd := BackendData{
+		Domain:       b.Domain,
+		IPs:          b.Ips,
+		HealthCheck:  b.HealthCheck,
+		Protocol:     b.Protocol,
+		MatchHeaders: b.MatchHeaders,
+		MaxConns:     int(b.MaxConns),
+		ClientRate:   b.ClientRate,
+		ClientBurst:  int(b.ClientBurst),
+		AllowCIDRs:   b.AllowCidrs,
+		DenyCIDRs:    b.DenyCidrs,
+		ACME:         b.Acme,
+		Labels:       b.Labels,
+	}
+	if b.BackendCert != nil {
+		bd.BackendCert = b.BackendCert.Cert
+		bd.BackendKey = b.BackendCert.Key
+	}
+	for _, c := range b.ExtraCerts {
+		bd.ExtraCerts = append(bd.ExtraCerts, CertPair{Cert: c.Cert, Key: c.Key})
+	}
+	if ca := b.ClientAuth; ca != nil {
+		bd.ClientCAs = ca.CaBundle
+		bd.ClientNames = ca.AllowedNames
+		bd.ForwardIdentity = ca.Forward
+	}
+	if pol := b.TlsPolicy; pol != nil {
+		bd.TLSMinVersion = pol.MinVersion
+		bd.TLSCipherSuites = pol.CipherSuites
+		bd.TLSCurves = pol.Curves
+		bd.HSTSMaxAge = pol.HstsMaxAge
+		bd.HSTSIncludeSubdomains = pol.HstsIncludeSubdomains
+	}
+	return bd
+}
+
 // KeyPair is a database type that represents curvetls key pairs. A KeyPair
 // must be in the database for each pure grpc client that wants to connect.
 // Not used for grpc websocket clients. We rely on HTTPS for those.
@@ -270,11 +627,18 @@ type KeyPair struct {
 	// for servers or clients.
 	Pub  string
 	Priv string
+	// Created and Revoked are set for client keys. A client whose key is
+	// revoked cannot connect.
+	Created time.Time
+	Revoked time.Time
+	// Role and Domains limit what a client may do; see Authorizer.
+	Role    Role
+	Domains []string
 }
 
 // RetrieveServerKeys gets the curvetls public key for our co-chair instance's api.
 // Clients will need to know the server's public key.
-func RetrieveServerKeys(db *storm.DB) (curvetls.Pubkey, curvetls.Privkey, error) {
+func RetrieveServerKeys(db storm.Node) (curvetls.Pubkey, curvetls.Privkey, error) {
 	var kp KeyPair
 	err := db.One("Name", "server", &kp)
 	if err != nil {
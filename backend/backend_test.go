@@ -41,7 +41,7 @@ func TestProxy(t *testing.T) {
 	// test proxy stuff
 	var b server.Backend
 	b.Domain = "harrington.io"
-	b.Ips = []string{"127.0.0.2"}
+	b.Ips = []string{"127.0.0.2:443"}
 	p.Put(ctx, &b)
 
 	var sr server.StateRequest
@@ -74,7 +74,7 @@ func NewTestProxyCleanup() (*Proxy, func(), error) {
 		return nil, nil, err
 	}
 
-	p, err := NewProxy(filepath.Join(dir, "co-chair-test.db"))
+	p, err := NewProxy(filepath.Join(dir, "co-chair-test.db"), nil)
 	if err != nil {
 		return nil, nil, err
 	}
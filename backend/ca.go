@@ -0,0 +1,435 @@
+package backend
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/asdine/storm"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Lifetimes of the certificates our CA creates.
+const (
+	caRootLifetime         = 10 * 365 * 24 * time.Hour
+	caIntermediateLifetime = 5 * 365 * 24 * time.Hour
+	caLeafLifetime         = 365 * 24 * time.Hour
+	// caRenewBefore is how close to expiry our own certs are reissued.
+	caRenewBefore = 30 * 24 * time.Hour
+)
+
+// CAKeyPair is a database type for the PEM-encoded certificates and keys of
+// our CA. Name is one of "root", "intermediate", or "proxy-client". An
+// imported CA has no root key.
+type CAKeyPair struct {
+	Name string `storm:"id"`
+	Cert []byte
+	Key  []byte
+}
+
+// IssuedCert is a database record of every certificate our CA has issued.
+type IssuedCert struct {
+	Serial    string `storm:"id"`
+	Name      string `storm:"index"`
+	Client    bool
+	NotAfter  time.Time
+	Revoked   bool
+	RevokedAt time.Time
+}
+
+// CA is our internal certificate authority. It issues from an intermediate,
+// so the root key can be kept offline by importing an intermediate instead.
+type CA struct {
+	db *storm.DB
+
+	mtx    sync.Mutex
+	cert   *x509.Certificate
+	key    crypto.Signer
+	bundle []byte
+}
+
+// LoadCA loads our CA from db, creating a root and an intermediate if there
+// is none yet.
+func LoadCA(db *storm.DB) (*CA, error) {
+	ca := &CA{db: db}
+	var inter CAKeyPair
+	err := db.One("Name", "intermediate", &inter)
+	if err == storm.ErrNotFound {
+		if inter, err = ca.create(); err != nil {
+			return nil, fmt.Errorf("create ca: %v", err)
+		}
+	} else if err != nil {
+		return nil, err
+	}
+	if err := ca.load(inter); err != nil {
+		return nil, fmt.Errorf("load ca: %v", err)
+	}
+	return ca, nil
+}
+
+// create makes and saves a new root and intermediate.
+func (ca *CA) create() (CAKeyPair, error) {
+	rootKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return CAKeyPair{}, err
+	}
+	rootTmpl := caTemplate("co-chair root CA", caRootLifetime)
+	rootTmpl.MaxPathLen = 1
+	rootDER, err := x509.CreateCertificate(rand.Reader, rootTmpl, rootTmpl, rootKey.Public(), rootKey)
+	if err != nil {
+		return CAKeyPair{}, err
+	}
+	root, err := x509.ParseCertificate(rootDER)
+	if err != nil {
+		return CAKeyPair{}, err
+	}
+
+	interKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return CAKeyPair{}, err
+	}
+	interTmpl := caTemplate("co-chair intermediate CA", caIntermediateLifetime)
+	interTmpl.MaxPathLenZero = true
+	interDER, err := x509.CreateCertificate(rand.Reader, interTmpl, root, interKey.Public(), rootKey)
+	if err != nil {
+		return CAKeyPair{}, err
+	}
+
+	rootKP := CAKeyPair{Name: "root", Cert: certPEM(rootDER)}
+	if rootKP.Key, err = keyPEM(rootKey); err != nil {
+		return CAKeyPair{}, err
+	}
+	// The intermediate's Cert holds its whole chain, so it is our bundle.
+	interKP := CAKeyPair{Name: "intermediate", Cert: append(certPEM(interDER), rootKP.Cert...)}
+	if interKP.Key, err = keyPEM(interKey); err != nil {
+		return CAKeyPair{}, err
+	}
+	if err := ca.db.Save(&rootKP); err != nil {
+		return CAKeyPair{}, err
+	}
+	return interKP, ca.db.Save(&interKP)
+}
+
+func (ca *CA) load(kp CAKeyPair) error {
+	pair, err := tls.X509KeyPair(kp.Cert, kp.Key)
+	if err != nil {
+		return err
+	}
+	cert, err := x509.ParseCertificate(pair.Certificate[0])
+	if err != nil {
+		return err
+	}
+	if !cert.IsCA {
+		return errors.New("not a CA certificate")
+	}
+	key, ok := pair.PrivateKey.(crypto.Signer)
+	if !ok {
+		return errors.New("unsupported private key")
+	}
+	ca.mtx.Lock()
+	defer ca.mtx.Unlock()
+	ca.cert, ca.key, ca.bundle = cert, key, kp.Cert
+	return nil
+}
+
+// ImportCA replaces our issuing CA with certPEM and keyPEM. certPEM starts
+// with the issuing certificate and may be followed by its chain. The
+// previous CA's records are dropped; certs it issued stay in the database.
+func ImportCA(db *storm.DB, certPEM, keyPEM []byte) error {
+	kp := CAKeyPair{Name: "intermediate", Cert: certPEM, Key: keyPEM}
+	if err := (&CA{}).load(kp); err != nil {
+		return fmt.Errorf("import ca: %v", err)
+	}
+	for _, name := range []string{"root", "proxy-client"} {
+		if err := db.DeleteStruct(&CAKeyPair{Name: name}); err != nil && err != storm.ErrNotFound {
+			return err
+		}
+	}
+	return db.Save(&kp)
+}
+
+// Bundle returns the PEM-encoded chain of our issuing CA, for clients and
+// backends to trust.
+func (ca *CA) Bundle() []byte {
+	ca.mtx.Lock()
+	defer ca.mtx.Unlock()
+	return ca.bundle
+}
+
+// IssueServer issues a serving certificate for domain. It returns the
+// certificate with its chain, and the private key, PEM-encoded.
+func (ca *CA) IssueServer(domain string) ([]byte, []byte, error) {
+	tmpl := &x509.Certificate{
+		Subject:     pkix.Name{CommonName: domain},
+		DNSNames:    []string{domain},
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	return ca.issue(ca.db, tmpl, domain, false)
+}
+
+// IssueClient issues a client certificate for name, for backend mTLS.
+func (ca *CA) IssueClient(name string) ([]byte, []byte, error) {
+	return ca.issueClient(ca.db, name)
+}
+
+// issueClient is IssueClient, keeping its record in db.
+func (ca *CA) issueClient(db storm.Node, name string) ([]byte, []byte, error) {
+	tmpl := &x509.Certificate{
+		Subject:     pkix.Name{CommonName: name},
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	return ca.issue(db, tmpl, name, true)
+}
+
+func (ca *CA) issue(db storm.Node, tmpl *x509.Certificate, name string, client bool) ([]byte, []byte, error) {
+	serial, err := newSerial()
+	if err != nil {
+		return nil, nil, err
+	}
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+	tmpl.SerialNumber = serial
+	tmpl.NotBefore = time.Now().Add(-time.Hour)
+	tmpl.NotAfter = time.Now().Add(caLeafLifetime)
+	tmpl.KeyUsage = x509.KeyUsageDigitalSignature
+
+	ca.mtx.Lock()
+	issuer, signer, bundle := ca.cert, ca.key, ca.bundle
+	ca.mtx.Unlock()
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, issuer, key.Public(), signer)
+	if err != nil {
+		return nil, nil, fmt.Errorf("issue %s: %v", name, err)
+	}
+	rec := IssuedCert{Serial: serial.Text(16), Name: name, Client: client, NotAfter: tmpl.NotAfter}
+	if err := db.Save(&rec); err != nil {
+		return nil, nil, err
+	}
+	kp, err := keyPEM(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	return append(certPEM(der), bundle...), kp, nil
+}
+
+// ProxyClientCertificate is the client certificate we present to backends,
+// so they can require mTLS from us. It is issued once, kept in the
+// database, and reissued as it nears expiry.
+func (ca *CA) ProxyClientCertificate() (tls.Certificate, error) {
+	var kp CAKeyPair
+	err := ca.db.One("Name", "proxy-client", &kp)
+	if err != nil && err != storm.ErrNotFound {
+		return tls.Certificate{}, err
+	}
+	if err == nil {
+		cert, err := tls.X509KeyPair(kp.Cert, kp.Key)
+		if err == nil && !expiresWithin(cert, caRenewBefore) {
+			return cert, nil
+		}
+	}
+	kp.Name = "proxy-client"
+	if kp.Cert, kp.Key, err = ca.IssueClient("co-chair"); err != nil {
+		return tls.Certificate{}, err
+	}
+	if err := ca.db.Save(&kp); err != nil {
+		return tls.Certificate{}, err
+	}
+	return tls.X509KeyPair(kp.Cert, kp.Key)
+}
+
+// ProxyClientCert holds the client certificate we present to backends, and
+// reissues it as it nears expiry, for proxies that run for longer than it
+// lasts.
+type ProxyClientCert struct {
+	ca   *CA
+	mtx  sync.RWMutex
+	cert *tls.Certificate
+}
+
+// NewProxyClientCert loads our client certificate from ca, issuing it if
+// need be. Call Renew to keep it current.
+func NewProxyClientCert(ca *CA) (*ProxyClientCert, error) {
+	c := &ProxyClientCert{ca: ca}
+	if err := c.Renew(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Certificate returns our current client certificate.
+func (c *ProxyClientCert) Certificate() *tls.Certificate {
+	c.mtx.RLock()
+	defer c.mtx.RUnlock()
+	return c.cert
+}
+
+// Renew reissues our client certificate if it nears expiry. Connections
+// to backends use the new one from their next handshake.
+func (c *ProxyClientCert) Renew() error {
+	cert, err := c.ca.ProxyClientCertificate()
+	if err != nil {
+		return fmt.Errorf("proxy client cert: %v", err)
+	}
+	c.mtx.Lock()
+	c.cert = &cert
+	c.mtx.Unlock()
+	return nil
+}
+
+// RenewCACerts reissues the certs our CA gave backends that near expiry,
+// and returns their domains. Put and the like renew them too, but only for
+// the backends they write, so this should run on a schedule.
+func (p *Proxy) RenewCACerts(ctx context.Context) ([]string, error) {
+	if p.CA == nil {
+		return nil, nil
+	}
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
+	var backends []BackendData
+	if err := p.DB.All(&backends); err != nil {
+		return nil, fmt.Errorf("db error: %v", err)
+	}
+	var renewed []string
+	for _, bd := range backends {
+		if bd.ACME || !bd.CAIssued || !caCertExpiring(bd.BackendCert) {
+			continue
+		}
+		before := bd
+		if err := p.issueCACert(&bd); err != nil {
+			return renewed, fmt.Errorf("renew %s: %v", bd.Domain, err)
+		}
+		bd.Revision++
+		if err := p.commitBackend(ctx, "RenewCACert", &before, &bd); err != nil {
+			return renewed, err
+		}
+		p.routes.Swap(p.routes.Table().With(bd))
+		renewed = append(renewed, bd.Domain)
+	}
+	return renewed, nil
+}
+
+// Revoke marks the certificate with a hex serial as revoked. It appears on
+// our CRL from then on.
+func (ca *CA) Revoke(serial string) error {
+	return ca.revoke(ca.db, serial)
+}
+
+// revoke is Revoke, in db.
+func (ca *CA) revoke(db storm.Node, serial string) error {
+	var rec IssuedCert
+	if err := db.One("Serial", serial, &rec); err != nil {
+		if err == storm.ErrNotFound {
+			return status.Errorf(codes.NotFound, "no certificate with serial %s", serial)
+		}
+		return err
+	}
+	if rec.Revoked {
+		return nil
+	}
+	rec.Revoked = true
+	rec.RevokedAt = time.Now()
+	return db.Save(&rec)
+}
+
+// CRL returns a PEM-encoded revocation list of every unexpired certificate
+// we have revoked, signed by our issuing CA. It is valid for a day.
+func (ca *CA) CRL() ([]byte, error) {
+	var revoked []IssuedCert
+	if err := ca.db.Find("Revoked", true, &revoked); err != nil && err != storm.ErrNotFound {
+		return nil, err
+	}
+	now := time.Now()
+	var entries []x509.RevocationListEntry
+	for _, rec := range revoked {
+		if rec.NotAfter.Before(now) {
+			continue
+		}
+		serial, ok := new(big.Int).SetString(rec.Serial, 16)
+		if !ok {
+			continue
+		}
+		entries = append(entries, x509.RevocationListEntry{
+			SerialNumber:   serial,
+			RevocationTime: rec.RevokedAt,
+		})
+	}
+
+	ca.mtx.Lock()
+	issuer, signer := ca.cert, ca.key
+	ca.mtx.Unlock()
+	der, err := x509.CreateRevocationList(rand.Reader, &x509.RevocationList{
+		Number:                    big.NewInt(now.Unix()),
+		ThisUpdate:                now,
+		NextUpdate:                now.Add(24 * time.Hour),
+		RevokedCertificateEntries: entries,
+	}, issuer, signer)
+	if err != nil {
+		return nil, fmt.Errorf("crl: %v", err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "X509 CRL", Bytes: der}), nil
+}
+
+func caTemplate(cn string, lifetime time.Duration) *x509.Certificate {
+	serial, _ := newSerial()
+	return &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: cn, Organization: []string{"co-chair"}},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(lifetime),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+	}
+}
+
+func newSerial() (*big.Int, error) {
+	return rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+}
+
+func certPEM(der []byte) []byte {
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+}
+
+func keyPEM(key *ecdsa.PrivateKey) ([]byte, error) {
+	der, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der}), nil
+}
+
+// expiresWithin reports whether the leaf of cert expires within d.
+func expiresWithin(cert tls.Certificate, d time.Duration) bool {
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		return true
+	}
+	return time.Now().Add(d).After(leaf.NotAfter)
+}
+
+// caCertExpiring reports whether a PEM-encoded cert is near expiry.
+func caCertExpiring(pemCert []byte) bool {
+	block, _ := pem.Decode(pemCert)
+	if block == nil {
+		return true
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return true
+	}
+	return time.Now().Add(caRenewBefore).After(cert.NotAfter)
+}
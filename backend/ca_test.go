@@ -0,0 +1,153 @@
+package backend
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/anxiousmodernman/co-chair/proto/server"
+)
+
+func TestCAIssueAndRevoke(t *testing.T) {
+	p, cleanup, err := NewTestProxyCleanup()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cleanup()
+
+	// Loading again must find the CA NewProxy created, not make another.
+	ca, err := LoadCA(p.DB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(ca.Bundle()) != string(p.CA.Bundle()) {
+		t.Fatalf("expected LoadCA to load the existing CA")
+	}
+
+	roots := x509.NewCertPool()
+	if !roots.AppendCertsFromPEM(ca.Bundle()) {
+		t.Fatal("expected certificates in CA bundle")
+	}
+	certPEM, keyPEM, err := ca.IssueServer("internal.example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	pair, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	leaf, _ := x509.ParseCertificate(pair.Certificate[0])
+	if _, err := leaf.Verify(x509.VerifyOptions{Roots: roots, DNSName: "internal.example.com"}); err != nil {
+		t.Errorf("expected issued cert to verify against our bundle: %v", err)
+	}
+
+	if err := p.CA.Revoke(leaf.SerialNumber.Text(16)); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.CA.Revoke("abc123"); err == nil {
+		t.Errorf("expected error revoking an unknown serial")
+	}
+	info, err := p.GetCA(context.TODO(), &server.CARequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	block, _ := pem.Decode(info.Crl)
+	if block == nil {
+		t.Fatal("expected PEM-encoded CRL")
+	}
+	crl, err := x509.ParseRevocationList(block.Bytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(crl.RevokedCertificateEntries) != 1 ||
+		crl.RevokedCertificateEntries[0].SerialNumber.Cmp(leaf.SerialNumber) != 0 {
+		t.Errorf("expected our revoked serial on the CRL, got %v", crl.RevokedCertificateEntries)
+	}
+}
+
+func TestPutIssuesCert(t *testing.T) {
+	p, cleanup, err := NewTestProxyCleanup()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cleanup()
+
+	b := &server.Backend{Domain: "internal.example.com", Ips: []string{"127.0.0.1:9999"}}
+	if _, err := p.Put(context.TODO(), b); err != nil {
+		t.Fatal(err)
+	}
+	route, err := p.Router().Route("internal.example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if route.Certificate == nil || !route.CAIssued {
+		t.Fatalf("expected a cert from our CA, got error: %v", route.CertErr)
+	}
+
+	// Uploading a cert replaces the issued one.
+	_, signed := testNewCAAndCert(t)
+	b.BackendCert = &server.X509Cert{Cert: signed.Cert, Key: signed.PrivateKey}
+	if _, err := p.Put(context.TODO(), b); err != nil {
+		t.Fatal(err)
+	}
+	route, _ = p.Router().Route("internal.example.com")
+	if route.CAIssued || string(route.BackendCert) != string(signed.Cert) {
+		t.Errorf("expected the uploaded cert to be served")
+	}
+}
+
+func TestRenewCACerts(t *testing.T) {
+	p, cleanup, err := NewTestProxyCleanup()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cleanup()
+
+	b := &server.Backend{Domain: "internal.example.com", Ips: []string{"127.0.0.1:9999"}}
+	if _, err := p.Put(context.TODO(), b); err != nil {
+		t.Fatal(err)
+	}
+	if renewed, err := p.RenewCACerts(context.TODO()); err != nil || len(renewed) != 0 {
+		t.Fatalf("expected nothing to renew, got %v: %v", renewed, err)
+	}
+
+	// Age the issued cert, as if a year had gone by without a Put.
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	tmpl := &x509.Certificate{SerialNumber: big.NewInt(1), Subject: pkix.Name{CommonName: b.Domain},
+		DNSNames: []string{b.Domain}, NotBefore: time.Now().Add(-time.Hour), NotAfter: time.Now().Add(24 * time.Hour)}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, key.Public(), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var bd BackendData
+	if err := p.DB.One("Domain", b.Domain, &bd); err != nil {
+		t.Fatal(err)
+	}
+	bd.BackendCert = pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	if err := p.DB.Save(&bd); err != nil {
+		t.Fatal(err)
+	}
+
+	renewed, err := p.RenewCACerts(context.TODO())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(renewed) != 1 || renewed[0] != b.Domain {
+		t.Fatalf("expected %s renewed, got %v", b.Domain, renewed)
+	}
+	route, err := p.Router().Route(b.Domain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !route.CAIssued || caCertExpiring(route.BackendCert) {
+		t.Error("expected a fresh cert from our CA to be served")
+	}
+}
@@ -0,0 +1,169 @@
+package backend
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/anxiousmodernman/co-chair/proto/server"
+	"github.com/asdine/storm"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ListCertificates describes the certificates of every domain: the one served
+// first, any extras, and any staged for promotion.
+func (p *Proxy) ListCertificates(_ context.Context, _ *server.CertificatesRequest) (*server.CertificateList, error) {
+	var backends []BackendData
+	if err := p.DB.All(&backends); err != nil {
+		return nil, fmt.Errorf("db error: %v", err)
+	}
+	var list server.CertificateList
+	for _, bd := range backends {
+		list.Certificates = append(list.Certificates, p.certificateInfo(bd))
+		if bd.ACME {
+			continue
+		}
+		for _, c := range bd.ExtraCerts {
+			list.Certificates = append(list.Certificates, pairInfo(bd.Domain, c, "uploaded", false))
+		}
+		for _, c := range bd.StagedCerts {
+			list.Certificates = append(list.Certificates, pairInfo(bd.Domain, c, "uploaded", true))
+		}
+	}
+	return &list, nil
+}
+
+// GetCertificate describes the certificate a domain serves first.
+func (p *Proxy) GetCertificate(_ context.Context, req *server.CertificateRequest) (*server.CertificateInfo, error) {
+	var bd BackendData
+	if err := p.DB.One("Domain", req.Domain, &bd); err != nil {
+		if err == storm.ErrNotFound {
+			return nil, status.Errorf(codes.NotFound, "domain not found: %s", req.Domain)
+		}
+		return nil, fmt.Errorf("db error: %v", err)
+	}
+	return p.certificateInfo(bd), nil
+}
+
+func (p *Proxy) certificateInfo(bd BackendData) *server.CertificateInfo {
+	certPEM, source := bd.BackendCert, "uploaded"
+	switch {
+	case bd.ACME:
+		source = "acme"
+		// autocert caches the key and chain under the domain name.
+		data, err := getSecret(p.DB, acmeBucket, bd.Domain)
+		if err != nil {
+			return &server.CertificateInfo{Domain: bd.Domain, Source: source, Error: "not issued yet"}
+		}
+		certPEM = data
+	case bd.CAIssued:
+		source = "ca"
+	}
+	leaf, err := parseLeaf(certPEM)
+	if err != nil {
+		return &server.CertificateInfo{Domain: bd.Domain, Source: source, Error: err.Error()}
+	}
+	info := describeCert(leaf)
+	info.Domain, info.Source = bd.Domain, source
+	return info
+}
+
+func pairInfo(domain string, c CertPair, source string, staged bool) *server.CertificateInfo {
+	leaf, err := parseLeaf(c.Cert)
+	if err != nil {
+		return &server.CertificateInfo{Domain: domain, Source: source, Staged: staged, Error: err.Error()}
+	}
+	info := describeCert(leaf)
+	info.Domain, info.Source, info.Staged = domain, source, staged
+	return info
+}
+
+// parseLeaf returns the first certificate in PEM data, skipping any keys.
+func parseLeaf(data []byte) (*x509.Certificate, error) {
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			return nil, errors.New("no certificate")
+		}
+		if block.Type == "CERTIFICATE" {
+			return x509.ParseCertificate(block.Bytes)
+		}
+	}
+}
+
+func describeCert(c *x509.Certificate) *server.CertificateInfo {
+	info := &server.CertificateInfo{
+		Subject:     c.Subject.String(),
+		Issuer:      c.Issuer.String(),
+		NotBefore:   c.NotBefore.Unix(),
+		NotAfter:    c.NotAfter.Unix(),
+		KeyType:     keyType(c),
+		Fingerprint: fingerprint(c.Raw),
+		Serial:      c.SerialNumber.Text(16),
+	}
+	info.Sans = append(info.Sans, c.DNSNames...)
+	for _, ip := range c.IPAddresses {
+		info.Sans = append(info.Sans, ip.String())
+	}
+	info.Sans = append(info.Sans, c.EmailAddresses...)
+	for _, u := range c.URIs {
+		info.Sans = append(info.Sans, u.String())
+	}
+	return info
+}
+
+func keyType(c *x509.Certificate) string {
+	switch pub := c.PublicKey.(type) {
+	case *ecdsa.PublicKey:
+		return "ECDSA " + pub.Curve.Params().Name
+	case *rsa.PublicKey:
+		return fmt.Sprintf("RSA %d", pub.N.BitLen())
+	case ed25519.PublicKey:
+		return "Ed25519"
+	}
+	return c.PublicKeyAlgorithm.String()
+}
+
+// validateCert checks that a cert and key are a pair, and that the cert
+// covers domain.
+func validateCert(domain string, certPEM, keyPEM []byte) error {
+	pair, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		return fmt.Errorf("certificate: %v", err)
+	}
+	leaf, err := x509.ParseCertificate(pair.Certificate[0])
+	if err != nil {
+		return fmt.Errorf("certificate: %v", err)
+	}
+	if !certCovers(leaf, domain) {
+		return fmt.Errorf("certificate does not cover %s", domain)
+	}
+	return nil
+}
+
+// certCovers reports whether c is valid for domain. A wildcard domain needs
+// the same wildcard SAN. We still accept a matching common name, as older
+// clients did.
+func certCovers(c *x509.Certificate, domain string) bool {
+	if strings.EqualFold(c.Subject.CommonName, domain) {
+		return true
+	}
+	if strings.HasPrefix(domain, "*.") {
+		for _, name := range c.DNSNames {
+			if strings.EqualFold(name, domain) {
+				return true
+			}
+		}
+		return false
+	}
+	return c.VerifyHostname(domain) == nil
+}
@@ -0,0 +1,144 @@
+package backend
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/anxiousmodernman/co-chair/proto/server"
+	"github.com/sirupsen/logrus"
+)
+
+func TestValidateCert(t *testing.T) {
+	p, cleanup, err := NewTestProxyCleanup()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cleanup()
+
+	cert, key, err := p.CA.IssueServer("www.example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	wildCert, wildKey, err := p.CA.IssueServer("*.example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		name      string
+		domain    string
+		cert, key []byte
+		ok        bool
+	}{
+		{"match", "www.example.com", cert, key, true},
+		{"other domain", "api.example.com", cert, key, false},
+		{"mismatched key", "www.example.com", cert, wildKey, false},
+		{"wildcard covers label", "api.example.com", wildCert, wildKey, true},
+		{"wildcard domain", "*.example.com", wildCert, wildKey, true},
+		{"wildcard domain, plain cert", "*.example.com", cert, key, false},
+	}
+	for _, c := range cases {
+		err := validateCert(c.domain, c.cert, c.key)
+		if (err == nil) != c.ok {
+			t.Errorf("%s: expected ok %v, got %v", c.name, c.ok, err)
+		}
+	}
+
+	b := &server.Backend{
+		Domain:      "api.example.com",
+		Ips:         []string{"127.0.0.1:9999"},
+		BackendCert: &server.X509Cert{Cert: cert, Key: key},
+	}
+	if _, err := p.Put(context.TODO(), b); err == nil {
+		t.Errorf("expected Put to refuse a cert for another domain")
+	}
+}
+
+func TestListCertificates(t *testing.T) {
+	p, cleanup, err := NewTestProxyCleanup()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cleanup()
+
+	for _, b := range []*server.Backend{
+		{Domain: "internal.example.com", Ips: []string{"127.0.0.1:9999"}},
+		{Domain: "auto.example.com", Ips: []string{"127.0.0.1:9999"}, Acme: true},
+	} {
+		if _, err := p.Put(context.TODO(), b); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	info, err := p.GetCertificate(context.TODO(), &server.CertificateRequest{Domain: "internal.example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Source != "ca" || info.KeyType != "ECDSA P-256" || len(info.Sans) != 1 || info.Fingerprint == "" {
+		t.Errorf("unexpected certificate info: %+v", info)
+	}
+	info, err = p.GetCertificate(context.TODO(), &server.CertificateRequest{Domain: "auto.example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Source != "acme" || info.Error == "" {
+		t.Errorf("expected unissued acme cert to report an error: %+v", info)
+	}
+	list, err := p.ListCertificates(context.TODO(), &server.CertificatesRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list.Certificates) != 2 {
+		t.Errorf("expected 2 certificates, got %d", len(list.Certificates))
+	}
+}
+
+func TestExpiryChecker(t *testing.T) {
+	p, cleanup, err := NewTestProxyCleanup()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cleanup()
+
+	b := &server.Backend{Domain: "internal.example.com", Ips: []string{"127.0.0.1:9999"}}
+	if _, err := p.Put(context.TODO(), b); err != nil {
+		t.Fatal(err)
+	}
+
+	events := make(chan ExpiryEvent, 10)
+	hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var ev ExpiryEvent
+		json.NewDecoder(r.Body).Decode(&ev)
+		events <- ev
+	}))
+	defer hook.Close()
+
+	// Our CA's certs last a year, so a week's warning finds nothing.
+	c := NewExpiryChecker(p, 7*24*time.Hour, hook.URL, logrus.New())
+	expiring, err := c.Check()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(expiring) != 0 {
+		t.Errorf("expected no expiring certs, got %d", len(expiring))
+	}
+
+	c.Warn = 2 * 365 * 24 * time.Hour
+	for i := 0; i < 2; i++ {
+		if expiring, _ = c.Check(); len(expiring) != 1 {
+			t.Fatalf("expected 1 expiring cert, got %d", len(expiring))
+		}
+	}
+	if len(events) != 1 {
+		t.Fatalf("expected one webhook event per day, got %d", len(events))
+	}
+	if ev := <-events; ev.Domain != "internal.example.com" || ev.Source != "ca" {
+		t.Errorf("unexpected event: %+v", ev)
+	}
+	if certExpiry.Get("internal.example.com") == nil {
+		t.Errorf("expected expiry metric for domain")
+	}
+}
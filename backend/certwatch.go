@@ -0,0 +1,192 @@
+package backend
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"expvar"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/anxiousmodernman/co-chair/proto/server"
+	"github.com/sirupsen/logrus"
+)
+
+// Seconds until each domain's certificate expires, published with expvar.
+var certExpiry = expvar.NewMap("cochair_cert_expiry_seconds")
+
+// ExpiryEvent is the JSON body we post to an ExpiryChecker's webhook.
+type ExpiryEvent struct {
+	Event    string    `json:"event"`
+	Domain   string    `json:"domain"`
+	Source   string    `json:"source"`
+	NotAfter time.Time `json:"not_after"`
+	DaysLeft int       `json:"days_left"`
+}
+
+// ExpiryChecker watches the certificates of a Proxy. Certificates that
+// expire within Warn are logged and, if Webhook is set, posted to it at most
+// once a day per domain. Before each check, certificates from our own CA
+// are renewed, ClientCert included if set.
+type ExpiryChecker struct {
+	P          *Proxy
+	Warn       time.Duration
+	Webhook    string
+	Interval   time.Duration
+	ClientCert *ProxyClientCert
+
+	logger *logrus.Logger
+	client *http.Client
+	stop   chan struct{}
+
+	mtx      sync.Mutex
+	notified map[string]time.Time
+}
+
+// NewExpiryChecker returns an ExpiryChecker for p. Call Start to run it.
+func NewExpiryChecker(p *Proxy, warn time.Duration, webhook string, logger *logrus.Logger) *ExpiryChecker {
+	return &ExpiryChecker{
+		P:        p,
+		Warn:     warn,
+		Webhook:  webhook,
+		Interval: time.Hour,
+		logger:   logger,
+		client:   &http.Client{Timeout: 10 * time.Second},
+		stop:     make(chan struct{}),
+		notified: make(map[string]time.Time),
+	}
+}
+
+// Check looks at every certificate once, and returns the ones that are
+// expiring or missing.
+func (c *ExpiryChecker) Check() ([]*server.CertificateInfo, error) {
+	list, err := c.P.ListCertificates(context.Background(), &server.CertificatesRequest{})
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	// A domain with several certs reports the soonest to expire.
+	soonest := make(map[string]int64)
+	var expiring []*server.CertificateInfo
+	for _, info := range list.Certificates {
+		// Staged certs are not served yet.
+		if info.Staged {
+			continue
+		}
+		if info.Error != "" {
+			c.logger.Warnf("certificate for %s: %s", info.Domain, info.Error)
+			expiring = append(expiring, info)
+			continue
+		}
+		notAfter := time.Unix(info.NotAfter, 0)
+		left := notAfter.Sub(now)
+		if s, ok := soonest[info.Domain]; !ok || int64(left.Seconds()) < s {
+			soonest[info.Domain] = int64(left.Seconds())
+		}
+		if left > c.Warn {
+			continue
+		}
+		expiring = append(expiring, info)
+		c.logger.Warnf("certificate for %s (%s) expires %s", info.Domain, info.Source, notAfter.Format(time.RFC3339))
+		c.notify(ExpiryEvent{
+			Event:    "certificate_expiring",
+			Domain:   info.Domain,
+			Source:   info.Source,
+			NotAfter: notAfter,
+			DaysLeft: int(left.Hours() / 24),
+		})
+	}
+	for domain, secs := range soonest {
+		v := new(expvar.Int)
+		v.Set(secs)
+		certExpiry.Set(domain, v)
+	}
+	// Forget domains that are gone.
+	var gone []string
+	certExpiry.Do(func(kv expvar.KeyValue) {
+		if _, ok := soonest[kv.Key]; !ok {
+			gone = append(gone, kv.Key)
+		}
+	})
+	for _, domain := range gone {
+		certExpiry.Delete(domain)
+	}
+	return expiring, nil
+}
+
+func (c *ExpiryChecker) notify(ev ExpiryEvent) {
+	if c.Webhook == "" {
+		return
+	}
+	c.mtx.Lock()
+	if last, ok := c.notified[ev.Domain]; ok && time.Since(last) < 24*time.Hour {
+		c.mtx.Unlock()
+		return
+	}
+	c.notified[ev.Domain] = time.Now()
+	c.mtx.Unlock()
+
+	if err := c.post(ev); err != nil {
+		c.logger.Errorf("cert expiry webhook: %v", err)
+	}
+}
+
+func (c *ExpiryChecker) post(ev ExpiryEvent) error {
+	body, err := json.Marshal(ev)
+	if err != nil {
+		return err
+	}
+	resp, err := c.client.Post(c.Webhook, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("%s: %s", c.Webhook, resp.Status)
+	}
+	return nil
+}
+
+// Renew reissues the certificates our CA issued that near expiry.
+func (c *ExpiryChecker) Renew() {
+	// Renewals are made by co-chair itself, not a client.
+	ctx := context.WithValue(context.Background(), principalKey{}, Principal{Name: "co-chair", Role: RoleAdmin})
+	renewed, err := c.P.RenewCACerts(ctx)
+	for _, domain := range renewed {
+		c.logger.Infof("renewed CA-issued certificate for %s", domain)
+	}
+	if err != nil {
+		c.logger.Errorf("CA cert renewal: %v", err)
+	}
+	if c.ClientCert != nil {
+		if err := c.ClientCert.Renew(); err != nil {
+			c.logger.Errorf("%v", err)
+		}
+	}
+}
+
+// Start renews and checks now, then every Interval until Stop is called.
+func (c *ExpiryChecker) Start() {
+	go func() {
+		ticker := time.NewTicker(c.Interval)
+		defer ticker.Stop()
+		for {
+			c.Renew()
+			if _, err := c.Check(); err != nil {
+				c.logger.Errorf("cert expiry check: %v", err)
+			}
+			select {
+			case <-c.stop:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Stop ends background checks.
+func (c *ExpiryChecker) Stop() {
+	close(c.stop)
+}
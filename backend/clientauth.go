@@ -0,0 +1,189 @@
+package backend
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+)
+
+// ClientCertHeader carries a verified client certificate's identity to
+// HTTP1 backends that forward it by header. Clients cannot set it; we
+// replace whatever they send.
+const ClientCertHeader = "X-Forwarded-Client-Cert"
+
+// compileClientAuth parses the Route's client CA bundle, if it has one.
+func (r *Route) compileClientAuth() {
+	if len(r.ClientCAs) == 0 {
+		return
+	}
+	r.clientCAs = x509.NewCertPool()
+	if !r.clientCAs.AppendCertsFromPEM(r.ClientCAs) {
+		r.clientErr = fmt.Errorf("%s client CA bundle has no certificates", r.Domain)
+	}
+}
+
+// RequiresClientCert reports whether clients must authenticate with a
+// certificate to reach the Route.
+func (r *Route) RequiresClientCert() bool {
+	return r.clientCAs != nil
+}
+
+// VerifyClient checks the client certificate of a TLS connection against the
+// Route's CA bundle and allowed names. Routes without client auth accept
+// every connection.
+func (r *Route) VerifyClient(cs tls.ConnectionState) error {
+	if !r.RequiresClientCert() {
+		return nil
+	}
+	if r.clientErr != nil {
+		return r.clientErr
+	}
+	if len(cs.PeerCertificates) == 0 {
+		return errors.New("no client certificate")
+	}
+	leaf := cs.PeerCertificates[0]
+	intermediates := x509.NewCertPool()
+	for _, c := range cs.PeerCertificates[1:] {
+		intermediates.AddCert(c)
+	}
+	_, err := leaf.Verify(x509.VerifyOptions{
+		Roots:         r.clientCAs,
+		Intermediates: intermediates,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	})
+	if err != nil {
+		return fmt.Errorf("client certificate: %v", err)
+	}
+	if len(r.ClientNames) == 0 {
+		return nil
+	}
+	for _, name := range certNames(leaf) {
+		for _, pattern := range r.ClientNames {
+			if matchName(pattern, name) {
+				return nil
+			}
+		}
+	}
+	return fmt.Errorf("client certificate %q is not allowed", leaf.Subject.CommonName)
+}
+
+// certNames lists the subject common name and SANs of a certificate.
+func certNames(c *x509.Certificate) []string {
+	var names []string
+	if c.Subject.CommonName != "" {
+		names = append(names, c.Subject.CommonName)
+	}
+	names = append(names, c.DNSNames...)
+	names = append(names, c.EmailAddresses...)
+	for _, u := range c.URIs {
+		names = append(names, u.String())
+	}
+	return names
+}
+
+func matchName(pattern, name string) bool {
+	pattern, name = strings.ToLower(pattern), strings.ToLower(name)
+	if pattern == name {
+		return true
+	}
+	if strings.HasPrefix(pattern, "*.") {
+		w, ok := wildcard(name)
+		return ok && w == pattern
+	}
+	return false
+}
+
+// peerCertificate returns the client certificate conn was authenticated
+// with, if any.
+func peerCertificate(conn net.Conn) *x509.Certificate {
+	tc, ok := conn.(*tls.Conn)
+	if !ok {
+		return nil
+	}
+	certs := tc.ConnectionState().PeerCertificates
+	if len(certs) == 0 {
+		return nil
+	}
+	return certs[0]
+}
+
+// clientCertValue formats a certificate for ClientCertHeader, like Envoy:
+// Hash=<sha256 hex>;Subject="...";DNS=...;URI=...
+func clientCertValue(c *x509.Certificate) string {
+	sum := sha256.Sum256(c.Raw)
+	parts := []string{
+		"Hash=" + hex.EncodeToString(sum[:]),
+		fmt.Sprintf("Subject=%q", c.Subject.String()),
+	}
+	for _, u := range c.URIs {
+		parts = append(parts, "URI="+u.String())
+	}
+	for _, d := range c.DNSNames {
+		parts = append(parts, "DNS="+d)
+	}
+	return strings.Join(parts, ";")
+}
+
+// PROXY protocol v2 TLV types we send. See the PROXY protocol spec,
+// section 2.2.
+const (
+	pp2TypeAuthority = 0x02
+	pp2TypeSSL       = 0x20
+	pp2SubtypeSSLCN  = 0x22
+	// pp2TypeClientCert is custom, and holds clientCertValue.
+	pp2TypeClientCert = 0xE0
+
+	pp2ClientSSL      = 0x01
+	pp2ClientCertConn = 0x02
+)
+
+// proxyV2Header builds a PROXY protocol v2 header announcing a connection
+// from src to dst, with the server name the client asked for and, if it
+// authenticated, its certificate.
+func proxyV2Header(src, dst net.Addr, serverName string, cert *x509.Certificate) []byte {
+	var addrs []byte
+	fam := byte(0x00) // AF_UNSPEC
+	s, sok := src.(*net.TCPAddr)
+	d, dok := dst.(*net.TCPAddr)
+	if sok && dok {
+		if s4, d4 := s.IP.To4(), d.IP.To4(); s4 != nil && d4 != nil {
+			fam = 0x11 // TCP over IPv4
+			addrs = append(append(addrs, s4...), d4...)
+		} else {
+			fam = 0x21 // TCP over IPv6
+			addrs = append(append(addrs, s.IP.To16()...), d.IP.To16()...)
+		}
+		addrs = binary.BigEndian.AppendUint16(addrs, uint16(s.Port))
+		addrs = binary.BigEndian.AppendUint16(addrs, uint16(d.Port))
+	}
+
+	var tlvs []byte
+	if serverName != "" {
+		tlvs = appendTLV(tlvs, pp2TypeAuthority, []byte(serverName))
+	}
+	ssl := []byte{pp2ClientSSL, 0, 0, 0, 0} // client flags; verify is 0, ok
+	if cert != nil {
+		ssl[0] |= pp2ClientCertConn
+		ssl = appendTLV(ssl, pp2SubtypeSSLCN, []byte(cert.Subject.CommonName))
+		tlvs = appendTLV(tlvs, pp2TypeClientCert, []byte(clientCertValue(cert)))
+	}
+	tlvs = appendTLV(tlvs, pp2TypeSSL, ssl)
+
+	hdr := append([]byte{}, proxyV2Sig...)
+	hdr = append(hdr, 0x21, fam) // version 2, PROXY command
+	hdr = binary.BigEndian.AppendUint16(hdr, uint16(len(addrs)+len(tlvs)))
+	hdr = append(hdr, addrs...)
+	return append(hdr, tlvs...)
+}
+
+func appendTLV(b []byte, typ byte, value []byte) []byte {
+	b = append(b, typ)
+	b = binary.BigEndian.AppendUint16(b, uint16(len(value)))
+	return append(b, value...)
+}
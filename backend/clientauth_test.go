@@ -0,0 +1,162 @@
+package backend
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testClientCA returns a PEM-encoded CA and a func that issues client
+// certificates from it.
+func testClientCA(t *testing.T) ([]byte, func(cn string, dns ...string) *x509.Certificate) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test client CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ca, _ := x509.ParseCertificate(der)
+	serial := int64(1)
+	issue := func(cn string, dns ...string) *x509.Certificate {
+		serial++
+		leafKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		tmpl := &x509.Certificate{
+			SerialNumber: big.NewInt(serial),
+			Subject:      pkix.Name{CommonName: cn},
+			DNSNames:     dns,
+			NotBefore:    time.Now().Add(-time.Hour),
+			NotAfter:     time.Now().Add(time.Hour),
+			ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		}
+		der, err := x509.CreateCertificate(rand.Reader, tmpl, ca, &leafKey.PublicKey, key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		leaf, _ := x509.ParseCertificate(der)
+		return leaf
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), issue
+}
+
+func TestVerifyClient(t *testing.T) {
+	bundle, issue := testClientCA(t)
+	_, otherIssue := testClientCA(t)
+
+	open := compileRoute(BackendData{Domain: "open"})
+	if err := open.VerifyClient(tls.ConnectionState{}); err != nil {
+		t.Errorf("expected route without client auth to accept anyone: %v", err)
+	}
+
+	r := compileRoute(BackendData{
+		Domain:      "admin",
+		ClientCAs:   bundle,
+		ClientNames: []string{"alice", "*.ops.example.com"},
+	})
+	cases := []struct {
+		name  string
+		certs []*x509.Certificate
+		ok    bool
+	}{
+		{"no cert", nil, false},
+		{"allowed cn", []*x509.Certificate{issue("alice")}, true},
+		{"allowed san", []*x509.Certificate{issue("bob", "bob.ops.example.com")}, true},
+		{"name not allowed", []*x509.Certificate{issue("mallory")}, false},
+		{"wrong ca", []*x509.Certificate{otherIssue("alice")}, false},
+	}
+	for _, c := range cases {
+		err := r.VerifyClient(tls.ConnectionState{PeerCertificates: c.certs})
+		if (err == nil) != c.ok {
+			t.Errorf("%s: expected ok %v, got %v", c.name, c.ok, err)
+		}
+	}
+
+	bad := compileRoute(BackendData{Domain: "bad", ClientCAs: []byte("garbage")})
+	if !bad.RequiresClientCert() {
+		t.Errorf("expected route with a broken bundle to still require certs")
+	}
+	if err := bad.VerifyClient(tls.ConnectionState{PeerCertificates: []*x509.Certificate{issue("alice")}}); err == nil {
+		t.Errorf("expected route with a broken bundle to refuse everyone")
+	}
+}
+
+func TestProxyV2Header(t *testing.T) {
+	_, issue := testClientCA(t)
+	src := &net.TCPAddr{IP: net.ParseIP("192.0.2.1"), Port: 56324}
+	dst := &net.TCPAddr{IP: net.ParseIP("198.51.100.1"), Port: 443}
+	hdr := proxyV2Header(src, dst, "admin.example.com", issue("alice"))
+
+	r := bufio.NewReader(io.MultiReader(bytes.NewReader(hdr), strings.NewReader("rest")))
+	addr, err := readProxyHeader(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if addr.String() != src.String() {
+		t.Errorf("expected source %v, got %v", src, addr)
+	}
+	rest, _ := r.ReadString(0)
+	if rest != "rest" {
+		t.Errorf("expected TLVs to be consumed, %q left", rest)
+	}
+	if !bytes.Contains(hdr, []byte("admin.example.com")) || !bytes.Contains(hdr, []byte("alice")) {
+		t.Errorf("expected server name and client CN in TLVs")
+	}
+}
+
+func TestRewriteRequests(t *testing.T) {
+	client, backend := net.Pipe()
+	defer backend.Close()
+
+	reqs := "GET /one HTTP/1.1\r\nHost: admin\r\n" + ClientCertHeader + ": forged\r\n\r\n" +
+		"POST /two HTTP/1.1\r\nHost: admin\r\nContent-Length: 5\r\n\r\nhello"
+	go func() {
+		w := &httpRewriter{identity: true, identityValue: "Hash=abc"}
+		w.rewriteRequests(client, strings.NewReader(reqs))
+		client.Close()
+	}()
+
+	br := bufio.NewReader(backend)
+	for _, path := range []string{"/one", "/two"} {
+		req, err := http.ReadRequest(br)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if req.URL.Path != path {
+			t.Errorf("expected %s, got %s", path, req.URL.Path)
+		}
+		if got := req.Header[ClientCertHeader]; len(got) != 1 || got[0] != "Hash=abc" {
+			t.Errorf("%s: expected our identity only, got %v", path, got)
+		}
+		if _, ok := req.Header["User-Agent"]; ok {
+			t.Errorf("%s: expected no User-Agent to be added", path)
+		}
+		body, _ := ioutil.ReadAll(req.Body)
+		if path == "/two" && string(body) != "hello" {
+			t.Errorf("expected body to pass through, got %q", body)
+		}
+	}
+}
@@ -0,0 +1,222 @@
+package backend
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Rudd-O/curvetls"
+	"github.com/anxiousmodernman/co-chair/proto/server"
+	"github.com/asdine/storm"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// CreateClientKey makes a keypair for a new gRPC API client. The private key
+// is returned, but not kept.
+func (p *Proxy) CreateClientKey(ctx context.Context, req *server.ClientKeyRequest) (*server.ClientKey, error) {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	var ck *server.ClientKey
+	err := p.auditOne(ctx, "CreateClientKey", req.Name, func(tx storm.Node) ([]FieldChange, error) {
+		var err error
+		if ck, err = CreateClientKey(tx, req.Name, req.Role, req.Domains); err != nil {
+			return nil, err
+		}
+		return []FieldChange{
+			{Field: "Pub", After: ck.Pub},
+			{Field: "Role", After: ck.Role},
+			{Field: "Domains", After: strings.Join(ck.Domains, " ")},
+		}, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ck, nil
+}
+
+// ListClientKeys returns every client key, revoked or not, without private
+// keys.
+func (p *Proxy) ListClientKeys(_ context.Context, _ *server.ClientKeysRequest) (*server.ClientKeyList, error) {
+	var kps []KeyPair
+	if err := p.DB.All(&kps); err != nil {
+		return nil, fmt.Errorf("db error: %v", err)
+	}
+	serverPub, _, err := RetrieveServerKeys(p.DB)
+	if err != nil {
+		return nil, err
+	}
+	var list server.ClientKeyList
+	for _, kp := range kps {
+		if isServerKey(kp.Name) {
+			continue
+		}
+		list.Keys = append(list.Keys, kp.clientKey("", serverPub))
+	}
+	return &list, nil
+}
+
+// RevokeClientKey stops a client from connecting. The StormKeystore sees it
+// on the client's next connection.
+func (p *Proxy) RevokeClientKey(ctx context.Context, req *server.ClientKeyRequest) (*server.OpResult, error) {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
+	kp, err := clientKeyPair(p.DB, req.Name)
+	if err != nil {
+		return nil, err
+	}
+	if !kp.Revoked.IsZero() {
+		return &server.OpResult{Code: 200, Status: "already revoked"}, nil
+	}
+	kp.Revoked = time.Now()
+	err = p.auditOne(ctx, "RevokeClientKey", kp.Name, func(tx storm.Node) ([]FieldChange, error) {
+		if err := tx.Save(&kp); err != nil {
+			return nil, fmt.Errorf("save: %v", err)
+		}
+		return []FieldChange{{Field: "Revoked", After: kp.Revoked.UTC().Format(time.RFC3339)}}, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &server.OpResult{Code: 200, Status: "Ok"}, nil
+}
+
+// RotateClientKey replaces a client's keypair. The old key stops working
+// immediately. Revoked keys can be rotated back into use.
+func (p *Proxy) RotateClientKey(ctx context.Context, req *server.ClientKeyRequest) (*server.ClientKey, error) {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
+	kp, err := clientKeyPair(p.DB, req.Name)
+	if err != nil {
+		return nil, err
+	}
+	old := kp.Pub
+	kp.Revoked = time.Time{}
+	var ck *server.ClientKey
+	err = p.auditOne(ctx, "RotateClientKey", kp.Name, func(tx storm.Node) ([]FieldChange, error) {
+		var err error
+		if ck, err = saveClientKey(tx, kp); err != nil {
+			return nil, err
+		}
+		return []FieldChange{{Field: "Pub", Before: old, After: ck.Pub}}, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ck, nil
+}
+
+// SetClientKeyRole changes what a client may do, from its next call.
+func (p *Proxy) SetClientKeyRole(ctx context.Context, req *server.ClientKeyRequest) (*server.ClientKey, error) {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
+	kp, err := clientKeyPair(p.DB, req.Name)
+	if err != nil {
+		return nil, err
+	}
+	before := kp
+	if kp.Role, err = ParseRole(req.Role); err != nil {
+		return nil, invalid("role", "%v", err)
+	}
+	if err := validScopes(req.Domains); err != nil {
+		return nil, invalid("domains", "%v", err)
+	}
+	kp.Domains = req.Domains
+	err = p.auditOne(ctx, "SetClientKeyRole", kp.Name, func(tx storm.Node) ([]FieldChange, error) {
+		if err := tx.Save(&kp); err != nil {
+			return nil, fmt.Errorf("save: %v", err)
+		}
+		return []FieldChange{
+			{Field: "Role", Before: string(before.Role), After: string(kp.Role)},
+			{Field: "Domains", Before: strings.Join(before.Domains, " "), After: strings.Join(kp.Domains, " ")},
+		}, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	serverPub, _, err := RetrieveServerKeys(p.DB)
+	if err != nil {
+		return nil, err
+	}
+	return kp.clientKey("", serverPub), nil
+}
+
+// CreateClientKey makes a client key directly in db, for commands that run
+// while co-chair is stopped.
+func CreateClientKey(db storm.Node, name, role string, domains []string) (*server.ClientKey, error) {
+	if name == "" {
+		return nil, invalid("name", "is required")
+	}
+	r, err := ParseRole(role)
+	if err != nil {
+		return nil, invalid("role", "%v", err)
+	}
+	if err := validScopes(domains); err != nil {
+		return nil, invalid("domains", "%v", err)
+	}
+	if isServerKey(name) {
+		return nil, invalid("name", "%q is the name of our own key", name)
+	}
+	var kp KeyPair
+	err = db.One("Name", name, &kp)
+	if err == nil {
+		return nil, status.Errorf(codes.AlreadyExists, "client key exists: %s; rotate it instead", name)
+	}
+	if err != storm.ErrNotFound {
+		return nil, fmt.Errorf("db error: %v", err)
+	}
+	return saveClientKey(db, KeyPair{Name: name, Role: r, Domains: domains})
+}
+
+// saveClientKey gives kp a new keypair and saves it.
+func saveClientKey(db storm.Node, kp KeyPair) (*server.ClientKey, error) {
+	serverPub, _, err := RetrieveServerKeys(db)
+	if err != nil {
+		return nil, err
+	}
+	priv, pub, err := curvetls.GenKeyPair()
+	if err != nil {
+		return nil, err
+	}
+	kp.Pub, kp.Created = pub.String(), time.Now()
+	if err := db.Save(&kp); err != nil {
+		return nil, fmt.Errorf("save: %v", err)
+	}
+	return kp.clientKey(priv.String(), serverPub), nil
+}
+
+func clientKeyPair(db *storm.DB, name string) (KeyPair, error) {
+	var kp KeyPair
+	if isServerKey(name) {
+		return kp, invalid("name", "%q is the name of our own key", name)
+	}
+	if err := db.One("Name", name, &kp); err != nil {
+		if err == storm.ErrNotFound {
+			return kp, status.Errorf(codes.NotFound, "client key not found: %s", name)
+		}
+		return kp, fmt.Errorf("db error: %v", err)
+	}
+	return kp, nil
+}
+
+func (kp KeyPair) clientKey(priv string, serverPub curvetls.Pubkey) *server.ClientKey {
+	ck := &server.ClientKey{
+		Name:      kp.Name,
+		Pub:       kp.Pub,
+		Priv:      priv,
+		ServerPub: serverPub.String(),
+		Role:      string(kp.Role),
+		Domains:   kp.Domains,
+	}
+	if !kp.Created.IsZero() {
+		ck.Created = kp.Created.Unix()
+	}
+	if !kp.Revoked.IsZero() {
+		ck.Revoked = kp.Revoked.Unix()
+	}
+	return ck
+}
@@ -0,0 +1,72 @@
+package backend
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Rudd-O/curvetls"
+	"github.com/anxiousmodernman/co-chair/proto/server"
+)
+
+func TestClientKeys(t *testing.T) {
+	p, cleanup, err := NewTestProxyCleanup()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cleanup()
+	ks := &StormKeystore{DB: p.DB}
+	req := &server.ClientKeyRequest{Name: "forwarder-1", Role: "forwarder"}
+	allowed := func(ck *server.ClientKey) bool {
+		pub, err := curvetls.PubkeyFromString(ck.Pub)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return ks.Allowed(pub)
+	}
+
+	ck, err := p.CreateClientKey(context.TODO(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ck.Priv == "" || ck.ServerPub == "" {
+		t.Errorf("expected a private key and the server's public key")
+	}
+	if !allowed(ck) {
+		t.Errorf("expected a new key to be allowed")
+	}
+	if _, err := p.CreateClientKey(context.TODO(), req); err == nil {
+		t.Errorf("expected an error creating a key that exists")
+	}
+	if _, err := p.CreateClientKey(context.TODO(), &server.ClientKeyRequest{Name: "server", Role: "admin"}); err == nil {
+		t.Errorf("expected an error creating a key named server")
+	}
+
+	rotated, err := p.RotateClientKey(context.TODO(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if allowed(ck) || !allowed(rotated) {
+		t.Errorf("expected only the rotated key to be allowed")
+	}
+
+	if _, err := p.RevokeClientKey(context.TODO(), req); err != nil {
+		t.Fatal(err)
+	}
+	if allowed(rotated) {
+		t.Errorf("expected a revoked key to be refused")
+	}
+
+	list, err := p.ListClientKeys(context.TODO(), &server.ClientKeysRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list.Keys) != 1 || list.Keys[0].Revoked == 0 || list.Keys[0].Priv != "" ||
+		list.Keys[0].Role != "forwarder" {
+		t.Errorf("expected one revoked key without its private key, got %v", list.Keys)
+	}
+
+	serverPub, _, _ := RetrieveServerKeys(p.DB)
+	if ks.Allowed(serverPub) {
+		t.Errorf("expected the server's own key to be refused")
+	}
+}
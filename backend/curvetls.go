@@ -5,15 +5,18 @@ import (
 	"github.com/asdine/storm"
 )
 
+// StormKeystore is a curvetls.KeyStore of the client keys in our DB. It
+// looks keys up on every connection, so revocations apply at once.
 type StormKeystore struct {
 	DB *storm.DB
 }
 
+// Allowed is true for client keys that are not revoked.
 func (sk *StormKeystore) Allowed(pubkey curvetls.Pubkey) bool {
 	var kp KeyPair
 	// pass the value we are querying for as the second param
-	if err := sk.DB.One("Pub", pubkey.String(), &kp); err == nil {
-		return true
+	if err := sk.DB.One("Pub", pubkey.String(), &kp); err != nil {
+		return false
 	}
-	return false
+	return !isServerKey(kp.Name) && kp.Revoked.IsZero()
 }
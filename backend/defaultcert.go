@@ -0,0 +1,169 @@
+package backend
+
+import (
+	"bytes"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/hpack"
+)
+
+// CertReloader serves a certificate from PEM files, reloading it when the
+// files change. We use it for clients whose SNI matches no Route.
+type CertReloader struct {
+	CertPath, KeyPath string
+
+	logger *logrus.Logger
+	stop   chan struct{}
+
+	mtx     sync.RWMutex
+	cert    *tls.Certificate
+	modTime time.Time
+}
+
+// NewCertReloader loads the certificate at certPath and key at keyPath.
+// Call Start to watch them for changes.
+func NewCertReloader(certPath, keyPath string, logger *logrus.Logger) (*CertReloader, error) {
+	r := &CertReloader{
+		CertPath: certPath,
+		KeyPath:  keyPath,
+		logger:   logger,
+		stop:     make(chan struct{}),
+	}
+	if _, err := r.Reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Certificate returns the last certificate we loaded.
+func (r *CertReloader) Certificate() *tls.Certificate {
+	r.mtx.RLock()
+	defer r.mtx.RUnlock()
+	return r.cert
+}
+
+// Reload loads our files again if either changed since the last load, and
+// reports whether it did. On error we keep serving the old certificate.
+func (r *CertReloader) Reload() (bool, error) {
+	modTime, err := r.lastModified()
+	if err != nil {
+		return false, err
+	}
+	r.mtx.RLock()
+	same := r.cert != nil && modTime.Equal(r.modTime)
+	r.mtx.RUnlock()
+	if same {
+		return false, nil
+	}
+	cert, err := tls.LoadX509KeyPair(r.CertPath, r.KeyPath)
+	if err != nil {
+		return false, fmt.Errorf("default certificate: %v", err)
+	}
+	r.mtx.Lock()
+	r.cert, r.modTime = &cert, modTime
+	r.mtx.Unlock()
+	return true, nil
+}
+
+func (r *CertReloader) lastModified() (time.Time, error) {
+	var last time.Time
+	for _, path := range []string{r.CertPath, r.KeyPath} {
+		fi, err := os.Stat(path)
+		if err != nil {
+			return last, fmt.Errorf("default certificate: %v", err)
+		}
+		if fi.ModTime().After(last) {
+			last = fi.ModTime()
+		}
+	}
+	return last, nil
+}
+
+// Start checks our files every interval until Stop is called.
+func (r *CertReloader) Start(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-r.stop:
+				return
+			case <-ticker.C:
+			}
+			reloaded, err := r.Reload()
+			if err != nil {
+				r.logger.Errorf("reload: %v", err)
+				continue
+			}
+			if reloaded {
+				r.logger.Infof("reloaded default certificate %s", r.CertPath)
+			}
+		}
+	}()
+}
+
+// Stop ends background reloads.
+func (r *CertReloader) Stop() {
+	close(r.stop)
+}
+
+const notFoundPage = `<!DOCTYPE html>
+<html>
+<head><title>404 Not Found</title></head>
+<body>
+<h1>Not Found</h1>
+<p>No site is configured for this address.</p>
+</body>
+</html>
+`
+
+// notFound answers a request for a domain we have no Route for. Clients
+// without SNI reach us on the default certificate, so we can tell them what
+// went wrong instead of hanging up.
+func notFound(conn net.Conn, http2Conn bool) {
+	defer conn.Close()
+	if http2Conn {
+		writeHTTP2NotFound(conn)
+		return
+	}
+	fmt.Fprintf(conn, "HTTP/1.1 404 Not Found\r\n"+
+		"Content-Type: text/html; charset=utf-8\r\n"+
+		"Content-Length: %d\r\n"+
+		"Connection: close\r\n\r\n%s", len(notFoundPage), notFoundPage)
+}
+
+// writeHTTP2NotFound answers the client's first stream with our 404 page,
+// then sends GOAWAY.
+func writeHTTP2NotFound(conn net.Conn) error {
+	// Clients open stream 1 first.
+	const streamID = 1
+	framer := http2.NewFramer(conn, nil)
+	if err := framer.WriteSettings(); err != nil {
+		return err
+	}
+	var block bytes.Buffer
+	enc := hpack.NewEncoder(&block)
+	enc.WriteField(hpack.HeaderField{Name: ":status", Value: "404"})
+	enc.WriteField(hpack.HeaderField{Name: "content-type", Value: "text/html; charset=utf-8"})
+	enc.WriteField(hpack.HeaderField{Name: "content-length", Value: strconv.Itoa(len(notFoundPage))})
+	err := framer.WriteHeaders(http2.HeadersFrameParam{
+		StreamID:      streamID,
+		BlockFragment: block.Bytes(),
+		EndHeaders:    true,
+	})
+	if err != nil {
+		return err
+	}
+	if err := framer.WriteData(streamID, true, []byte(notFoundPage)); err != nil {
+		return err
+	}
+	return framer.WriteGoAway(streamID, http2.ErrCodeNo, nil)
+}
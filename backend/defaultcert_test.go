@@ -0,0 +1,122 @@
+package backend
+
+import (
+	"bufio"
+	"crypto/tls"
+	"crypto/x509"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/hpack"
+)
+
+func TestCertReloader(t *testing.T) {
+	p, cleanup, err := NewTestProxyCleanup()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cleanup()
+
+	dir, err := ioutil.TempDir("", "cochair-defaultcert")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	certPath, keyPath := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	write := func(name string, mtime time.Time) {
+		cert, key, err := p.CA.IssueServer(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for path, data := range map[string][]byte{certPath: cert, keyPath: key} {
+			if err := ioutil.WriteFile(path, data, 0600); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.Chtimes(path, mtime, mtime); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	now := time.Now()
+	write("first.example.com", now.Add(-time.Minute))
+	r, err := NewCertReloader(certPath, keyPath, logrus.New())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reloaded, err := r.Reload(); reloaded || err != nil {
+		t.Errorf("expected no reload of unchanged files, got %v, %v", reloaded, err)
+	}
+
+	fwdr, _ := NewTCPForwarder(WithRouter(p.Router()), WithLogger(logrus.New()), WithDefaultCertificate(r))
+	cert, err := fwdr.GetCertificate(&tls.ClientHelloInfo{ServerName: ""})
+	if err != nil || cert != r.Certificate() {
+		t.Fatalf("expected the default cert for a client without SNI, got %v", err)
+	}
+
+	write("second.example.com", now)
+	if reloaded, err := r.Reload(); !reloaded || err != nil {
+		t.Fatalf("expected reload of changed files, got %v, %v", reloaded, err)
+	}
+	leaf, err := x509.ParseCertificate(r.Certificate().Certificate[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if leaf.Subject.CommonName != "second.example.com" {
+		t.Errorf("expected the new cert, got %s", leaf.Subject.CommonName)
+	}
+
+	// A broken file keeps the old cert in service.
+	ioutil.WriteFile(keyPath, []byte("garbage"), 0600)
+	os.Chtimes(keyPath, now.Add(time.Minute), now.Add(time.Minute))
+	if _, err := r.Reload(); err == nil {
+		t.Errorf("expected an error reloading a bad key")
+	}
+	if r.Certificate() == nil {
+		t.Errorf("expected to keep serving the old cert")
+	}
+}
+
+func TestNotFound(t *testing.T) {
+	server, client := net.Pipe()
+	go notFound(server, false)
+	resp, err := http.ReadResponse(bufio.NewReader(client), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := ioutil.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusNotFound || string(body) != notFoundPage {
+		t.Errorf("unexpected http1 response: %v %q", resp.Status, body)
+	}
+
+	server, client = net.Pipe()
+	go notFound(server, true)
+	framer := http2.NewFramer(ioutil.Discard, client)
+	framer.ReadMetaHeaders = hpack.NewDecoder(4096, nil)
+	var status string
+	var gotData, gotGoAway bool
+	for !gotGoAway {
+		f, err := framer.ReadFrame()
+		if err != nil {
+			t.Fatal(err)
+		}
+		switch f := f.(type) {
+		case *http2.MetaHeadersFrame:
+			status = f.PseudoValue("status")
+		case *http2.DataFrame:
+			gotData = f.StreamEnded() && string(f.Data()) == notFoundPage
+		case *http2.GoAwayFrame:
+			gotGoAway = true
+		}
+	}
+	if status != "404" || !gotData {
+		t.Errorf("unexpected http2 response: status %q, data %v", status, gotData)
+	}
+}
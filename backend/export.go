@@ -0,0 +1,412 @@
+package backend
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/anxiousmodernman/co-chair/proto/server"
+	"github.com/asdine/storm"
+	bolt "github.com/coreos/bbolt"
+	"golang.org/x/crypto/scrypt"
+)
+
+// DocumentVersion is the version of the Documents we export. We import
+// only this version.
+const DocumentVersion = 1
+
+// Document is our configuration in portable form: backends, KV data and
+// client public keys. Our CA, server keys and audit log stay with us.
+// Certs our CA issued are left out, to be issued again by the importing
+// CA.
+type Document struct {
+	Version  int
+	Exported time.Time
+	// Passphrase is set if private keys are encrypted with one. If not,
+	// private keys are left out.
+	Passphrase *PassphraseKey `json:",omitempty"`
+	Backends   []BackendData
+	KV         []KVPair
+	ClientKeys []KeyPair
+}
+
+// PassphraseKey derives the key a Document's private keys are encrypted
+// with from a passphrase, with scrypt.
+type PassphraseKey struct {
+	Salt    []byte
+	N, R, P int
+}
+
+// KVPair is a key and value from the streams bucket.
+type KVPair struct {
+	Key, Value []byte
+}
+
+func newPassphraseKey() (*PassphraseKey, error) {
+	pk := &PassphraseKey{Salt: make([]byte, 16), N: 1 << 15, R: 8, P: 1}
+	if _, err := rand.Read(pk.Salt); err != nil {
+		return nil, err
+	}
+	return pk, nil
+}
+
+func (pk *PassphraseKey) sealer(passphrase string) (*Sealer, error) {
+	key, err := scrypt.Key([]byte(passphrase), pk.Salt, pk.N, pk.R, pk.P, 32)
+	if err != nil {
+		return nil, fmt.Errorf("passphrase: %v", err)
+	}
+	return NewSealer(key)
+}
+
+// Export writes our configuration as a JSON Document.
+func (p *Proxy) Export(_ context.Context, req *server.ExportRequest) (*server.ConfigDocument, error) {
+	doc := Document{Version: DocumentVersion, Exported: time.Now().UTC()}
+	var s *Sealer
+	if req.Passphrase != "" {
+		pk, err := newPassphraseKey()
+		if err != nil {
+			return nil, err
+		}
+		if s, err = pk.sealer(req.Passphrase); err != nil {
+			return nil, err
+		}
+		doc.Passphrase = pk
+	}
+
+	if err := p.DB.All(&doc.Backends); err != nil {
+		return nil, fmt.Errorf("db error: %v", err)
+	}
+	for i := range doc.Backends {
+		bd := &doc.Backends[i]
+		bd.ID, bd.Revision = 0, 0
+		if bd.CAIssued {
+			bd.BackendCert, bd.BackendKey = nil, nil
+		}
+		var err error
+		if bd.BackendKey, err = exportKey(s, bd.BackendKey); err != nil {
+			return nil, err
+		}
+		for _, pairs := range [][]CertPair{bd.ExtraCerts, bd.StagedCerts} {
+			for j := range pairs {
+				if pairs[j].Key, err = exportKey(s, pairs[j].Key); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+
+	err := p.DB.Bolt.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte("streams"))
+		if b == nil {
+			return nil
+		}
+		return b.ForEach(func(k, v []byte) error {
+			doc.KV = append(doc.KV, KVPair{Key: append([]byte{}, k...), Value: append([]byte{}, v...)})
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("db error: %v", err)
+	}
+
+	var keys []KeyPair
+	if err := p.DB.All(&keys); err != nil {
+		return nil, fmt.Errorf("db error: %v", err)
+	}
+	for _, kp := range keys {
+		if isServerKey(kp.Name) {
+			continue
+		}
+		kp.Priv = ""
+		doc.ClientKeys = append(doc.ClientKeys, kp)
+	}
+
+	data, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return &server.ConfigDocument{Data: data}, nil
+}
+
+// exportKey encrypts a private key with s, or leaves it out if s is nil.
+func exportKey(s *Sealer, key []byte) ([]byte, error) {
+	if s == nil || len(key) == 0 {
+		return nil, nil
+	}
+	return s.Seal(key)
+}
+
+func importKey(s *Sealer, key []byte) ([]byte, error) {
+	if s == nil || len(key) == 0 {
+		return nil, nil
+	}
+	plain, err := s.Open(key)
+	if err != nil {
+		return nil, invalid("passphrase", "does not decrypt the document's private keys")
+	}
+	return plain, nil
+}
+
+// Import adds the configuration in a Document to ours, in one
+// transaction. Anything in the document that differs from what we have is
+// a conflict, and nothing is imported, unless req.Overwrite is set. Certs
+// whose keys were left out keep our key if we have the same cert, and are
+// dropped otherwise.
+func (p *Proxy) Import(ctx context.Context, req *server.ImportRequest) (*server.ImportResult, error) {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
+	var doc Document
+	if err := json.Unmarshal(req.Data, &doc); err != nil {
+		return nil, invalid("data", "%v", err)
+	}
+	if doc.Version != DocumentVersion {
+		return nil, invalid("data", "unsupported document version %d", doc.Version)
+	}
+	var s *Sealer
+	if doc.Passphrase != nil {
+		if req.Passphrase == "" {
+			return nil, invalid("passphrase", "is required; the document's private keys are encrypted")
+		}
+		var err error
+		if s, err = doc.Passphrase.sealer(req.Passphrase); err != nil {
+			return nil, err
+		}
+	}
+	result := &server.ImportResult{}
+	conflict := func(format string, args ...interface{}) {
+		result.Conflicts = append(result.Conflicts, fmt.Sprintf(format, args...))
+	}
+
+	var current []BackendData
+	if err := p.DB.All(&current); err != nil {
+		return nil, fmt.Errorf("db error: %v", err)
+	}
+	existing := make(map[string]BackendData)
+	for _, bd := range current {
+		existing[bd.Domain] = bd
+	}
+	// Documents can be edited by hand, so we check each backend as Put
+	// would before we diff.
+	var fe fieldErrors
+	imported := make(map[string]BackendData)
+	for i, bd := range doc.Backends {
+		prefix := fmt.Sprintf("backends[%d].", i)
+		if _, ok := imported[bd.Domain]; ok && bd.Domain != "" {
+			fe.add(prefix+"domain", fmt.Errorf("%s is listed more than once", bd.Domain))
+			continue
+		}
+		old, exists := existing[bd.Domain]
+		bd.ID = old.ID
+		var err error
+		if bd.BackendKey, err = importKey(s, bd.BackendKey); err != nil {
+			return nil, err
+		}
+		if bd.CAIssued && exists && old.CAIssued {
+			bd.BackendCert, bd.BackendKey = old.BackendCert, old.BackendKey
+		}
+		if len(bd.BackendCert) > 0 && len(bd.BackendKey) == 0 {
+			if bytes.Equal(bd.BackendCert, old.BackendCert) {
+				bd.BackendKey = old.BackendKey
+			} else {
+				bd.BackendCert = nil
+				result.Warnings = append(result.Warnings, bd.Domain+": cert has no key, and was left out")
+			}
+		}
+		extra, staged := len(bd.ExtraCerts), len(bd.StagedCerts)
+		if bd.ExtraCerts, err = importPairs(s, bd.ExtraCerts, old.ExtraCerts); err != nil {
+			return nil, err
+		}
+		if bd.StagedCerts, err = importPairs(s, bd.StagedCerts, old.StagedCerts); err != nil {
+			return nil, err
+		}
+		if n := extra + staged - len(bd.ExtraCerts) - len(bd.StagedCerts); n > 0 {
+			result.Warnings = append(result.Warnings, fmt.Sprintf("%s: %d extra or staged certs have no key, and were left out", bd.Domain, n))
+		}
+		b := bd.AsRoute()
+		fe = append(fe, validateBackend(prefix, b)...)
+		fe.checkIPs(prefix+"ips", b.Ips)
+		for j, c := range bd.StagedCerts {
+			if err := validateCert(bd.Domain, c.Cert, c.Key); err != nil {
+				fe.add(fmt.Sprintf("%sstaged_certs[%d]", prefix, j), err)
+			}
+		}
+		if exists && len(diffBackends(&old, &bd)) > 0 && !req.Overwrite {
+			conflict("domain %s differs from ours", bd.Domain)
+		}
+		imported[bd.Domain] = bd
+	}
+	if err := fe.err(); err != nil {
+		return nil, err
+	}
+
+	var kv []KVPair
+	for _, pair := range doc.KV {
+		ours, err := p.DB.GetBytes("streams", pair.Key)
+		if err == storm.ErrNotFound {
+			kv = append(kv, pair)
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("db error: %v", err)
+		}
+		if bytes.Equal(ours, pair.Value) {
+			continue
+		}
+		if !req.Overwrite {
+			conflict("KV key %q differs from ours", pair.Key)
+		}
+		kv = append(kv, pair)
+	}
+
+	var keys []KeyPair
+	for _, kp := range doc.ClientKeys {
+		if isServerKey(kp.Name) {
+			return nil, invalid("data", "%q is the name of our own key", kp.Name)
+		}
+		var byPub KeyPair
+		err := p.DB.One("Pub", kp.Pub, &byPub)
+		if err != nil && err != storm.ErrNotFound {
+			return nil, fmt.Errorf("db error: %v", err)
+		}
+		if err == nil && byPub.Name != kp.Name {
+			conflict("client key %s has the public key of our %s", kp.Name, byPub.Name)
+			continue
+		}
+		var ours KeyPair
+		err = p.DB.One("Name", kp.Name, &ours)
+		if err != nil && err != storm.ErrNotFound {
+			return nil, fmt.Errorf("db error: %v", err)
+		}
+		if err == nil {
+			kp.Created = ours.Created
+			if reflect.DeepEqual(kp, ours) {
+				continue
+			}
+			if !req.Overwrite {
+				conflict("client key %s differs from ours", kp.Name)
+			}
+		}
+		keys = append(keys, kp)
+	}
+
+	next := make([]BackendData, 0, len(current)+len(imported))
+	for _, bd := range current {
+		if _, ok := imported[bd.Domain]; !ok {
+			next = append(next, bd)
+		}
+	}
+	for _, bd := range doc.Backends {
+		next = append(next, imported[bd.Domain])
+	}
+	if !req.DryRun && len(result.Conflicts) == 0 {
+		for i := range next {
+			if err := p.issueCACert(&next[i]); err != nil {
+				return nil, fmt.Errorf("%s: %v", next[i].Domain, err)
+			}
+		}
+	}
+	diff := diffConfigs(current, next)
+	result.Changes = diff.Domains
+	if req.DryRun || len(result.Conflicts) > 0 ||
+		len(diff.Domains)+len(kv)+len(keys) == 0 {
+		return result, nil
+	}
+	changed := make(map[string]bool)
+	for _, dd := range diff.Domains {
+		changed[dd.Domain] = true
+	}
+
+	tx, err := p.DB.Begin(true)
+	if err != nil {
+		return nil, fmt.Errorf("db error: %v", err)
+	}
+	defer tx.Rollback()
+	for i := range next {
+		bd := &next[i]
+		if !changed[bd.Domain] {
+			continue
+		}
+		bd.Revision = existing[bd.Domain].Revision + 1
+		if err := tx.Save(bd); err != nil {
+			return nil, fmt.Errorf("save %s: %v", bd.Domain, err)
+		}
+	}
+	for _, pair := range kv {
+		if err := tx.SetBytes("streams", pair.Key, pair.Value); err != nil {
+			return nil, fmt.Errorf("SetBytes: %v", err)
+		}
+	}
+	for _, kp := range keys {
+		if err := tx.Save(&kp); err != nil {
+			return nil, fmt.Errorf("save client key %s: %v", kp.Name, err)
+		}
+	}
+	var events []AuditEvent
+	record := func(target string, changes []FieldChange) error {
+		e, err := p.audit(ctx, tx, "Import", target, changes)
+		events = append(events, e)
+		return err
+	}
+	for _, dd := range diff.Domains {
+		if err := record(dd.Domain, fieldChanges(dd)); err != nil {
+			return nil, err
+		}
+	}
+	for _, kp := range keys {
+		changes := []FieldChange{{Field: "Pub", After: kp.Pub}, {Field: "Role", After: string(kp.Role)}}
+		if err := record(kp.Name, changes); err != nil {
+			return nil, err
+		}
+	}
+	if len(kv) > 0 {
+		changes := make([]FieldChange, 0, len(kv))
+		for _, pair := range kv {
+			changes = append(changes, FieldChange{Field: string(pair.Key), After: fmt.Sprintf("%d bytes", len(pair.Value))})
+		}
+		if err := record("streams", changes); err != nil {
+			return nil, err
+		}
+	}
+	if len(diff.Domains) > 0 {
+		rev, err := p.saveRevision(ctx, tx, "Import", fmt.Sprintf("%d domains", len(diff.Domains)))
+		if err != nil {
+			return nil, err
+		}
+		result.Revision = int64(rev)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("commit: %v", err)
+	}
+	p.routes.Swap(NewTable(next))
+	p.mirror(events...)
+	return result, nil
+}
+
+// importPairs decrypts the keys of pairs. Pairs without a key keep ours,
+// if we have the same cert, and are dropped otherwise.
+func importPairs(s *Sealer, pairs, ours []CertPair) ([]CertPair, error) {
+	var out []CertPair
+	for _, pair := range pairs {
+		key, err := importKey(s, pair.Key)
+		if err != nil {
+			return nil, err
+		}
+		if key == nil {
+			for _, o := range ours {
+				if bytes.Equal(o.Cert, pair.Cert) {
+					key = o.Key
+				}
+			}
+		}
+		if key == nil {
+			continue
+		}
+		out = append(out, CertPair{Cert: pair.Cert, Key: key})
+	}
+	return out, nil
+}
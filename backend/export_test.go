@@ -0,0 +1,153 @@
+package backend
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/anxiousmodernman/co-chair/proto/server"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestImportPairs(t *testing.T) {
+	pk, err := newPassphraseKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := pk.sealer("correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sealed, err := exportKey(s, []byte("key a"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	pairs := []CertPair{{Cert: []byte("cert a"), Key: sealed}, {Cert: []byte("cert b")}, {Cert: []byte("cert c")}}
+	ours := []CertPair{{Cert: []byte("cert b"), Key: []byte("key b")}}
+
+	got, err := importPairs(s, pairs, ours)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || string(got[0].Key) != "key a" || string(got[1].Key) != "key b" {
+		t.Errorf("expected a decrypted, b to keep our key and c dropped, got %v", got)
+	}
+
+	wrong, _ := pk.sealer("battery staple")
+	if _, err := importPairs(wrong, pairs, ours); err == nil {
+		t.Errorf("expected the wrong passphrase to fail")
+	}
+	if key, _ := exportKey(nil, []byte("key a")); key != nil {
+		t.Errorf("expected keys to be left out without a passphrase")
+	}
+}
+
+func TestExportImport(t *testing.T) {
+	src, cleanup, err := NewTestProxyCleanup()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cleanup()
+	b := &server.Backend{Domain: "www.example.com", Ips: []string{"10.0.0.1:443"}}
+	if _, err := src.Put(context.TODO(), b); err != nil {
+		t.Fatal(err)
+	}
+	if err := src.DB.SetBytes("streams", []byte("k"), []byte("v")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := CreateClientKey(src.DB, "deploy", string(RoleOperator), nil); err != nil {
+		t.Fatal(err)
+	}
+	doc, err := src.Export(context.TODO(), &server.ExportRequest{Passphrase: "correct horse"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dst, cleanup2, err := NewTestProxyCleanup()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cleanup2()
+	if _, err := dst.Import(context.TODO(), &server.ImportRequest{Data: doc.Data}); err == nil {
+		t.Errorf("expected an encrypted document to need its passphrase")
+	}
+	// A different backend for the same domain is a conflict.
+	b.Ips = []string{"10.0.0.2:443"}
+	if _, err := dst.Put(context.TODO(), b); err != nil {
+		t.Fatal(err)
+	}
+	req := &server.ImportRequest{Data: doc.Data, Passphrase: "correct horse"}
+	result, err := dst.Import(context.TODO(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Conflicts) != 1 || !strings.Contains(result.Conflicts[0], "www.example.com") {
+		t.Fatalf("expected a conflict for www.example.com, got %v", result.Conflicts)
+	}
+	if v, err := dst.DB.GetBytes("streams", []byte("k")); err == nil {
+		t.Errorf("expected nothing imported on conflict, got %q", v)
+	}
+
+	req.Overwrite = true
+	if result, err = dst.Import(context.TODO(), req); err != nil {
+		t.Fatal(err)
+	}
+	if result.Revision == 0 {
+		t.Errorf("expected a revision")
+	}
+	var bd BackendData
+	if err := dst.DB.One("Domain", "www.example.com", &bd); err != nil {
+		t.Fatal(err)
+	}
+	if len(bd.IPs) != 1 || bd.IPs[0] != "10.0.0.1:443" {
+		t.Errorf("expected the exported IPs, got %v", bd.IPs)
+	}
+	var kp KeyPair
+	if err := dst.DB.One("Name", "deploy", &kp); err != nil || kp.Role != RoleOperator {
+		t.Errorf("expected the client key to be imported: %v", err)
+	}
+
+	// Importing again changes nothing.
+	req.Overwrite = false
+	if result, err = dst.Import(context.TODO(), req); err != nil || len(result.Conflicts)+len(result.Changes) != 0 {
+		t.Errorf("expected no conflicts or changes, got %v: %v", result, err)
+	}
+}
+
+func TestImportValidates(t *testing.T) {
+	p, cleanup, err := NewTestProxyCleanup()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cleanup()
+	data, err := json.Marshal(Document{Version: DocumentVersion, Backends: []BackendData{
+		{Domain: "www.example.com", IPs: []string{"10.0.0.1:443"}},
+		{Domain: "bad domain", IPs: []string{"10.0.0.1"}, DenyCIDRs: []string{"10.0.0.0/33"}},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = p.Import(context.TODO(), &server.ImportRequest{Data: data})
+	s, _ := status.FromError(err)
+	if s.Code() != codes.InvalidArgument {
+		t.Fatalf("expected InvalidArgument, got %v", err)
+	}
+	var fields []string
+	for _, d := range s.Details() {
+		for _, v := range d.(*errdetails.BadRequest).FieldViolations {
+			fields = append(fields, v.Field)
+		}
+	}
+	want := "[backends[1].domain backends[1].deny_cidrs backends[1].ips[0]]"
+	if got := fmt.Sprint(fields); got != want {
+		t.Errorf("expected fields %s, got %s", want, got)
+	}
+	var backends []BackendData
+	if err := p.DB.All(&backends); err != nil || len(backends) != 0 {
+		t.Errorf("expected nothing imported, got %v: %v", backends, err)
+	}
+}
@@ -15,27 +15,35 @@ import (
 	"net/http"
 	"net/http/httputil"
 	"strings"
+	"sync"
 	"time"
 
+	"golang.org/x/crypto/acme"
 	"golang.org/x/net/http2"
 	"golang.org/x/net/http2/hpack"
 
 	"github.com/anxiousmodernman/co-chair/proto/server"
 	"github.com/asdine/storm"
-	"github.com/asdine/storm/q"
 	"github.com/sirupsen/logrus"
 	"github.com/vulcand/oxy/trace"
 )
 
 // NewTCPForwarder constructs a TCPForwarder from a variable list of options.
-// Passing a database (either by pass or by reference) is required or the
-// TCPForwarder will fail at runtime.
+// Passing a database (either by path or by reference) or a Router is required
+// or the TCPForwarder will fail at runtime. If only a database is passed, we
+// route from it with a StormRouter.
 func NewTCPForwarder(opts ...Opt) (*TCPForwarder, error) {
 
 	var fwdr TCPForwarder
 	for _, opt := range opts {
 		opt(&fwdr)
 	}
+	if fwdr.Router == nil && fwdr.DB != nil {
+		fwdr.Router = &StormRouter{DB: fwdr.DB}
+	}
+	if fwdr.OCSP != nil && fwdr.DefaultCert != nil {
+		fwdr.OCSP.Include(fwdr.DefaultCert.Certificate)
+	}
 
 	return &fwdr, nil
 }
@@ -46,7 +54,7 @@ type Opt func(*TCPForwarder)
 // WithDBPath opens a DB at path and sets it on our TCPForwarder.
 func WithDBPath(path string) Opt {
 	return func(fwdr *TCPForwarder) {
-		db, err := storm.Open(path)
+		db, err := OpenDB(path, nil)
 		if err != nil {
 			panic(err)
 		}
@@ -83,6 +91,87 @@ func WithProxyClient(pc server.ProxyClient) Opt {
 	}
 }
 
+// WithRouter sets the Router our TCPForwarder selects backends with.
+func WithRouter(r Router) Opt {
+	return func(fwdr *TCPForwarder) {
+		fwdr.Router = r
+	}
+}
+
+// WithLimits sets listener-wide connection limits.
+func WithLimits(l Limits) Opt {
+	return func(fwdr *TCPForwarder) {
+		fwdr.Limits = l
+	}
+}
+
+// WithDeny sets a listener-wide deny list. Clients in it are turned away
+// before we route their connections.
+func WithDeny(nets []*net.IPNet) Opt {
+	return func(fwdr *TCPForwarder) {
+		fwdr.Deny = nets
+	}
+}
+
+// WithProxyProtocol trusts PROXY protocol headers from peers in nets, such
+// as our load balancers. Client addresses are then taken from the headers.
+// Has no effect if used in conjunction with WithListener.
+func WithProxyProtocol(nets []*net.IPNet) Opt {
+	return func(fwdr *TCPForwarder) {
+		fwdr.ProxyProtocol = nets
+	}
+}
+
+// WithACME lets our TCPForwarder serve certificates from ACME for the
+// Routes that ask for it, and answer TLS-ALPN-01 challenges.
+func WithACME(a *ACME) Opt {
+	return func(fwdr *TCPForwarder) {
+		fwdr.ACME = a
+	}
+}
+
+// WithClientCertificate sets the certificate we present to backends that
+// require mTLS. get is called for each backend we dial, so the certificate
+// may be renewed or reloaded while we run.
+func WithClientCertificate(get func() *tls.Certificate) Opt {
+	return func(fwdr *TCPForwarder) {
+		fwdr.clientCert = get
+	}
+}
+
+// WithIdleTimeout sets our TCPForwarder's IdleTimeout.
+func WithIdleTimeout(d time.Duration) Opt {
+	return func(fwdr *TCPForwarder) {
+		fwdr.IdleTimeout = d
+	}
+}
+
+// WithDefaultCertificate sets the certificate we serve to clients whose SNI
+// matches no Route, including clients that connect by IP.
+func WithDefaultCertificate(r *CertReloader) Opt {
+	return func(fwdr *TCPForwarder) {
+		fwdr.DefaultCert = r
+	}
+}
+
+// WithOCSPStapler staples OCSP responses from s to the certificates we
+// serve for Routes.
+func WithOCSPStapler(s *OCSPStapler) Opt {
+	return func(fwdr *TCPForwarder) {
+		fwdr.OCSP = s
+	}
+}
+
+// WithSessionTickets has our TCPForwarder take its session ticket keys from
+// src, checking for new ones every interval. Has no effect if used in
+// conjunction with WithListener.
+func WithSessionTickets(src TicketKeySource, interval time.Duration) Opt {
+	return func(fwdr *TCPForwarder) {
+		fwdr.tickets = src
+		fwdr.ticketInterval = interval
+	}
+}
+
 // WithListener sets our TCPForwarder's net.Listener.
 func WithListener(l net.Listener) Opt {
 	return func(fwdr *TCPForwarder) {
@@ -92,53 +181,115 @@ func WithListener(l net.Listener) Opt {
 
 // TCPForwarder is our actual listener type that clients will connect to. This
 // implementation then inspects the requests that come in on connections, and
-// selects an appropriate backend by talking gRPC to a Proxy instance via C, its
+// selects an appropriate backend by asking its Router. The Router reads either
+// the local database, or a cache synced from a Proxy instance via C, its
 // embedded server.ProxyClient.
 type TCPForwarder struct {
 	C      server.ProxyClient
 	L      net.Listener
 	logger *logrus.Logger
 	DB     *storm.DB
+	Router Router
 	Addr   string
+	Limits Limits
+	// Deny lists clients refused on every domain. ProxyProtocol lists the
+	// peers we accept PROXY headers from.
+	Deny          []*net.IPNet
+	ProxyProtocol []*net.IPNet
+	ACME          *ACME
+	DefaultCert   *CertReloader
+	OCSP          *OCSPStapler
+	clientCert    func() *tls.Certificate
+	// IdleTimeout closes tunnels that have carried nothing for that long.
+	// Zero means never.
+	IdleTimeout time.Duration
+
+	tickets        TicketKeySource
+	ticketInterval time.Duration
+	stop           chan struct{}
+
+	// sem caps concurrent conns and limiter rate limits clients,
+	// listener-wide. Both are nil unless set in Limits.
+	sem     chan struct{}
+	limiter *clientLimiter
+	// conns and backendLimiters enforce per-backend limits.
+	conns            connCounter
+	mtx              sync.Mutex
+	backendLimiters  map[string]*clientLimiter
+	lastLimiterSweep time.Time
 }
 
-// GetCertificate fetches tls.Certificate from the database for
+// GetCertificate fetches tls.Certificate from our Router for
 // each connection. This lets us dynamically fetch certs.
 func (f *TCPForwarder) GetCertificate(hi *tls.ClientHelloInfo) (*tls.Certificate, error) {
 	host := hi.ServerName
-	fmt.Println("SERVER Name", hi.ServerName)
 
-	var bd BackendData
-	err := f.DB.One("Domain", host, &bd)
+	route, err := f.Router.Route(host)
 	if err != nil {
-		if err == storm.ErrNotFound {
-			return nil, fmt.Errorf("%s no found", host)
+		if err == ErrNoRoute {
+			if f.DefaultCert != nil {
+				return f.staple(f.DefaultCert.Certificate()), nil
+			}
+			return nil, fmt.Errorf("%s not found", host)
 		}
 		f.logger.Error(err)
 		return nil, err
 	}
+	// Forwarders have no ACME manager. They serve the certificate the
+	// control plane obtained, which comes with the route, and leave
+	// challenges to the control plane.
+	if route.ACME && f.ACME != nil {
+		cert, err := f.ACME.GetCertificate(hi)
+		if err != nil {
+			return nil, err
+		}
+		return f.staple(cert), nil
+	}
+	if route.ACME && isACMEChallenge(hi) {
+		return nil, fmt.Errorf("%s: ACME challenges are answered by the control plane, not here", host)
+	}
+	if route.CertErr != nil {
+		return nil, route.CertErr
+	}
+	return f.staple(route.CertificateFor(hi)), nil
+}
 
-	cert, err := tls.X509KeyPair(bd.BackendCert, bd.BackendKey)
-	return &cert, err
+// staple is cert with our OCSP response for it, if we have one.
+func (f *TCPForwarder) staple(cert *tls.Certificate) *tls.Certificate {
+	if f.OCSP == nil {
+		return cert
+	}
+	return f.OCSP.Staple(cert)
 }
 
 // Start accepts TCP connections.
 func (f *TCPForwarder) Start() error {
-	if f.DB == nil {
-		return errors.New("database is nil")
+	if f.Router == nil {
+		return errors.New("router is nil")
 	}
 	// If we did not have a listener set directly, spin one up.
 	// This is the normal path, because we do not set a listener
 	// in main.go, currently.
 	if f.L == nil {
-		var tlsConf tls.Config
-		tlsConf.GetCertificate = f.GetCertificate
-		tlsConf.NextProtos = []string{"h2"}
-		lis, err := tls.Listen("tcp", f.Addr, &tlsConf)
+		tlsConf := f.tlsConfig()
+		tlsConf.GetConfigForClient = f.GetConfigForClient
+		lis, err := net.Listen("tcp", f.Addr)
 		if err != nil {
 			return err
 		}
-		f.L = lis
+		if len(f.ProxyProtocol) > 0 {
+			lis = &proxyListener{Listener: lis, trusted: f.ProxyProtocol}
+		}
+		f.L = tls.NewListener(lis, tlsConf)
+		if f.tickets != nil {
+			f.startTicketSync(tlsConf)
+		}
+	}
+	if f.Limits.MaxConns > 0 {
+		f.sem = make(chan struct{}, f.Limits.MaxConns)
+	}
+	if f.Limits.ClientRate > 0 {
+		f.limiter = newClientLimiter(f.Limits.ClientRate, f.Limits.ClientBurst)
 	}
 	go func() {
 		for {
@@ -161,12 +312,122 @@ func (f *TCPForwarder) Start() error {
 	return nil
 }
 
+// tlsConfig is the base config our listener serves with. GetConfigForClient
+// narrows it for known domains.
+func (f *TCPForwarder) tlsConfig() *tls.Config {
+	conf := &tls.Config{
+		GetCertificate: f.GetCertificate,
+		NextProtos:     []string{"h2", "http/1.1"},
+	}
+	if f.ACME != nil {
+		conf.NextProtos = append(conf.NextProtos, acme.ALPNProto)
+	}
+	return conf
+}
+
+// admit applies the deny list and listener-wide limits to a newly accepted
+// conn, resetting it if it is refused. Admitted conns must be let go with
+// f.release.
+func (f *TCPForwarder) admit(conn net.Conn) bool {
+	if containsIP(f.Deny, addrIP(conn.RemoteAddr())) {
+		f.logger.Warnf("denied %s: on deny list", conn.RemoteAddr())
+		rejected.Add("deny", 1)
+		reset(conn)
+		return false
+	}
+	if f.limiter != nil && !f.limiter.allow(clientIP(conn)) {
+		rejected.Add("client_rate", 1)
+		reset(conn)
+		return false
+	}
+	if f.sem != nil {
+		select {
+		case f.sem <- struct{}{}:
+		default:
+			rejected.Add("max_conns", 1)
+			reset(conn)
+			return false
+		}
+	}
+	return true
+}
+
+func (f *TCPForwarder) release() {
+	if f.sem != nil {
+		<-f.sem
+	}
+}
+
+// admitToBackend applies the limits configured on a backend. Admitted conns
+// must be let go with f.conns.release.
+func (f *TCPForwarder) admitToBackend(conn net.Conn, r *Route, http1 bool) bool {
+	if r.ClientRate > 0 && !f.backendLimiter(r).allow(clientIP(conn)) {
+		rejected.Add("client_rate:"+r.Domain, 1)
+		refuse(conn, http1)
+		return false
+	}
+	if !f.conns.acquire(r.Domain, r.MaxConns) {
+		rejected.Add("max_conns:"+r.Domain, 1)
+		refuse(conn, http1)
+		return false
+	}
+	return true
+}
+
+// backendLimiter returns the clientLimiter for a backend, replacing it if
+// the backend's rate has been reconfigured.
+func (f *TCPForwarder) backendLimiter(r *Route) *clientLimiter {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+	if f.backendLimiters == nil {
+		f.backendLimiters = make(map[string]*clientLimiter)
+	}
+	f.sweepLimiters(time.Now())
+	l, ok := f.backendLimiters[r.Domain]
+	if !ok || l.rate != r.ClientRate || l.burst != burstFor(r.ClientRate, r.ClientBurst) {
+		l = newClientLimiter(r.ClientRate, r.ClientBurst)
+		f.backendLimiters[r.Domain] = l
+	}
+	return l
+}
+
+// sweepLimiters forgets the limiters of backends that were removed or are
+// no longer rate limited, so churned domains do not pile up. Like
+// clientLimiter.sweep, it runs at most once a minute. Callers hold f.mtx.
+func (f *TCPForwarder) sweepLimiters(now time.Time) {
+	if now.Sub(f.lastLimiterSweep) < time.Minute {
+		return
+	}
+	f.lastLimiterSweep = now
+	for domain := range f.backendLimiters {
+		r, err := f.Router.Route(domain)
+		if err == ErrNoRoute || err == nil && (r.Domain != domain || r.ClientRate <= 0) {
+			delete(f.backendLimiters, domain)
+		}
+	}
+}
+
 // Where all the fun happens!
 func (f *TCPForwarder) handleConn(ctx context.Context, conn net.Conn) {
 	_, done := context.WithCancel(ctx)
 	defer done()
-	defer conn.Close()
+	// Set our deadline before admit, which may read a PROXY header.
 	conn.SetDeadline(time.Now().Add(3 * time.Second))
+	if !f.admit(conn) {
+		return
+	}
+	defer f.release()
+	defer conn.Close()
+	if tc, ok := conn.(*tls.Conn); ok {
+		if err := tc.Handshake(); err != nil {
+			f.logger.Debugf("handshake: %v", err)
+			return
+		}
+		// The handshake was all a TLS-ALPN-01 challenge needed.
+		if tc.ConnectionState().NegotiatedProtocol == acme.ALPNProto {
+			return
+		}
+	}
 	// bufForBackend collects all the connection's reads until we select a backend,
 	// then we write all of bufForBackend's contents to the backend conn before
 	// tunneling the rest of the bytes through.
@@ -181,23 +442,22 @@ func (f *TCPForwarder) handleConn(ctx context.Context, conn net.Conn) {
 		return
 	}
 
-	var matched BackendData
-	var found []BackendData
+	var matched *Route
 	if hasHTTP2Preface(prefaceBytes) {
 		headers := gatherHTTP2Headers(tee)
-		query := f.DB.Select(
-			q.In("Protocol", []server.Backend_Protocol{
-				server.Backend_GRPC,
-				server.Backend_HTTP2,
-			}),
-			q.Eq("Domain", HostWithoutPort(headers[":authority"])),
-		)
-		err := query.Find(&found)
+		matched, err = f.Router.Route(HostWithoutPort(headers[":authority"]))
+		if err == ErrNoRoute {
+			notFound(conn, true)
+			return
+		}
 		if err != nil {
-			f.logger.Error(err)
+			f.logger.Errorf("http2 route error: %v", err)
+			return
+		}
+		if matched.Protocol != server.Backend_GRPC && matched.Protocol != server.Backend_HTTP2 {
+			f.logger.Errorf("backend %s does not speak http2", matched.Domain)
 			return
 		}
-		matched = found[0]
 	} else {
 		partial := make([]byte, 4096)
 		n, err := tee.Read(partial)
@@ -213,15 +473,40 @@ func (f *TCPForwarder) handleConn(ctx context.Context, conn net.Conn) {
 				host = strings.TrimSpace(strings.Split(line, ":")[1])
 			}
 		}
-		query := f.DB.Select(q.Eq("Domain", HostWithoutPort(host)))
-		err = query.Find(&found)
+		matched, err = f.Router.Route(HostWithoutPort(host))
+		if err == ErrNoRoute {
+			notFound(conn, false)
+			return
+		}
 		if err != nil {
-			f.logger.Errorf("http1 query error: %v", err)
+			f.logger.Errorf("http1 route error: %v", err)
 			return
 		}
-		matched = found[0]
 	}
-	if err := f.DialAndTunnel(&matched, bufForBackend, conn); err != nil {
+	if !matched.Allowed(addrIP(conn.RemoteAddr())) {
+		f.logger.Warnf("denied %s to %s", conn.RemoteAddr(), matched.Domain)
+		rejected.Add("deny:"+matched.Domain, 1)
+		return
+	}
+	// The handshake verified client certs for the SNI domain. Check again,
+	// in case the Host header named a different one.
+	if matched.RequiresClientCert() {
+		var cs tls.ConnectionState
+		if tc, ok := conn.(*tls.Conn); ok {
+			cs = tc.ConnectionState()
+		}
+		if err := matched.VerifyClient(cs); err != nil {
+			f.logger.Warnf("denied %s to %s: %v", conn.RemoteAddr(), matched.Domain, err)
+			rejected.Add("client_cert:"+matched.Domain, 1)
+			return
+		}
+	}
+	if !f.admitToBackend(conn, matched, !hasHTTP2Preface(prefaceBytes)) {
+		f.logger.Debugf("refused conn to %s from %s", matched.Domain, conn.RemoteAddr())
+		return
+	}
+	defer f.conns.release(matched.Domain)
+	if err := f.DialAndTunnel(&matched.BackendData, bufForBackend, conn); err != nil {
 		f.logger.Errorf("could not proxy: %v", err)
 	}
 }
@@ -236,15 +521,50 @@ func (f *TCPForwarder) DialAndTunnel(bd *BackendData, buffered *bytes.Buffer, co
 
 	f.logger.Debugf("dialing backend: %v", bd.IPs[0])
 	bTLSConfig := &tls.Config{InsecureSkipVerify: true}
+	if f.clientCert != nil {
+		if cert := f.clientCert(); cert != nil {
+			bTLSConfig.Certificates = []tls.Certificate{*cert}
+		}
+	}
 	if bd.Protocol == server.Backend_GRPC || bd.Protocol == server.Backend_HTTP2 {
 		bTLSConfig.NextProtos = []string{"h2"}
 	}
-	bConn, err := tls.Dial("tcp", bd.IPs[0], bTLSConfig)
+	raw, err := net.DialTimeout("tcp", bd.IPs[0], 3*time.Second)
 	if err != nil {
 		return fmt.Errorf("dial backend: %v", err)
 	}
+	bConn := tls.Client(raw, bTLSConfig)
 	defer bConn.Close()
 	bConn.SetDeadline(time.Now().Add(3 * time.Second))
+
+	cert := peerCertificate(conn)
+	if bd.ForwardIdentity == server.ClientAuth_PROXY_V2 {
+		var serverName string
+		if tc, ok := conn.(*tls.Conn); ok {
+			serverName = tc.ConnectionState().ServerName
+		}
+		hdr := proxyV2Header(conn.RemoteAddr(), conn.LocalAddr(), serverName, cert)
+		if _, err := raw.Write(hdr); err != nil {
+			return fmt.Errorf("write proxy header: %v", err)
+		}
+	}
+	if err := bConn.Handshake(); err != nil {
+		return fmt.Errorf("dial backend: %v", err)
+	}
+
+	// Forwarding identity by header and HSTS both need us to proxy HTTP1
+	// at layer 7.
+	if bd.Protocol == server.Backend_HTTP1 &&
+		(bd.ForwardIdentity == server.ClientAuth_HEADER || bd.hstsHeader() != "") {
+		var value string
+		if cert != nil {
+			value = clientCertValue(cert)
+		}
+		conn.SetDeadline(time.Time{})
+		bConn.SetDeadline(time.Time{})
+		f.logger.Debug("proxying at layer 7")
+		return f.idleOK(bd, runHTTPTunnel(conn, bConn, buffered.Bytes(), newHTTPRewriter(bd, value), f.IdleTimeout))
+	}
 	// our first backend write is the little buffer we read
 	// from the incoming conn, by writing here we
 	// pass it upstream after we've inspected it.
@@ -253,59 +573,51 @@ func (f *TCPForwarder) DialAndTunnel(bd *BackendData, buffered *bytes.Buffer, co
 		return fmt.Errorf("first write to backend: %v", err)
 	}
 
-	t := Tunnel{ErrorState: nil, ErrorSig: make(chan error)}
+	// The deadlines only guard backend selection. Once tunneling, conns
+	// live as long as both sides want them to, or until they go idle.
+	conn.SetDeadline(time.Time{})
+	bConn.SetDeadline(time.Time{})
 
 	f.logger.Debug("proxying")
-	go t.pipe(conn, bConn, "conn->bConn")
-	go t.pipe(bConn, conn, "bConn->conn")
-	return <-t.ErrorSig
+	t := Tunnel{Client: conn, Backend: bConn, IdleTimeout: f.IdleTimeout}
+	return f.idleOK(bd, t.Run())
 }
 
-// Stop ...
-func (f *TCPForwarder) Stop() error {
-	return f.L.Close()
-}
-
-func (t *Tunnel) pipe(src, dst net.Conn, dir string) {
-
-	buff := make([]byte, 0xffff)
-	for {
-		if t.ErrorState != nil {
-			return
-		}
-		n, err := src.Read(buff)
-		if err != nil {
-			t.err(fmt.Errorf("%s read: %v", dir, err))
-			return
-		}
-		b := buff[:n]
-
-		n, err = dst.Write(b)
-		if err != nil {
-			t.err(fmt.Errorf("%s write: %v", dir, err))
-			return
-		}
+// idleOK is err from a tunnel, unless we closed it for being idle, which
+// is routine.
+func (f *TCPForwarder) idleOK(bd *BackendData, err error) error {
+	if err == errIdle {
+		f.logger.Debugf("%s: closed idle tunnel", bd.Domain)
+		return nil
 	}
+	return err
 }
 
-// A Tunnel streams data between two conns.
-type Tunnel struct {
-	ErrorState error
-	ErrorSig   chan error
-}
-
-func (t *Tunnel) err(err error) {
-	t.ErrorState = err
-	t.ErrorSig <- err
+// Stop ...
+func (f *TCPForwarder) Stop() error {
+	if f.stop != nil {
+		close(f.stop)
+		f.stop = nil
+	}
+	return f.L.Close()
 }
 
-// NewTCPForwarderFromGRPCClient ...
+// NewTCPForwarderFromGRPCClient returns a TCPForwarder that routes from db if
+// it is non-nil. Otherwise, routes are synced from the control plane via pc;
+// callers must Start the returned forwarder's RemoteRouter.
 func NewTCPForwarderFromGRPCClient(l net.Listener, pc server.ProxyClient, db *storm.DB, logger *logrus.Logger) *TCPForwarder {
+	var r Router
+	if db != nil {
+		r = &StormRouter{DB: db}
+	} else {
+		r = NewRemoteRouter(pc, DefaultSyncInterval, logger)
+	}
 	return &TCPForwarder{
 		C:      pc,
 		L:      l,
 		logger: logger,
 		DB:     db,
+		Router: r,
 	}
 }
 
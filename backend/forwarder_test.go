@@ -19,7 +19,7 @@ import (
 	"google.golang.org/grpc"
 )
 
-func testNewCAAndCert(t *testing.T) (*ls.CA, *ls.SignedCert) {
+func testNewCAAndCert(t testing.TB) (*ls.CA, *ls.SignedCert) {
 	ca, err := ls.NewCA()
 	if err != nil {
 		t.Fatal(err)
@@ -263,7 +263,7 @@ func grpcListenerClientCleanup() (*Proxy, server.ProxyClient, func()) {
 	// Proxy, our concrent implementation
 	dir, _ := ioutil.TempDir("", "co-chair-test")
 	dbPath := filepath.Join(dir, "co-chair-test.db")
-	px, _ := NewProxy(dbPath)
+	px, _ := NewProxy(dbPath, nil)
 
 	// grpc server setup
 	gs := grpc.NewServer()
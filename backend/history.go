@@ -0,0 +1,234 @@
+package backend
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/anxiousmodernman/co-chair/proto/server"
+	"github.com/asdine/storm"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// DefaultRevisionsKept is how many revisions of our routing configuration
+// we keep, if Proxy.RevisionsKept is unset.
+const DefaultRevisionsKept = 100
+
+// Revision is a snapshot of every BackendData after a change. Snapshots
+// include private keys, so they are sealed like BackendData.
+type Revision struct {
+	ID       int `storm:"id,increment"`
+	Time     time.Time
+	Actor    string
+	Method   string
+	Target   string
+	Backends []BackendData
+}
+
+// saveRevision snapshots our routing configuration after a change by the
+// caller in ctx, in tx, the transaction that makes the change. It returns
+// the revision's ID. Callers hold p.mtx.
+func (p *Proxy) saveRevision(ctx context.Context, tx storm.Node, method, target string) (int, error) {
+	var backends []BackendData
+	if err := tx.All(&backends); err != nil {
+		return 0, fmt.Errorf("revision: %v", err)
+	}
+	rev := Revision{Time: time.Now(), Method: method, Target: target, Backends: backends}
+	rev.Actor, _ = actor(ctx)
+	if err := tx.Save(&rev); err != nil {
+		return 0, fmt.Errorf("revision: %v", err)
+	}
+	return rev.ID, p.pruneRevisions(tx)
+}
+
+func (p *Proxy) pruneRevisions(tx storm.Node) error {
+	kept := p.RevisionsKept
+	if kept <= 0 {
+		kept = DefaultRevisionsKept
+	}
+	n, err := tx.Count(&Revision{})
+	if err != nil || n <= kept {
+		return err
+	}
+	var old []Revision
+	if err := tx.All(&old, storm.Limit(n-kept)); err != nil {
+		return fmt.Errorf("db error: %v", err)
+	}
+	for _, rev := range old {
+		if err := tx.DeleteStruct(&rev); err != nil {
+			return fmt.Errorf("delete: %v", err)
+		}
+	}
+	return nil
+}
+
+// ListRevisions returns revisions, newest first.
+func (p *Proxy) ListRevisions(_ context.Context, req *server.RevisionsRequest) (*server.RevisionList, error) {
+	limit := int(req.Limit)
+	if limit <= 0 {
+		limit = 20
+	}
+	var revs []Revision
+	if err := p.DB.All(&revs, storm.Reverse(), storm.Limit(limit)); err != nil {
+		return nil, fmt.Errorf("db error: %v", err)
+	}
+	var list server.RevisionList
+	for _, rev := range revs {
+		list.Revisions = append(list.Revisions, &server.Revision{
+			Id:      int64(rev.ID),
+			Time:    rev.Time.Unix(),
+			Actor:   rev.Actor,
+			Method:  rev.Method,
+			Target:  rev.Target,
+			Domains: int32(len(rev.Backends)),
+		})
+	}
+	return &list, nil
+}
+
+// DiffRevisions compares the routing configuration of two revisions, or of
+// one revision and now.
+func (p *Proxy) DiffRevisions(_ context.Context, req *server.DiffRequest) (*server.RevisionDiff, error) {
+	from, err := p.revisionBackends(req.From)
+	if err != nil {
+		return nil, err
+	}
+	to, err := p.revisionBackends(req.To)
+	if err != nil {
+		return nil, err
+	}
+	return diffConfigs(from, to), nil
+}
+
+// revisionBackends returns the backends of revision id, or our current
+// backends if id is 0.
+func (p *Proxy) revisionBackends(id int64) ([]BackendData, error) {
+	if id == 0 {
+		var backends []BackendData
+		if err := p.DB.All(&backends); err != nil {
+			return nil, fmt.Errorf("db error: %v", err)
+		}
+		return backends, nil
+	}
+	var rev Revision
+	if err := p.DB.One("ID", int(id), &rev); err != nil {
+		if err == storm.ErrNotFound {
+			return nil, status.Errorf(codes.NotFound, "revision not found: %d", id)
+		}
+		return nil, fmt.Errorf("db error: %v", err)
+	}
+	return rev.Backends, nil
+}
+
+func diffConfigs(from, to []BackendData) *server.RevisionDiff {
+	byDomain := func(backends []BackendData) map[string]*BackendData {
+		m := make(map[string]*BackendData)
+		for i := range backends {
+			m[backends[i].Domain] = &backends[i]
+		}
+		return m
+	}
+	a, b := byDomain(from), byDomain(to)
+	var domains []string
+	for d := range a {
+		domains = append(domains, d)
+	}
+	for d := range b {
+		if _, ok := a[d]; !ok {
+			domains = append(domains, d)
+		}
+	}
+	sort.Strings(domains)
+
+	var diff server.RevisionDiff
+	for _, d := range domains {
+		changes := diffBackends(a[d], b[d])
+		if len(changes) == 0 {
+			continue
+		}
+		dd := &server.DomainDiff{Domain: d}
+		for _, c := range changes {
+			dd.Changes = append(dd.Changes, &server.FieldChange{Field: c.Field, Before: c.Before, After: c.After})
+		}
+		diff.Domains = append(diff.Domains, dd)
+	}
+	return &diff
+}
+
+// fieldChanges converts the changes in dd for the audit log.
+func fieldChanges(dd *server.DomainDiff) []FieldChange {
+	changes := make([]FieldChange, 0, len(dd.Changes))
+	for _, c := range dd.Changes {
+		changes = append(changes, FieldChange{Field: c.Field, Before: c.Before, After: c.After})
+	}
+	return changes
+}
+
+// Rollback restores the routing configuration of a revision, in one
+// transaction, and swaps in its routes.
+func (p *Proxy) Rollback(ctx context.Context, req *server.RollbackRequest) (*server.OpResult, error) {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
+	if req.Revision == 0 {
+		return nil, invalid("revision", "is required")
+	}
+	target, err := p.revisionBackends(req.Revision)
+	if err != nil {
+		return nil, err
+	}
+	var current []BackendData
+	if err := p.DB.All(&current); err != nil {
+		return nil, fmt.Errorf("db error: %v", err)
+	}
+
+	tx, err := p.DB.Begin(true)
+	if err != nil {
+		return nil, fmt.Errorf("db error: %v", err)
+	}
+	defer tx.Rollback()
+	// Records keep the IDs they have now, so storm's sequence never hands
+	// out an ID twice.
+	// Revisions only go up, so callers holding one from before the
+	// rollback find it changed.
+	ids := make(map[string]int)
+	revs := make(map[string]int64)
+	for _, bd := range current {
+		ids[bd.Domain], revs[bd.Domain] = bd.ID, bd.Revision
+		if err := tx.DeleteStruct(&bd); err != nil {
+			return nil, fmt.Errorf("delete: %v", err)
+		}
+	}
+	for _, bd := range target {
+		bd.ID = ids[bd.Domain]
+		if revs[bd.Domain] >= bd.Revision {
+			bd.Revision = revs[bd.Domain]
+		}
+		bd.Revision++
+		if err := tx.Save(&bd); err != nil {
+			return nil, fmt.Errorf("save: %v", err)
+		}
+	}
+	rev := fmt.Sprintf("revision %d", req.Revision)
+	var changes []FieldChange
+	for _, dd := range diffConfigs(current, target).Domains {
+		for _, c := range dd.Changes {
+			changes = append(changes, FieldChange{Field: dd.Domain + " " + c.Field, Before: c.Before, After: c.After})
+		}
+	}
+	e, err := p.audit(ctx, tx, "Rollback", rev, changes)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := p.saveRevision(ctx, tx, "Rollback", rev); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("commit: %v", err)
+	}
+	p.routes.Swap(NewTable(target))
+	p.mirror(e)
+	return &server.OpResult{Code: 200, Status: "rolled back to " + rev}, nil
+}
@@ -0,0 +1,81 @@
+package backend
+
+import (
+	"context"
+	"testing"
+
+	"github.com/anxiousmodernman/co-chair/proto/server"
+)
+
+func TestDiffConfigs(t *testing.T) {
+	from := []BackendData{
+		{Domain: "a.example.com", IPs: []string{"10.0.0.1:443"}},
+		{Domain: "b.example.com", IPs: []string{"10.0.0.2:443"}},
+	}
+	to := []BackendData{
+		{Domain: "a.example.com", IPs: []string{"10.0.0.1:443"}},
+		{Domain: "c.example.com", IPs: []string{"10.0.0.3:443"}},
+	}
+	diff := diffConfigs(from, to)
+	if len(diff.Domains) != 2 {
+		t.Fatalf("expected b removed and c added, got %v", diff.Domains)
+	}
+	if diff.Domains[0].Domain != "b.example.com" || diff.Domains[1].Domain != "c.example.com" {
+		t.Errorf("expected domains sorted, got %v", diff.Domains)
+	}
+	if len(diffConfigs(from, from).Domains) != 0 {
+		t.Errorf("expected no changes between equal configurations")
+	}
+}
+
+func TestRollback(t *testing.T) {
+	p, cleanup, err := NewTestProxyCleanup()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cleanup()
+	p.RevisionsKept = 3
+
+	put := func(ips ...string) {
+		b := &server.Backend{Domain: "www.example.com", Ips: ips}
+		if _, err := p.Put(context.TODO(), b); err != nil {
+			t.Fatal(err)
+		}
+	}
+	put("10.0.0.1:443")
+	put("10.0.0.2:443")
+	put("10.0.0.3:443")
+	put("10.0.0.4:443")
+
+	list, err := p.ListRevisions(context.TODO(), &server.RevisionsRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list.Revisions) != 3 {
+		t.Fatalf("expected 3 revisions kept, got %d", len(list.Revisions))
+	}
+	// Newest first; roll back to the oldest we kept.
+	oldest := list.Revisions[len(list.Revisions)-1].Id
+	if _, err := p.Rollback(context.TODO(), &server.RollbackRequest{Revision: oldest}); err != nil {
+		t.Fatal(err)
+	}
+	var bd BackendData
+	if err := p.DB.One("Domain", "www.example.com", &bd); err != nil {
+		t.Fatal(err)
+	}
+	// Put adds IPs, so the oldest revision kept has the first two.
+	if len(bd.IPs) != 2 {
+		t.Errorf("expected the IPs of revision %d, got %v", oldest, bd.IPs)
+	}
+	if _, err := p.routes.Route("www.example.com"); err != nil {
+		t.Errorf("expected the rolled back route to be served")
+	}
+
+	diff, err := p.DiffRevisions(context.TODO(), &server.DiffRequest{From: oldest})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(diff.Domains) != 0 {
+		t.Errorf("expected no changes from revision %d to now, got %v", oldest, diff.Domains)
+	}
+}
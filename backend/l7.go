@@ -0,0 +1,167 @@
+package backend
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/anxiousmodernman/co-chair/proto/server"
+)
+
+// httpRewriter edits the HTTP1 traffic of a conn we proxy at layer 7,
+// rather than tunnel: to forward the client's identity in a header, or to
+// add HSTS to responses.
+type httpRewriter struct {
+	// If identity is set, requests get ClientCertHeader set to
+	// identityValue, replacing whatever the client sent.
+	identity      bool
+	identityValue string
+	// If hsts is set, responses without a Strict-Transport-Security
+	// header get this one.
+	hsts string
+	// methods passes request methods to rewriteResponses, which needs them
+	// to read responses. It is closed once requests are no longer HTTP1.
+	// done is closed once rewriteResponses returns, and no longer reads
+	// methods.
+	methods chan string
+	done    chan struct{}
+}
+
+func newHTTPRewriter(bd *BackendData, identityValue string) *httpRewriter {
+	w := &httpRewriter{hsts: bd.hstsHeader()}
+	if bd.ForwardIdentity == server.ClientAuth_HEADER {
+		w.identity, w.identityValue = true, identityValue
+	}
+	if w.hsts != "" {
+		w.methods = make(chan string, 64)
+		w.done = make(chan struct{})
+	}
+	return w
+}
+
+// rewriteRequests copies HTTP1 requests from src to dst. After a protocol
+// upgrade, such as a websocket handshake, the rest of src is copied as is.
+func (w *httpRewriter) rewriteRequests(dst net.Conn, src io.Reader) error {
+	methods := w.methods
+	defer func() {
+		if methods != nil {
+			close(methods)
+		}
+	}()
+	br := bufio.NewReader(src)
+	for {
+		req, err := http.ReadRequest(br)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("conn->bConn: read request: %v", err)
+		}
+		if w.identity {
+			req.Header.Del(ClientCertHeader)
+			if w.identityValue != "" {
+				req.Header.Set(ClientCertHeader, w.identityValue)
+			}
+		}
+		// Don't let net/http add a User-Agent the client didn't send.
+		if _, ok := req.Header["User-Agent"]; !ok {
+			req.Header.Set("User-Agent", "")
+		}
+		if methods != nil {
+			select {
+			case methods <- req.Method:
+			case <-w.done:
+				// The backend is done answering, so nothing more we
+				// send it gets a response.
+				return closeWrite(dst, "conn->bConn")
+			}
+		}
+		if err := req.Write(dst); err != nil {
+			return fmt.Errorf("conn->bConn: write request: %v", err)
+		}
+		if req.Header.Get("Upgrade") != "" {
+			if methods != nil {
+				close(methods)
+				methods = nil
+			}
+			buf := bufPool.Get().(*[]byte)
+			_, err = io.CopyBuffer(dst, br, *buf)
+			bufPool.Put(buf)
+			if err != nil {
+				return fmt.Errorf("conn->bConn: %v", err)
+			}
+			break
+		}
+	}
+	return closeWrite(dst, "conn->bConn")
+}
+
+// rewriteResponses copies HTTP1 responses from src to dst, one for each
+// method rewriteRequests sends. Once it stops sending, the rest of src is
+// copied as is.
+func (w *httpRewriter) rewriteResponses(dst net.Conn, src io.Reader, methods <-chan string) error {
+	br := bufio.NewReader(src)
+	for method := range methods {
+		for {
+			// ReadResponse reports EOF as unexpected, even between
+			// responses, where it is how a backend says it is done.
+			if _, err := br.Peek(1); err == io.EOF {
+				return closeWrite(dst, "bConn->conn")
+			}
+			resp, err := http.ReadResponse(br, &http.Request{Method: method})
+			if err != nil {
+				return fmt.Errorf("bConn->conn: read response: %v", err)
+			}
+			if resp.Header.Get("Strict-Transport-Security") == "" {
+				resp.Header.Set("Strict-Transport-Security", w.hsts)
+			}
+			err = resp.Write(dst)
+			resp.Body.Close()
+			if err != nil {
+				return fmt.Errorf("bConn->conn: write response: %v", err)
+			}
+			// Informational responses come before the real one, except
+			// 101, after which the conn is no longer HTTP.
+			if resp.StatusCode < 100 || resp.StatusCode > 199 || resp.StatusCode == http.StatusSwitchingProtocols {
+				break
+			}
+		}
+	}
+	buf := bufPool.Get().(*[]byte)
+	_, err := io.CopyBuffer(dst, br, *buf)
+	bufPool.Put(buf)
+	if err != nil {
+		return fmt.Errorf("bConn->conn: %v", err)
+	}
+	return closeWrite(dst, "bConn->conn")
+}
+
+func closeWrite(conn net.Conn, dir string) error {
+	if cw, ok := conn.(closeWriter); ok {
+		if err := cw.CloseWrite(); err != nil {
+			return fmt.Errorf("%s close write: %v", dir, err)
+		}
+	}
+	return nil
+}
+
+// runHTTPTunnel is like a Tunnel, but passes traffic through w. buffered
+// holds what we already read from conn.
+func runHTTPTunnel(conn, bConn net.Conn, buffered []byte, w *httpRewriter, idle time.Duration) error {
+	t, timer := Tunnel{Client: conn, Backend: bConn, IdleTimeout: idle}.withIdleTimeout()
+	conn, bConn = t.Client, t.Backend
+	src := io.MultiReader(bytes.NewReader(buffered), conn)
+	upstream := func() error { return w.rewriteRequests(bConn, src) }
+	downstream := func() error { return pipe(conn, bConn, "bConn->conn") }
+	if w.methods != nil {
+		downstream = func() error {
+			defer close(w.done)
+			return w.rewriteResponses(conn, bConn, w.methods)
+		}
+	}
+	return timer.done(t.run(upstream, downstream))
+}
@@ -0,0 +1,171 @@
+package backend
+
+import (
+	"crypto/tls"
+	"expvar"
+	"net"
+	"sync"
+	"time"
+)
+
+// Limits are listener-wide connection limits for a TCPForwarder. Zero values
+// mean no limit. Per-backend limits live on BackendData.
+type Limits struct {
+	// MaxConns caps the number of connections we handle at once.
+	MaxConns int
+	// ClientRate is the sustained number of new connections per second we
+	// accept from one client IP. ClientBurst is how many it may open at once.
+	ClientRate  float64
+	ClientBurst int
+}
+
+// Our limit counters, published with expvar. Keys of rejected are reasons,
+// suffixed with a domain for per-backend limits. Keys of active are domains.
+var (
+	rejected = expvar.NewMap("cochair_rejected")
+	active   = expvar.NewMap("cochair_active")
+)
+
+// clientLimiter is a token bucket per client IP.
+type clientLimiter struct {
+	rate  float64
+	burst float64
+
+	mtx       sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+func newClientLimiter(rate float64, burst int) *clientLimiter {
+	return &clientLimiter{
+		rate:      rate,
+		burst:     burstFor(rate, burst),
+		buckets:   make(map[string]*bucket),
+		lastSweep: time.Now(),
+	}
+}
+
+// burstFor defaults an unset burst to one second's worth of tokens.
+func burstFor(rate float64, burst int) float64 {
+	if burst < 1 {
+		burst = int(rate)
+		if burst < 1 {
+			burst = 1
+		}
+	}
+	return float64(burst)
+}
+
+// allow takes a token from ip's bucket, if there is one.
+func (l *clientLimiter) allow(ip string) bool {
+	now := time.Now()
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	l.sweep(now)
+
+	b, ok := l.buckets[ip]
+	if !ok {
+		b = &bucket{tokens: l.burst, last: now}
+		l.buckets[ip] = b
+	}
+	b.tokens += now.Sub(b.last).Seconds() * l.rate
+	if b.tokens > l.burst {
+		b.tokens = l.burst
+	}
+	b.last = now
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// sweep forgets clients whose buckets have refilled. It runs at most once
+// a minute, so our map does not grow with every IP we have ever seen.
+func (l *clientLimiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < time.Minute {
+		return
+	}
+	l.lastSweep = now
+	for ip, b := range l.buckets {
+		if b.tokens+now.Sub(b.last).Seconds()*l.rate >= l.burst {
+			delete(l.buckets, ip)
+		}
+	}
+}
+
+// connCounter counts open connections per backend domain.
+type connCounter struct {
+	mtx sync.Mutex
+	n   map[string]int
+}
+
+// acquire counts a connection to domain, unless max are already open. A max
+// of zero means no limit. Every successful acquire needs a release.
+func (c *connCounter) acquire(domain string, max int) bool {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	if c.n == nil {
+		c.n = make(map[string]int)
+	}
+	if max > 0 && c.n[domain] >= max {
+		return false
+	}
+	c.n[domain]++
+	active.Add(domain, 1)
+	return true
+}
+
+func (c *connCounter) release(domain string) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	c.n[domain]--
+	if c.n[domain] <= 0 {
+		delete(c.n, domain)
+	}
+	active.Add(domain, -1)
+}
+
+func clientIP(conn net.Conn) string {
+	host, _, err := net.SplitHostPort(conn.RemoteAddr().String())
+	if err != nil {
+		return conn.RemoteAddr().String()
+	}
+	return host
+}
+
+// reset closes conn with a TCP RST instead of a FIN, if we can reach the
+// underlying *net.TCPConn.
+func reset(conn net.Conn) {
+	raw := conn
+	if tc, ok := conn.(*tls.Conn); ok {
+		raw = tc.NetConn()
+	}
+	if pc, ok := raw.(*proxyConn); ok {
+		raw = pc.Conn
+	}
+	if tcp, ok := raw.(*net.TCPConn); ok {
+		tcp.SetLinger(0)
+	}
+	conn.Close()
+}
+
+const tooManyRequests = "HTTP/1.1 429 Too Many Requests\r\n" +
+	"Content-Length: 0\r\n" +
+	"Connection: close\r\n\r\n"
+
+// refuse turns away a conn over its backend's limits. HTTP1 clients get a
+// 429; everyone else gets reset.
+func refuse(conn net.Conn, http1 bool) {
+	if http1 {
+		conn.Write([]byte(tooManyRequests))
+		conn.Close()
+		return
+	}
+	reset(conn)
+}
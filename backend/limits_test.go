@@ -0,0 +1,77 @@
+package backend
+
+import (
+	"testing"
+	"time"
+)
+
+func TestClientLimiter(t *testing.T) {
+	l := newClientLimiter(1, 3)
+	for i := 0; i < 3; i++ {
+		if !l.allow("10.0.0.1") {
+			t.Fatalf("expected burst connection %d to be allowed", i)
+		}
+	}
+	if l.allow("10.0.0.1") {
+		t.Errorf("expected connection past burst to be refused")
+	}
+	if !l.allow("10.0.0.2") {
+		t.Errorf("expected another client to have its own bucket")
+	}
+
+	// Pretend a second went by; one token should be back.
+	l.buckets["10.0.0.1"].last = time.Now().Add(-time.Second)
+	if !l.allow("10.0.0.1") {
+		t.Errorf("expected a refilled token")
+	}
+	if l.allow("10.0.0.1") {
+		t.Errorf("expected only one refilled token")
+	}
+}
+
+func TestConnCounter(t *testing.T) {
+	var c connCounter
+	if !c.acquire("a.example.com", 2) || !c.acquire("a.example.com", 2) {
+		t.Fatalf("expected two connections under a max of 2")
+	}
+	if c.acquire("a.example.com", 2) {
+		t.Errorf("expected third connection to be refused")
+	}
+	if !c.acquire("b.example.com", 2) {
+		t.Errorf("expected domains to be counted separately")
+	}
+	c.release("a.example.com")
+	if !c.acquire("a.example.com", 2) {
+		t.Errorf("expected a released slot to be reusable")
+	}
+	for i := 0; i < 10; i++ {
+		if !c.acquire("c.example.com", 0) {
+			t.Fatalf("expected no limit with max of 0")
+		}
+	}
+}
+
+func TestSweepLimiters(t *testing.T) {
+	routes := NewTableRouter(NewTable([]BackendData{
+		{Domain: "a.example.com", ClientRate: 1},
+		{Domain: "b.example.com", ClientRate: 1},
+	}))
+	f, _ := NewTCPForwarder(WithRouter(routes))
+	for _, d := range []string{"a.example.com", "b.example.com"} {
+		r, _ := routes.Route(d)
+		f.backendLimiter(r)
+	}
+	// b is removed, and a's limit cleared.
+	routes.Swap(NewTable([]BackendData{{Domain: "a.example.com"}}))
+
+	f.mtx.Lock()
+	f.sweepLimiters(time.Now())
+	if len(f.backendLimiters) != 2 {
+		t.Errorf("expected no sweep within a minute of the last, got %v", f.backendLimiters)
+	}
+	f.sweepLimiters(time.Now().Add(time.Minute))
+	if len(f.backendLimiters) != 0 {
+		t.Errorf("expected both limiters forgotten, got %v", f.backendLimiters)
+	}
+	f.mtx.Unlock()
+}
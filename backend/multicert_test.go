@@ -0,0 +1,144 @@
+package backend
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/anxiousmodernman/co-chair/proto/server"
+)
+
+// testRSACert returns a self-signed RSA cert and key for domain.
+func testRSACert(t *testing.T, domain string) *server.X509Cert {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: domain},
+		DNSNames:     []string{domain},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(24 * time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &server.X509Cert{
+		Cert: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		Key:  pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)}),
+	}
+}
+
+func TestCertificateFor(t *testing.T) {
+	p, cleanup, err := NewTestProxyCleanup()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cleanup()
+
+	const domain = "www.example.com"
+	ecCert, ecKey, err := p.CA.IssueServer(domain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := &server.Backend{
+		Domain:      domain,
+		Ips:         []string{"127.0.0.1:9999"},
+		BackendCert: &server.X509Cert{Cert: ecCert, Key: ecKey},
+		ExtraCerts:  []*server.X509Cert{testRSACert(t, domain)},
+	}
+	if _, err := p.Put(context.TODO(), b); err != nil {
+		t.Fatal(err)
+	}
+	route, err := p.Router().Route(domain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(route.Certificates) != 2 {
+		t.Fatalf("expected 2 certificates, got %d", len(route.Certificates))
+	}
+
+	modern := &tls.ClientHelloInfo{
+		ServerName:        domain,
+		SupportedVersions: []uint16{tls.VersionTLS13, tls.VersionTLS12},
+		CipherSuites:      []uint16{tls.TLS_AES_128_GCM_SHA256},
+		SignatureSchemes:  []tls.SignatureScheme{tls.ECDSAWithP256AndSHA256, tls.PSSWithSHA256},
+		SupportedCurves:   []tls.CurveID{tls.X25519, tls.CurveP256},
+		SupportedPoints:   []uint8{0},
+	}
+	if c := route.CertificateFor(modern); keyType(c.Leaf) != "ECDSA P-256" {
+		t.Errorf("expected ECDSA cert for a modern client, got %s", keyType(c.Leaf))
+	}
+	rsaOnly := &tls.ClientHelloInfo{
+		ServerName:        domain,
+		SupportedVersions: []uint16{tls.VersionTLS12},
+		CipherSuites:      []uint16{tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256},
+		SignatureSchemes:  []tls.SignatureScheme{tls.PKCS1WithSHA256},
+		SupportedCurves:   []tls.CurveID{tls.CurveP256},
+		SupportedPoints:   []uint8{0},
+	}
+	if c := route.CertificateFor(rsaOnly); keyType(c.Leaf) != "RSA 2048" {
+		t.Errorf("expected RSA cert for an RSA-only client, got %s", keyType(c.Leaf))
+	}
+
+	b.BackendCert = nil
+	if _, err := p.Put(context.TODO(), b); err == nil {
+		t.Errorf("expected extra certs without a backend cert to be refused")
+	}
+}
+
+func TestStageAndPromote(t *testing.T) {
+	p, cleanup, err := NewTestProxyCleanup()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cleanup()
+
+	const domain = "internal.example.com"
+	if _, err := p.Put(context.TODO(), &server.Backend{Domain: domain, Ips: []string{"127.0.0.1:9999"}}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.PromoteCertificates(context.TODO(), &server.PromoteRequest{Domain: domain}); err == nil {
+		t.Errorf("expected error promoting with nothing staged")
+	}
+	bad := &server.StageRequest{Domain: domain, Certs: []*server.X509Cert{testRSACert(t, "other.example.com")}}
+	if _, err := p.StageCertificates(context.TODO(), bad); err == nil {
+		t.Errorf("expected staging a cert for another domain to fail")
+	}
+
+	staged := testRSACert(t, domain)
+	req := &server.StageRequest{Domain: domain, Certs: []*server.X509Cert{staged}}
+	if _, err := p.StageCertificates(context.TODO(), req); err != nil {
+		t.Fatal(err)
+	}
+	route, _ := p.Router().Route(domain)
+	if !route.CAIssued {
+		t.Errorf("expected staged cert not to be served before promotion")
+	}
+	list, err := p.ListCertificates(context.TODO(), &server.CertificatesRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list.Certificates) != 2 || !list.Certificates[1].Staged {
+		t.Errorf("expected the staged cert to be listed, got %+v", list.Certificates)
+	}
+
+	if _, err := p.PromoteCertificates(context.TODO(), &server.PromoteRequest{Domain: domain}); err != nil {
+		t.Fatal(err)
+	}
+	route, _ = p.Router().Route(domain)
+	if route.CAIssued || string(route.BackendCert) != string(staged.Cert) || len(route.StagedCerts) != 0 {
+		t.Errorf("expected the staged cert to be served after promotion")
+	}
+}
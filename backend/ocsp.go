@@ -0,0 +1,265 @@
+package backend
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/asdine/storm"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/crypto/ocsp"
+)
+
+const ocspBucket = "ocsp"
+
+// OCSPStapler fetches OCSP responses for the certificates of our Routes from
+// the responders named in them, and staples them to handshakes. Responses
+// are cached in bolt, if we have a DB, so restarts do not refetch them.
+type OCSPStapler struct {
+	DB     *storm.DB
+	routes *TableRouter
+	logger *logrus.Logger
+	client *http.Client
+	stop   chan struct{}
+
+	mtx     sync.RWMutex
+	staples map[string]*ocspStaple
+	// included are certificates we serve outside any Route.
+	included []func() *tls.Certificate
+}
+
+type ocspStaple struct {
+	der                    []byte
+	thisUpdate, nextUpdate time.Time
+}
+
+// refreshAt is halfway through a response's validity, so a failed fetch
+// has time to be retried before NextUpdate.
+func (s *ocspStaple) refreshAt() time.Time {
+	return s.thisUpdate.Add(s.nextUpdate.Sub(s.thisUpdate) / 2)
+}
+
+// NewOCSPStapler returns an OCSPStapler for the Routes in routes. db may be
+// nil, as it is on forwarders. Call Start to fetch responses.
+func NewOCSPStapler(db *storm.DB, routes *TableRouter, logger *logrus.Logger) *OCSPStapler {
+	return &OCSPStapler{
+		DB:      db,
+		routes:  routes,
+		logger:  logger,
+		client:  &http.Client{Timeout: 10 * time.Second},
+		stop:    make(chan struct{}),
+		staples: make(map[string]*ocspStaple),
+	}
+}
+
+// Staple returns cert with our OCSP response for it, if we have a current
+// one. cert itself is shared by every handshake, so we staple a copy.
+func (s *OCSPStapler) Staple(cert *tls.Certificate) *tls.Certificate {
+	if cert == nil || len(cert.Certificate) == 0 {
+		return cert
+	}
+	s.mtx.RLock()
+	staple, ok := s.staples[fingerprint(cert.Certificate[0])]
+	s.mtx.RUnlock()
+	if !ok || time.Now().After(staple.nextUpdate) {
+		return cert
+	}
+	stapled := *cert
+	stapled.OCSPStaple = staple.der
+	return &stapled
+}
+
+// Include has us staple the certificate get returns, too, such as a
+// default certificate, which no Route has.
+func (s *OCSPStapler) Include(get func() *tls.Certificate) {
+	s.mtx.Lock()
+	s.included = append(s.included, get)
+	s.mtx.Unlock()
+}
+
+// Refresh fetches a response for every certificate whose response is
+// missing or half expired. It returns the last error, after trying all.
+func (s *OCSPStapler) Refresh() error {
+	var last error
+	seen := make(map[string]bool)
+	check := func(name string, cert *tls.Certificate) {
+		if cert == nil || len(cert.Certificate) == 0 {
+			return
+		}
+		fp := fingerprint(cert.Certificate[0])
+		seen[fp] = true
+		if err := s.refresh(fp, cert); err != nil {
+			s.logger.Errorf("ocsp: %s: %v", name, err)
+			last = err
+		}
+	}
+	for _, r := range s.routes.Table().Routes() {
+		for _, cert := range r.Certificates {
+			check(r.Domain, cert)
+		}
+		if r.ACME && len(r.Certificates) == 0 {
+			check(r.Domain, s.acmeCertificate(r.Domain))
+		}
+	}
+	s.mtx.RLock()
+	included := s.included
+	s.mtx.RUnlock()
+	for _, get := range included {
+		check("default certificate", get())
+	}
+	// Forget certificates we no longer serve.
+	s.mtx.Lock()
+	for fp := range s.staples {
+		if !seen[fp] {
+			delete(s.staples, fp)
+			if s.DB != nil {
+				s.DB.Delete(ocspBucket, fp)
+			}
+		}
+	}
+	s.mtx.Unlock()
+	return last
+}
+
+// acmeCertificate is the certificate ACME got us for domain, or nil. On the
+// control plane, ACME routes have none of their own; forwarders get it
+// with the route.
+func (s *OCSPStapler) acmeCertificate(domain string) *tls.Certificate {
+	if s.DB == nil {
+		return nil
+	}
+	data, err := getSecret(s.DB, acmeBucket, domain)
+	if err != nil {
+		return nil
+	}
+	pair := splitACMECert(data)
+	cert, err := parsePair(pair.Cert, pair.Key)
+	if err != nil {
+		return nil
+	}
+	return cert
+}
+
+func (s *OCSPStapler) refresh(fp string, cert *tls.Certificate) error {
+	leaf := cert.Leaf
+	if leaf == nil {
+		var err error
+		if leaf, err = x509.ParseCertificate(cert.Certificate[0]); err != nil {
+			return fmt.Errorf("certificate: %v", err)
+		}
+	}
+	if len(leaf.OCSPServer) == 0 {
+		return nil
+	}
+	if len(cert.Certificate) < 2 {
+		return errors.New("no issuer in chain")
+	}
+	issuer, err := x509.ParseCertificate(cert.Certificate[1])
+	if err != nil {
+		return fmt.Errorf("issuer: %v", err)
+	}
+
+	s.mtx.RLock()
+	staple, ok := s.staples[fp]
+	s.mtx.RUnlock()
+	if !ok && s.DB != nil {
+		// A restart; we may have fetched it before.
+		if der, err := s.DB.GetBytes(ocspBucket, fp); err == nil {
+			staple, _ = parseStaple(der, leaf, issuer)
+		}
+	}
+	if staple != nil && time.Now().Before(staple.refreshAt()) {
+		s.store(fp, staple, false)
+		return nil
+	}
+
+	der, err := s.fetch(leaf, issuer)
+	if err != nil {
+		return err
+	}
+	fresh, err := parseStaple(der, leaf, issuer)
+	if err != nil {
+		return err
+	}
+	s.store(fp, fresh, true)
+	return nil
+}
+
+func (s *OCSPStapler) store(fp string, staple *ocspStaple, persist bool) {
+	s.mtx.Lock()
+	s.staples[fp] = staple
+	s.mtx.Unlock()
+	if persist && s.DB != nil {
+		if err := s.DB.SetBytes(ocspBucket, fp, staple.der); err != nil {
+			s.logger.Errorf("ocsp: save: %v", err)
+		}
+	}
+}
+
+// fetch asks leaf's first OCSP responder about it.
+func (s *OCSPStapler) fetch(leaf, issuer *x509.Certificate) ([]byte, error) {
+	req, err := ocsp.CreateRequest(leaf, issuer, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s.client.Post(leaf.OCSPServer[0], "application/ocsp-request", bytes.NewReader(req))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s: %s", leaf.OCSPServer[0], resp.Status)
+	}
+	return ioutil.ReadAll(resp.Body)
+}
+
+// parseStaple checks that der is a signed response for leaf that is still
+// current. We staple revoked responses, too; clients should see them.
+func parseStaple(der []byte, leaf, issuer *x509.Certificate) (*ocspStaple, error) {
+	resp, err := ocsp.ParseResponseForCert(der, leaf, issuer)
+	if err != nil {
+		return nil, err
+	}
+	if resp.Status == ocsp.Unknown {
+		return nil, errors.New("responder does not know the certificate")
+	}
+	if resp.NextUpdate.IsZero() || time.Now().After(resp.NextUpdate) {
+		return nil, errors.New("response is not current")
+	}
+	return &ocspStaple{der: der, thisUpdate: resp.ThisUpdate, nextUpdate: resp.NextUpdate}, nil
+}
+
+// Start refreshes now, then every interval until Stop is called. interval
+// should be well under the responder's validity period.
+func (s *OCSPStapler) Start(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			s.Refresh()
+			select {
+			case <-s.stop:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Stop ends background refreshes.
+func (s *OCSPStapler) Stop() {
+	close(s.stop)
+}
+
+func fingerprint(der []byte) string {
+	sum := sha256.Sum256(der)
+	return hex.EncodeToString(sum[:])
+}
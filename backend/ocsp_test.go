@@ -0,0 +1,141 @@
+package backend
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"golang.org/x/crypto/ocsp"
+)
+
+func TestOCSPStapling(t *testing.T) {
+	rootKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rootTmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test root"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	rootDER, _ := x509.CreateCertificate(rand.Reader, rootTmpl, rootTmpl, &rootKey.PublicKey, rootKey)
+	root, _ := x509.ParseCertificate(rootDER)
+
+	// Our local responder says every cert is good, for an hour.
+	var fetches int
+	responder := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		req, err := ocsp.ParseRequest(body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		fetches++
+		resp, err := ocsp.CreateResponse(root, root, ocsp.Response{
+			Status:       ocsp.Good,
+			SerialNumber: req.SerialNumber,
+			ThisUpdate:   time.Now().Add(-time.Minute),
+			NextUpdate:   time.Now().Add(time.Hour),
+		}, rootKey)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Write(resp)
+	}))
+	defer responder.Close()
+
+	leafKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	leafTmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "www.example.com"},
+		DNSNames:     []string{"www.example.com"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		OCSPServer:   []string{responder.URL},
+	}
+	leafDER, _ := x509.CreateCertificate(rand.Reader, leafTmpl, root, &leafKey.PublicKey, rootKey)
+	leafKeyPEM, err := keyPEM(leafKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	chainPEM := append(certPEM(leafDER), certPEM(rootDER)...)
+
+	routes := NewTableRouter(NewTable([]BackendData{
+		{Domain: "www.example.com", BackendCert: chainPEM, BackendKey: leafKeyPEM},
+	}))
+	stapler := NewOCSPStapler(nil, routes, logrus.New())
+	if err := stapler.Refresh(); err != nil {
+		t.Fatal(err)
+	}
+	// A fresh response is not fetched again.
+	if err := stapler.Refresh(); err != nil || fetches != 1 {
+		t.Errorf("expected one fetch, got %d: %v", fetches, err)
+	}
+
+	roots := x509.NewCertPool()
+	roots.AddCert(root)
+	checkStaple := func(f *TCPForwarder) {
+		server, client := net.Pipe()
+		go tls.Server(server, &tls.Config{GetCertificate: f.GetCertificate}).Handshake()
+		tc := tls.Client(client, &tls.Config{ServerName: "www.example.com", RootCAs: roots})
+		if err := tc.Handshake(); err != nil {
+			t.Fatal(err)
+		}
+		staple := tc.ConnectionState().OCSPResponse
+		if len(staple) == 0 {
+			t.Fatal("expected an OCSP staple in the handshake")
+		}
+		resp, err := ocsp.ParseResponseForCert(staple, tc.ConnectionState().PeerCertificates[0], root)
+		if err != nil || resp.Status != ocsp.Good {
+			t.Errorf("expected a good OCSP response, got %v", err)
+		}
+	}
+	f, _ := NewTCPForwarder(WithRouter(routes), WithLogger(logrus.New()), WithOCSPStapler(stapler))
+	checkStaple(f)
+
+	// The default certificate, which no Route has, is stapled too.
+	dir, err := ioutil.TempDir("", "ocsp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	certPath, keyPath := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	if err := ioutil.WriteFile(certPath, chainPEM, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(keyPath, leafKeyPEM, 0600); err != nil {
+		t.Fatal(err)
+	}
+	defaultCert, err := NewCertReloader(certPath, keyPath, logrus.New())
+	if err != nil {
+		t.Fatal(err)
+	}
+	empty := NewTableRouter(NewTable(nil))
+	stapler = NewOCSPStapler(nil, empty, logrus.New())
+	f, _ = NewTCPForwarder(WithRouter(empty), WithLogger(logrus.New()), WithOCSPStapler(stapler),
+		WithDefaultCertificate(defaultCert))
+	if err := stapler.Refresh(); err != nil || fetches != 2 {
+		t.Errorf("expected a fetch for the default certificate, got %d: %v", fetches, err)
+	}
+	checkStaple(f)
+}
@@ -0,0 +1,168 @@
+package backend
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/anxiousmodernman/co-chair/proto/server"
+	"github.com/asdine/storm"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// patchFields copy the Backend fields PatchBackend may set, by their
+// proto names. Nil client_auth and tls_policy are turned off, like the
+// empty values Put takes to do so.
+var patchFields = map[string]func(dst, src *server.Backend){
+	"ips":           func(dst, src *server.Backend) { dst.Ips = src.Ips },
+	"protocol":      func(dst, src *server.Backend) { dst.Protocol = src.Protocol },
+	"match_headers": func(dst, src *server.Backend) { dst.MatchHeaders = src.MatchHeaders },
+	"max_conns":     func(dst, src *server.Backend) { dst.MaxConns = src.MaxConns },
+	"client_rate":   func(dst, src *server.Backend) { dst.ClientRate = src.ClientRate },
+	"client_burst":  func(dst, src *server.Backend) { dst.ClientBurst = src.ClientBurst },
+	"allow_cidrs":   func(dst, src *server.Backend) { dst.AllowCidrs = src.AllowCidrs },
+	"deny_cidrs":    func(dst, src *server.Backend) { dst.DenyCidrs = src.DenyCidrs },
+	"acme":          func(dst, src *server.Backend) { dst.Acme = src.Acme },
+	"labels":        func(dst, src *server.Backend) { dst.Labels = src.Labels },
+	"backend_cert": func(dst, src *server.Backend) {
+		dst.BackendCert, dst.ExtraCerts = src.BackendCert, src.ExtraCerts
+	},
+	"client_auth": func(dst, src *server.Backend) {
+		dst.ClientAuth = src.ClientAuth
+		if dst.ClientAuth == nil {
+			dst.ClientAuth = &server.ClientAuth{}
+		}
+	},
+	"tls_policy": func(dst, src *server.Backend) {
+		dst.TlsPolicy = src.TlsPolicy
+		if dst.TlsPolicy == nil {
+			dst.TlsPolicy = &server.TLSPolicy{}
+		}
+	},
+}
+
+// checkRevision fails if a caller expected bd at another revision. A
+// revision of 0 skips the check.
+func checkRevision(bd BackendData, revision int64) error {
+	if revision != 0 && revision != bd.Revision {
+		return status.Errorf(codes.Aborted, "%s changed: it is at revision %d, not %d",
+			bd.Domain, bd.Revision, revision)
+	}
+	return nil
+}
+
+// PatchBackend changes the fields of an existing backend named in
+// req.UpdateMask, and adds and removes IPs, leaving everything else as it
+// is. It returns the backend as patched.
+func (p *Proxy) PatchBackend(ctx context.Context, req *server.PatchRequest) (*server.Backend, error) {
+	if req.Domain == "" {
+		return nil, invalid("domain", "is required")
+	}
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
+	var bd BackendData
+	if err := p.DB.One("Domain", req.Domain, &bd); err != nil {
+		if err == storm.ErrNotFound {
+			return nil, status.Errorf(codes.NotFound, "domain not found: %s", req.Domain)
+		}
+		return nil, fmt.Errorf("db error: %v", err)
+	}
+	if err := checkRevision(bd, req.Revision); err != nil {
+		return nil, err
+	}
+	before := bd
+
+	// Only what the mask names is passed on to updateBackend; the rest is
+	// what we have now, or nil where nil means unchanged.
+	b := bd.AsBackend()
+	b.MatchHeaders = bd.MatchHeaders
+	b.ClientAuth, b.TlsPolicy = nil, nil
+	values := req.Values
+	if values == nil {
+		values = &server.Backend{}
+	}
+	var fe fieldErrors
+	masked := make(map[string]bool)
+	for i, field := range req.UpdateMask {
+		set, ok := patchFields[field]
+		if !ok {
+			fe.add(fmt.Sprintf("update_mask[%d]", i), fmt.Errorf("unknown field %q", field))
+			continue
+		}
+		set(b, values)
+		masked[field] = true
+	}
+	// Only the masked fields are checked; what we have now was checked
+	// when it was set.
+	for _, v := range validateBackend("values.", b) {
+		if masked[maskedBy(v.Field)] {
+			fe = append(fe, v)
+		}
+	}
+	if masked["ips"] {
+		fe.checkIPs("values.ips", values.Ips)
+	}
+	fe.checkIPs("add_ips", req.AddIps)
+	if err := fe.err(); err != nil {
+		return nil, err
+	}
+	updateBackend(&bd, b)
+	if masked["backend_cert"] && values.BackendCert == nil {
+		bd.BackendCert, bd.BackendKey, bd.ExtraCerts, bd.CAIssued = nil, nil, nil, false
+	}
+	if err := p.issueCACert(&bd); err != nil {
+		return nil, err
+	}
+
+	if masked["ips"] {
+		bd.IPs = values.Ips
+	}
+	if len(req.AddIps) > 0 {
+		bd.IPs = combine(bd.IPs, req.AddIps)
+	}
+	if len(req.RemoveIps) > 0 {
+		bd.IPs = without(bd.IPs, req.RemoveIps)
+	}
+
+	if len(diffBackends(&before, &bd)) == 0 {
+		return bd.AsBackend(), nil
+	}
+	bd.Revision++
+	if err := p.commitBackend(ctx, "PatchBackend", &before, &bd); err != nil {
+		return nil, err
+	}
+	p.routes.Swap(p.routes.Table().With(bd))
+	return bd.AsBackend(), nil
+}
+
+// maskedBy names the update mask field that sets a field validateBackend
+// found invalid.
+func maskedBy(field string) string {
+	field = strings.TrimPrefix(field, "values.")
+	if i := strings.IndexAny(field, ".["); i >= 0 {
+		field = field[:i]
+	}
+	if field == "extra_certs" {
+		return "backend_cert"
+	}
+	return field
+}
+
+// without returns ips, less those in remove.
+func without(ips, remove []string) []string {
+	drop := make(map[string]bool)
+	for _, ip := range remove {
+		drop[strings.TrimSpace(ip)] = true
+	}
+	var kept []string
+	for _, ip := range ips {
+		if !drop[strings.TrimSpace(ip)] {
+			kept = append(kept, ip)
+		}
+	}
+	sort.Strings(kept)
+	return kept
+}
@@ -0,0 +1,85 @@
+package backend
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/anxiousmodernman/co-chair/proto/server"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestWithout(t *testing.T) {
+	got := without([]string{"10.0.0.2:443", "10.0.0.1:443", "10.0.0.3:443"}, []string{" 10.0.0.2:443"})
+	if want := []string{"10.0.0.1:443", "10.0.0.3:443"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
+
+func TestCheckRevision(t *testing.T) {
+	bd := BackendData{Domain: "www.example.com", Revision: 3}
+	if err := checkRevision(bd, 0); err != nil {
+		t.Errorf("expected no check without a revision: %v", err)
+	}
+	if err := checkRevision(bd, 3); err != nil {
+		t.Errorf("expected the current revision to pass: %v", err)
+	}
+	s, _ := status.FromError(checkRevision(bd, 2))
+	if s.Code() != codes.Aborted {
+		t.Errorf("expected Aborted for a stale revision, got %v", s.Code())
+	}
+}
+
+func TestPatchBackend(t *testing.T) {
+	p, cleanup, err := NewTestProxyCleanup()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cleanup()
+	b := &server.Backend{Domain: "www.example.com", Ips: []string{"10.0.0.1:443", "10.0.0.2:443"},
+		MaxConns: 10, DenyCidrs: []string{"192.0.2.0/24"}}
+	if _, err := p.Put(context.TODO(), b); err != nil {
+		t.Fatal(err)
+	}
+
+	patched, err := p.PatchBackend(context.TODO(), &server.PatchRequest{
+		Domain:     "www.example.com",
+		Values:     &server.Backend{MaxConns: 20},
+		UpdateMask: []string{"max_conns"},
+		AddIps:     []string{"10.0.0.3:443"},
+		RemoveIps:  []string{"10.0.0.1:443"},
+		Revision:   1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if patched.Revision != 2 || patched.MaxConns != 20 {
+		t.Errorf("expected revision 2 with max_conns 20, got %v", patched)
+	}
+	if want := []string{"10.0.0.2:443", "10.0.0.3:443"}; !reflect.DeepEqual(patched.Ips, want) {
+		t.Errorf("expected IPs %v, got %v", want, patched.Ips)
+	}
+	if len(patched.DenyCidrs) != 1 {
+		t.Errorf("expected fields outside the mask to be kept, got %v", patched.DenyCidrs)
+	}
+
+	// A stale revision fails, for PatchBackend and Put alike.
+	_, err = p.PatchBackend(context.TODO(), &server.PatchRequest{Domain: "www.example.com", Revision: 1})
+	if s, _ := status.FromError(err); s.Code() != codes.Aborted {
+		t.Errorf("expected Aborted, got %v", err)
+	}
+	b.Revision = 1
+	if _, err := p.Put(context.TODO(), b); err == nil {
+		t.Errorf("expected Put with a stale revision to fail")
+	}
+
+	_, err = p.PatchBackend(context.TODO(), &server.PatchRequest{Domain: "www.example.com", UpdateMask: []string{"domain"}})
+	if s, _ := status.FromError(err); s.Code() != codes.InvalidArgument {
+		t.Errorf("expected InvalidArgument for an unknown field, got %v", err)
+	}
+	_, err = p.PatchBackend(context.TODO(), &server.PatchRequest{Domain: "nope.example.com"})
+	if s, _ := status.FromError(err); s.Code() != codes.NotFound {
+		t.Errorf("expected NotFound, got %v", err)
+	}
+}
@@ -0,0 +1,167 @@
+package backend
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// proxyListener accepts conns from load balancers that speak the PROXY
+// protocol, v1 or v2. Conns from trusted peers that open with a PROXY header
+// report the client address from the header as their RemoteAddr. All other
+// conns are passed through untouched.
+type proxyListener struct {
+	net.Listener
+	trusted []*net.IPNet
+}
+
+func (l *proxyListener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	if !containsIP(l.trusted, addrIP(conn.RemoteAddr())) {
+		return conn, nil
+	}
+	return &proxyConn{Conn: conn, r: bufio.NewReader(conn)}, nil
+}
+
+// proxyConn reads its PROXY header lazily, on the first Read or RemoteAddr,
+// so a slow peer cannot stall the accept loop. Set a deadline first.
+type proxyConn struct {
+	net.Conn
+	r *bufio.Reader
+
+	once   sync.Once
+	remote net.Addr
+	err    error
+}
+
+func (c *proxyConn) init() {
+	c.once.Do(func() {
+		c.remote, c.err = readProxyHeader(c.r)
+		if c.err != nil {
+			c.err = fmt.Errorf("proxy protocol: %v", c.err)
+		}
+	})
+}
+
+func (c *proxyConn) Read(b []byte) (int, error) {
+	c.init()
+	if c.err != nil {
+		return 0, c.err
+	}
+	return c.r.Read(b)
+}
+
+// RemoteAddr is the client address from the PROXY header, if there was one.
+func (c *proxyConn) RemoteAddr() net.Addr {
+	c.init()
+	if c.remote != nil {
+		return c.remote
+	}
+	return c.Conn.RemoteAddr()
+}
+
+var proxyV2Sig = []byte("\r\n\r\n\x00\r\nQUIT\n")
+
+// readProxyHeader consumes a PROXY header from r and returns the source
+// address in it. If r does not begin with a header, or the header carries
+// no address, the returned address is nil and nothing is consumed.
+func readProxyHeader(r *bufio.Reader) (net.Addr, error) {
+	b, err := r.Peek(1)
+	if err != nil {
+		return nil, err
+	}
+	switch b[0] {
+	case 'P':
+		return readProxyV1(r)
+	case proxyV2Sig[0]:
+		return readProxyV2(r)
+	}
+	return nil, nil
+}
+
+// readProxyV1 parses the text header, e.g.
+// "PROXY TCP4 192.0.2.1 198.51.100.1 56324 443\r\n".
+func readProxyV1(r *bufio.Reader) (net.Addr, error) {
+	if b, err := r.Peek(6); err != nil || string(b) != "PROXY " {
+		return nil, nil
+	}
+	// A v1 header is at most 107 bytes.
+	var line []byte
+	for len(line) < 107 {
+		c, err := r.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		line = append(line, c)
+		if c == '\n' {
+			break
+		}
+	}
+	if !bytes.HasSuffix(line, []byte("\r\n")) {
+		return nil, errors.New("v1 header too long")
+	}
+	fields := strings.Fields(string(line))
+	if len(fields) >= 2 && fields[1] == "UNKNOWN" {
+		return nil, nil
+	}
+	if len(fields) != 6 || (fields[1] != "TCP4" && fields[1] != "TCP6") {
+		return nil, fmt.Errorf("bad v1 header: %q", line)
+	}
+	ip := net.ParseIP(fields[2])
+	port, err := strconv.Atoi(fields[4])
+	if ip == nil || err != nil {
+		return nil, fmt.Errorf("bad v1 source: %s %s", fields[2], fields[4])
+	}
+	return &net.TCPAddr{IP: ip, Port: port}, nil
+}
+
+// readProxyV2 parses the binary header. TLVs are skipped.
+func readProxyV2(r *bufio.Reader) (net.Addr, error) {
+	if b, err := r.Peek(len(proxyV2Sig)); err != nil || !bytes.Equal(b, proxyV2Sig) {
+		return nil, nil
+	}
+	hdr := make([]byte, 16)
+	if _, err := io.ReadFull(r, hdr); err != nil {
+		return nil, err
+	}
+	if hdr[12]>>4 != 2 {
+		return nil, fmt.Errorf("bad v2 version: %d", hdr[12]>>4)
+	}
+	body := make([]byte, binary.BigEndian.Uint16(hdr[14:16]))
+	if _, err := io.ReadFull(r, body); err != nil {
+		return nil, err
+	}
+	// LOCAL commands are the balancer's own health checks.
+	if hdr[12]&0xf == 0 {
+		return nil, nil
+	}
+	switch hdr[13] >> 4 {
+	case 1: // AF_INET
+		if len(body) < 12 {
+			return nil, errors.New("short v2 IPv4 address")
+		}
+		return &net.TCPAddr{
+			IP:   net.IP(body[0:4]),
+			Port: int(binary.BigEndian.Uint16(body[8:10])),
+		}, nil
+	case 2: // AF_INET6
+		if len(body) < 36 {
+			return nil, errors.New("short v2 IPv6 address")
+		}
+		return &net.TCPAddr{
+			IP:   net.IP(body[0:16]),
+			Port: int(binary.BigEndian.Uint16(body[32:34])),
+		}, nil
+	}
+	return nil, nil
+}
@@ -0,0 +1,293 @@
+package backend
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/Rudd-O/curvetls"
+	"github.com/anxiousmodernman/co-chair/proto/server"
+	"github.com/asdine/storm"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// Role is what an API client or web UI user may do.
+type Role string
+
+// Roles, from least to most privileged. Forwarders may read routes,
+// including their private keys, but change nothing.
+const (
+	RoleReadOnly  Role = "read-only"
+	RoleForwarder Role = "forwarder"
+	RoleOperator  Role = "operator"
+	RoleAdmin     Role = "admin"
+)
+
+var readMethods = []string{"State", "GetKVStream", "GetCA", "ListCertificates",
+	"GetCertificate", "GetServerKeys", "ListClientKeys", "ListRevisions", "DiffRevisions"}
+
+// roleMethods are the Proxy methods each role may call. Admins may call
+// every method.
+var roleMethods = map[Role][]string{
+	RoleReadOnly:  readMethods,
+	RoleForwarder: append([]string{"Routes", "SessionTicketKeys"}, readMethods...),
+	RoleOperator: append([]string{"Put", "PatchBackend", "Remove", "PutKVStream",
+		"StageCertificates", "PromoteCertificates", "Apply"}, readMethods...),
+}
+
+// Principals limited to domains may call these, and see only their
+// domains in the response.
+var filteredMethods = map[string]bool{"State": true, "ListCertificates": true}
+
+// publicMethods return nothing specific to a domain.
+var publicMethods = map[string]bool{"GetCA": true, "GetServerKeys": true}
+
+// ParseRole validates a role name.
+func ParseRole(s string) (Role, error) {
+	switch r := Role(s); r {
+	case RoleReadOnly, RoleForwarder, RoleOperator, RoleAdmin:
+		return r, nil
+	}
+	return "", fmt.Errorf("unknown role %q; use read-only, forwarder, operator or admin", s)
+}
+
+func validScopes(domains []string) error {
+	for _, d := range domains {
+		if d == "" || strings.Contains(strings.TrimPrefix(d, "*."), "*") {
+			return fmt.Errorf("invalid domain scope %q; use e.g. team.example.com or *.team.example.com", d)
+		}
+	}
+	return nil
+}
+
+// Principal is who is calling an RPC: an API client, or a web UI user.
+type Principal struct {
+	Name string
+	Role Role
+	// Domains limits the principal to these domains, if set. *.example.com
+	// matches every subdomain of example.com.
+	Domains []string
+}
+
+func (pr Principal) can(method string) bool {
+	if pr.Role == RoleAdmin {
+		return true
+	}
+	for _, m := range roleMethods[pr.Role] {
+		if m == method {
+			return true
+		}
+	}
+	return false
+}
+
+// inScope is true if pr may manage domain.
+func (pr Principal) inScope(domain string) bool {
+	if len(pr.Domains) == 0 {
+		return true
+	}
+	domain = strings.ToLower(domain)
+	for _, pattern := range pr.Domains {
+		pattern = strings.ToLower(pattern)
+		if pattern == domain {
+			return true
+		}
+		if strings.HasPrefix(pattern, "*.") && strings.HasSuffix(domain, pattern[1:]) {
+			return true
+		}
+	}
+	return false
+}
+
+// Identity finds the Principal making a call.
+type Identity func(ctx context.Context) (Principal, error)
+
+// KeyIdentity identifies API clients by their curvetls key. Callers whose
+// key is not in db get defaultRole, or are refused if it is blank. Keys
+// made before roles existed are admins.
+func KeyIdentity(db *storm.DB, defaultRole Role) Identity {
+	return func(ctx context.Context) (Principal, error) {
+		var pub string
+		if p, ok := peer.FromContext(ctx); ok {
+			if info, ok := p.AuthInfo.(*ClientKeyInfo); ok {
+				pub = info.Pub.String()
+			}
+		}
+		var kp KeyPair
+		err := db.One("Pub", pub, &kp)
+		if pub == "" || err == storm.ErrNotFound || isServerKey(kp.Name) {
+			if defaultRole == "" {
+				return Principal{}, errors.New("unknown client key")
+			}
+			return Principal{Name: pub, Role: defaultRole}, nil
+		}
+		if err != nil {
+			return Principal{}, fmt.Errorf("db error: %v", err)
+		}
+		if !kp.Revoked.IsZero() {
+			return Principal{}, fmt.Errorf("client key %s is revoked", kp.Name)
+		}
+		role := kp.Role
+		if role == "" {
+			role = RoleAdmin
+		}
+		return Principal{Name: kp.Name, Role: role, Domains: kp.Domains}, nil
+	}
+}
+
+// WebUserHeader carries the signed-in web UI user's email to our gRPC
+// handlers. The web server sets it; it is never taken from the browser.
+const WebUserHeader = "X-Cochair-User"
+
+// WebIdentity identifies web UI users by email. Users not in users get
+// defaultRole, or are refused if it is blank.
+func WebIdentity(users map[string]Principal, defaultRole Role) Identity {
+	return func(ctx context.Context) (Principal, error) {
+		var email string
+		if md, ok := metadata.FromIncomingContext(ctx); ok {
+			if v := md[strings.ToLower(WebUserHeader)]; len(v) > 0 {
+				email = v[0]
+			}
+		}
+		if pr, ok := users[strings.ToLower(email)]; ok && email != "" {
+			return pr, nil
+		}
+		if defaultRole == "" {
+			return Principal{}, fmt.Errorf("unknown web user %q", email)
+		}
+		return Principal{Name: email, Role: defaultRole}, nil
+	}
+}
+
+// Authorizer checks every call to our gRPC API against the caller's role
+// and domains.
+type Authorizer struct {
+	Identify Identity
+}
+
+// UnaryInterceptor authorizes unary calls. For principals limited to some
+// domains, it filters the results of State and ListCertificates.
+func (a *Authorizer) UnaryInterceptor(ctx context.Context, req interface{},
+	info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+
+	method := methodName(info.FullMethod)
+	pr, err := a.authorize(ctx, method)
+	if err != nil {
+		return nil, err
+	}
+	if apply, ok := req.(*server.ApplyRequest); ok {
+		for _, b := range apply.Backends {
+			if !pr.inScope(b.Domain) {
+				return nil, status.Errorf(codes.PermissionDenied, "%s may not manage %s", pr.Name, b.Domain)
+			}
+		}
+	} else if len(pr.Domains) > 0 && !filteredMethods[method] && !publicMethods[method] {
+		d, ok := req.(interface{ GetDomain() string })
+		if !ok {
+			return nil, status.Errorf(codes.PermissionDenied, "%s is limited to domains %v", pr.Name, pr.Domains)
+		}
+		if !pr.inScope(d.GetDomain()) {
+			return nil, status.Errorf(codes.PermissionDenied, "%s may not manage %s", pr.Name, d.GetDomain())
+		}
+	}
+	resp, err := handler(context.WithValue(ctx, principalKey{}, pr), req)
+	if err != nil || len(pr.Domains) == 0 {
+		return resp, err
+	}
+	switch r := resp.(type) {
+	case *server.ProxyState:
+		var backends []*server.Backend
+		for _, b := range r.Backends {
+			if pr.inScope(b.Domain) {
+				backends = append(backends, b)
+			}
+		}
+		r.Backends = backends
+	case *server.CertificateList:
+		var certs []*server.CertificateInfo
+		for _, c := range r.Certificates {
+			if pr.inScope(c.Domain) {
+				certs = append(certs, c)
+			}
+		}
+		r.Certificates = certs
+	}
+	return resp, nil
+}
+
+// StreamInterceptor authorizes streaming calls. Their messages carry no
+// domain, so principals limited to some domains may not make them.
+func (a *Authorizer) StreamInterceptor(srv interface{}, ss grpc.ServerStream,
+	info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+
+	pr, err := a.authorize(ss.Context(), methodName(info.FullMethod))
+	if err != nil {
+		return err
+	}
+	if len(pr.Domains) > 0 {
+		return status.Errorf(codes.PermissionDenied, "%s is limited to domains %v", pr.Name, pr.Domains)
+	}
+	return handler(srv, principalStream{ss, context.WithValue(ss.Context(), principalKey{}, pr)})
+}
+
+type principalKey struct{}
+
+// PrincipalFrom returns the Principal an Authorizer found for a call.
+func PrincipalFrom(ctx context.Context) (Principal, bool) {
+	pr, ok := ctx.Value(principalKey{}).(Principal)
+	return pr, ok
+}
+
+// principalStream is a grpc.ServerStream whose context has a Principal.
+type principalStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s principalStream) Context() context.Context {
+	return s.ctx
+}
+
+func (a *Authorizer) authorize(ctx context.Context, method string) (Principal, error) {
+	pr, err := a.Identify(ctx)
+	if err != nil {
+		return pr, status.Error(codes.Unauthenticated, err.Error())
+	}
+	if !pr.can(method) {
+		return pr, status.Errorf(codes.PermissionDenied, "%s role may not call %s", pr.Role, method)
+	}
+	return pr, nil
+}
+
+func methodName(fullMethod string) string {
+	return fullMethod[strings.LastIndex(fullMethod, "/")+1:]
+}
+
+// ClientKeyInfo is the AuthInfo of clients of our gRPC API, with the key
+// they connected with.
+type ClientKeyInfo struct {
+	credentials.AuthInfo
+	Pub curvetls.Pubkey
+}
+
+// keyRecorder is the curvetls.KeyStore we hand curvetls, to learn which key
+// a client connected with.
+type keyRecorder struct {
+	keystore curvetls.KeyStore
+	pub      curvetls.Pubkey
+	seen     bool
+}
+
+func (r *keyRecorder) Allowed(pub curvetls.Pubkey) bool {
+	r.pub, r.seen = pub, true
+	if r.keystore == nil {
+		return true
+	}
+	return r.keystore.Allowed(pub)
+}
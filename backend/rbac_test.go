@@ -0,0 +1,80 @@
+package backend
+
+import (
+	"context"
+	"testing"
+
+	"github.com/anxiousmodernman/co-chair/proto/server"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestAuthorizer(t *testing.T) {
+	team := Principal{Name: "team", Role: RoleOperator, Domains: []string{"*.team.example.com"}}
+	users := map[string]Principal{"team-lead@example.com": team}
+	a := &Authorizer{Identify: WebIdentity(users, RoleReadOnly)}
+	asUser := func(email string) context.Context {
+		return metadata.NewIncomingContext(context.TODO(), metadata.Pairs("x-cochair-user", email))
+	}
+	call := func(ctx context.Context, method string, req interface{}) (interface{}, error) {
+		info := &grpc.UnaryServerInfo{FullMethod: "/web.Proxy/" + method}
+		return a.UnaryInterceptor(ctx, req, info, func(context.Context, interface{}) (interface{}, error) {
+			return &server.ProxyState{Backends: []*server.Backend{
+				{Domain: "api.team.example.com"}, {Domain: "www.example.com"},
+			}}, nil
+		})
+	}
+	denied := func(err error) bool {
+		s, ok := status.FromError(err)
+		return err != nil && ok && s.Code() == codes.PermissionDenied
+	}
+
+	cases := []struct {
+		user, method string
+		req          interface{}
+		denied       bool
+	}{
+		{"someone@example.com", "State", &server.StateRequest{}, false},
+		{"someone@example.com", "Put", &server.Backend{Domain: "www.example.com"}, true},
+		{"someone@example.com", "Routes", &server.RoutesRequest{}, true},
+		{"team-lead@example.com", "Put", &server.Backend{Domain: "api.team.example.com"}, false},
+		{"team-lead@example.com", "Put", &server.Backend{Domain: "www.example.com"}, true},
+		{"team-lead@example.com", "Remove", &server.Backend{Domain: "team.example.com"}, true},
+		{"team-lead@example.com", "IssueClientCert", &server.IssueRequest{Name: "x"}, true},
+		{"team-lead@example.com", "GetCA", &server.CARequest{}, false},
+	}
+	for _, c := range cases {
+		_, err := call(asUser(c.user), c.method, c.req)
+		if denied(err) != c.denied {
+			t.Errorf("%s calling %s: expected denied=%v, got %v", c.user, c.method, c.denied, err)
+		}
+	}
+
+	resp, err := call(asUser("team-lead@example.com"), "State", &server.StateRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b := resp.(*server.ProxyState).Backends; len(b) != 1 || b[0].Domain != "api.team.example.com" {
+		t.Errorf("expected State filtered to the team's domains, got %v", b)
+	}
+
+	stream := &grpc.StreamServerInfo{FullMethod: "/web.Proxy/PutKVStream"}
+	err = a.StreamInterceptor(nil, fakeStream{asUser("team-lead@example.com")}, stream,
+		func(interface{}, grpc.ServerStream) error { return nil })
+	if !denied(err) {
+		t.Errorf("expected streams to be denied to principals limited to domains, got %v", err)
+	}
+}
+
+type fakeStream struct {
+	ctx context.Context
+}
+
+func (s fakeStream) Context() context.Context     { return s.ctx }
+func (s fakeStream) SetHeader(metadata.MD) error  { return nil }
+func (s fakeStream) SendHeader(metadata.MD) error { return nil }
+func (s fakeStream) SetTrailer(metadata.MD)       {}
+func (s fakeStream) SendMsg(interface{}) error    { return nil }
+func (s fakeStream) RecvMsg(interface{}) error    { return nil }
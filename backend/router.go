@@ -0,0 +1,115 @@
+package backend
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/anxiousmodernman/co-chair/proto/server"
+	"github.com/asdine/storm"
+	"github.com/sirupsen/logrus"
+)
+
+// ErrNoRoute is returned by a Router for a domain it has no backend for.
+var ErrNoRoute = errors.New("no route")
+
+// A Router resolves a domain to its Route. The TCPForwarder consults its
+// Router to pick a backend and a certificate for every connection.
+type Router interface {
+	Route(domain string) (*Route, error)
+}
+
+// StormRouter routes by querying a storm database on every lookup. Prefer
+// a TableRouter on hot paths; see Proxy.Router.
+type StormRouter struct {
+	DB *storm.DB
+}
+
+// Route looks up domain in the database, falling back to a wildcard domain.
+func (r *StormRouter) Route(domain string) (*Route, error) {
+	var bd BackendData
+	err := r.DB.One("Domain", domain, &bd)
+	if err == storm.ErrNotFound {
+		if w, ok := wildcard(domain); ok {
+			err = r.DB.One("Domain", w, &bd)
+		}
+	}
+	if err != nil {
+		if err == storm.ErrNotFound {
+			return nil, ErrNoRoute
+		}
+		return nil, err
+	}
+	return compileRoute(bd), nil
+}
+
+// DefaultSyncInterval is how often a RemoteRouter polls its control plane.
+const DefaultSyncInterval = 10 * time.Second
+
+// RemoteRouter routes for forwarders that run only the data plane. It syncs
+// the RouteTable from a co-chair control plane over gRPC and caches it in
+// memory as a Table. If the control plane is unreachable, the last synced
+// Table keeps serving.
+type RemoteRouter struct {
+	*TableRouter
+	C        server.ProxyClient
+	Interval time.Duration
+	logger   *logrus.Logger
+	stop     chan struct{}
+}
+
+// NewRemoteRouter returns a RemoteRouter with an empty Table. Call Sync or
+// Start to populate it.
+func NewRemoteRouter(pc server.ProxyClient, interval time.Duration, logger *logrus.Logger) *RemoteRouter {
+	if interval <= 0 {
+		interval = DefaultSyncInterval
+	}
+	return &RemoteRouter{
+		TableRouter: NewTableRouter(NewTable(nil)),
+		C:           pc,
+		Interval:    interval,
+		logger:      logger,
+		stop:        make(chan struct{}),
+	}
+}
+
+// Sync fetches the RouteTable from the control plane once, and replaces
+// the cached Table with it. On error, the cache is left alone.
+func (r *RemoteRouter) Sync() error {
+	ctx, cancel := context.WithTimeout(context.Background(), r.Interval)
+	defer cancel()
+	rt, err := r.C.Routes(ctx, &server.RoutesRequest{})
+	if err != nil {
+		return fmt.Errorf("sync routes: %v", err)
+	}
+	backends := make([]BackendData, 0, len(rt.Backends))
+	for _, b := range rt.Backends {
+		backends = append(backends, BackendDataFromRoute(b))
+	}
+	r.Swap(NewTable(backends))
+	return nil
+}
+
+// Start syncs every Interval in the background until Stop is called.
+func (r *RemoteRouter) Start() {
+	go func() {
+		ticker := time.NewTicker(r.Interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-r.stop:
+				return
+			case <-ticker.C:
+				if err := r.Sync(); err != nil {
+					r.logger.Errorf("serving cached routes: %v", err)
+				}
+			}
+		}
+	}()
+}
+
+// Stop ends background syncing.
+func (r *RemoteRouter) Stop() {
+	close(r.stop)
+}
@@ -0,0 +1,46 @@
+package backend
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/anxiousmodernman/co-chair/proto/server"
+	"github.com/sirupsen/logrus"
+)
+
+func TestRemoteRouter(t *testing.T) {
+	_, pc, cleanup := grpcListenerClientCleanup()
+	defer cleanup()
+
+	_, signed := testNewCAAndCert(t)
+	b := makeBackend(server.Backend_HTTP2, "server1", "127.0.0.1:9999", signed.Cert, signed.PrivateKey)
+	if _, err := pc.Put(context.TODO(), b); err != nil {
+		t.Fatal(err)
+	}
+
+	r := NewRemoteRouter(pc, time.Second, logrus.New())
+	if _, err := r.Route("server1"); err != ErrNoRoute {
+		t.Errorf("expected ErrNoRoute before sync, got %v", err)
+	}
+	if err := r.Sync(); err != nil {
+		t.Fatal(err)
+	}
+	bd, err := r.Route("server1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(bd.BackendKey, signed.PrivateKey) {
+		t.Errorf("expected synced route to carry the private key")
+	}
+
+	// With the control plane gone, a failed sync leaves the cache alone.
+	cleanup()
+	if err := r.Sync(); err == nil {
+		t.Errorf("expected sync error with control plane down")
+	}
+	if _, err := r.Route("server1"); err != nil {
+		t.Errorf("expected cached route, got %v", err)
+	}
+}
@@ -0,0 +1,401 @@
+package backend
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/asdine/storm"
+	"github.com/asdine/storm/codec/json"
+	bolt "github.com/coreos/bbolt"
+)
+
+// Secrets in bolt are encrypted with a random data key. The data key is
+// stored in bolt too, encrypted with a master key that is not. Changing the
+// master key only re-encrypts the data key.
+
+// MasterKeyEnv and MasterKeyCredential are where we look for a master key
+// if no file is given: an environment variable, then a systemd credential.
+const (
+	MasterKeyEnv        = "COCHAIR_MASTER_KEY"
+	MasterKeyCredential = "cochair-master-key"
+)
+
+const (
+	keysBucket  = "__cochair_keys"
+	dataKeyName = "data_key"
+)
+
+// sealedMagic prefixes every value we encrypt, so we can tell them from
+// values written before the database was encrypted.
+var sealedMagic = []byte("cochair-sealed:1:")
+
+// sealedBuckets hold records with secrets: private keys, including those in
+// revisions of our configuration, ACME account and certificate keys, and
+// session ticket keys. Their records are sealed when a database is first
+// encrypted. Records in other buckets are sealed as they are written.
+var sealedBuckets = []string{"BackendData", "Revision", "KeyPair", "CAKeyPair", "TicketKey", acmeBucket}
+
+var (
+	// ErrEncrypted is returned when opening an encrypted database without
+	// a master key.
+	ErrEncrypted = errors.New("database is encrypted; a master key is required")
+	// ErrWrongMasterKey is returned when the master key does not decrypt
+	// the database's data key.
+	ErrWrongMasterKey = errors.New("master key does not match this database")
+)
+
+// LoadMasterKey reads a base64-encoded 32 byte master key from path or, if
+// path is blank, from MasterKeyEnv or the systemd credential
+// MasterKeyCredential. It returns nil if none is configured.
+func LoadMasterKey(path string) ([]byte, error) {
+	var encoded string
+	switch {
+	case path != "":
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("master key: %v", err)
+		}
+		encoded = string(data)
+	case os.Getenv(MasterKeyEnv) != "":
+		encoded = os.Getenv(MasterKeyEnv)
+	case os.Getenv("CREDENTIALS_DIRECTORY") != "":
+		data, err := ioutil.ReadFile(filepath.Join(os.Getenv("CREDENTIALS_DIRECTORY"), MasterKeyCredential))
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("master key: %v", err)
+		}
+		encoded = string(data)
+	default:
+		return nil, nil
+	}
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+	if err != nil {
+		return nil, fmt.Errorf("master key: %v", err)
+	}
+	if len(key) != 32 {
+		return nil, fmt.Errorf("master key: need 32 bytes, got %d", len(key))
+	}
+	return key, nil
+}
+
+// Sealer encrypts and decrypts with AES-256-GCM.
+type Sealer struct {
+	aead cipher.AEAD
+}
+
+// NewSealer returns a Sealer for a 32 byte key.
+func NewSealer(key []byte) (*Sealer, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &Sealer{aead: aead}, nil
+}
+
+// Seal encrypts plain, prefixed with sealedMagic and a random nonce.
+func (s *Sealer) Seal(plain []byte) ([]byte, error) {
+	nonce := make([]byte, s.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	out := append(append([]byte{}, sealedMagic...), nonce...)
+	return s.aead.Seal(out, nonce, plain, nil), nil
+}
+
+// Open decrypts what Seal returned.
+func (s *Sealer) Open(data []byte) ([]byte, error) {
+	if !isSealed(data) {
+		return nil, errors.New("value is not sealed")
+	}
+	data = data[len(sealedMagic):]
+	n := s.aead.NonceSize()
+	if len(data) < n {
+		return nil, errors.New("sealed value is too short")
+	}
+	return s.aead.Open(nil, data[:n], data[n:], nil)
+}
+
+func isSealed(data []byte) bool {
+	return bytes.HasPrefix(data, sealedMagic)
+}
+
+// sealedCodec is storm's JSON codec, sealing records when we have a data
+// key. Records written before the database was encrypted are still read.
+type sealedCodec struct {
+	sealer *Sealer
+}
+
+func (c *sealedCodec) Marshal(v interface{}) ([]byte, error) {
+	b, err := json.Codec.Marshal(v)
+	if err != nil || c.sealer == nil {
+		return b, err
+	}
+	return c.sealer.Seal(b)
+}
+
+func (c *sealedCodec) Unmarshal(b []byte, v interface{}) error {
+	if isSealed(b) {
+		if c.sealer == nil {
+			return ErrEncrypted
+		}
+		plain, err := c.sealer.Open(b)
+		if err != nil {
+			return fmt.Errorf("decrypt: %v", err)
+		}
+		b = plain
+	}
+	return json.Codec.Unmarshal(b, v)
+}
+
+// Name is JSON's, since that is what storm recorded for our buckets.
+func (c *sealedCodec) Name() string {
+	return json.Codec.Name()
+}
+
+// OpenDB opens the bolt database at path. If masterKey is set, secrets are
+// encrypted, and secrets written before are encrypted now. An encrypted
+// database cannot be opened without its master key.
+func OpenDB(path string, masterKey []byte) (*storm.DB, error) {
+	c := &sealedCodec{}
+	db, err := storm.Open(path, storm.Codec(c))
+	if err != nil {
+		return nil, err
+	}
+	if c.sealer, err = unlock(db, masterKey); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return db, nil
+}
+
+// unlock returns the Sealer for db's data key, making one if db is not yet
+// encrypted.
+func unlock(db *storm.DB, masterKey []byte) (*Sealer, error) {
+	dataKey, err := unwrapDataKey(db, masterKey)
+	if err != nil {
+		return nil, err
+	}
+	if dataKey != nil {
+		return NewSealer(dataKey)
+	}
+	if masterKey == nil {
+		return nil, nil
+	}
+	kek, err := NewSealer(masterKey)
+	if err != nil {
+		return nil, err
+	}
+	return encryptDB(db, kek)
+}
+
+// unwrapDataKey returns db's data key, or nil if db is not encrypted.
+func unwrapDataKey(db *storm.DB, masterKey []byte) ([]byte, error) {
+	wrapped, err := db.GetBytes(keysBucket, dataKeyName)
+	if err == storm.ErrNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("data key: %v", err)
+	}
+	if masterKey == nil {
+		return nil, ErrEncrypted
+	}
+	kek, err := NewSealer(masterKey)
+	if err != nil {
+		return nil, err
+	}
+	dataKey, err := kek.Open(wrapped)
+	if err != nil {
+		return nil, ErrWrongMasterKey
+	}
+	return dataKey, nil
+}
+
+// encryptDB makes a data key for db, wrapped with kek, and seals every
+// secret in it.
+func encryptDB(db *storm.DB, kek *Sealer) (*Sealer, error) {
+	dataKey := make([]byte, 32)
+	if _, err := rand.Read(dataKey); err != nil {
+		return nil, err
+	}
+	sealer, err := NewSealer(dataKey)
+	if err != nil {
+		return nil, err
+	}
+	wrapped, err := kek.Seal(dataKey)
+	if err != nil {
+		return nil, err
+	}
+	err = db.Bolt.Update(func(tx *bolt.Tx) error {
+		if err := reseal(tx, nil, sealer); err != nil {
+			return err
+		}
+		return putDataKey(tx, wrapped)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("encrypt database: %v", err)
+	}
+	return sealer, nil
+}
+
+// Rekey wraps the data key of the database at path with newKey, so that
+// it opens with newKey instead of oldKey. If rotateData is set, every
+// secret is re-encrypted with a new data key, too. An unencrypted
+// database is encrypted.
+func Rekey(path string, oldKey, newKey []byte, rotateData bool) error {
+	if newKey == nil {
+		return errors.New("rekey: a new master key is required")
+	}
+	kek, err := NewSealer(newKey)
+	if err != nil {
+		return err
+	}
+	db, err := storm.Open(path)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	dataKey, err := unwrapDataKey(db, oldKey)
+	if err != nil {
+		return err
+	}
+	if dataKey == nil {
+		_, err := encryptDB(db, kek)
+		return err
+	}
+	return db.Bolt.Update(func(tx *bolt.Tx) error {
+		if rotateData {
+			old, err := NewSealer(dataKey)
+			if err != nil {
+				return err
+			}
+			dataKey = make([]byte, 32)
+			if _, err := rand.Read(dataKey); err != nil {
+				return err
+			}
+			sealer, err := NewSealer(dataKey)
+			if err != nil {
+				return err
+			}
+			if err := reseal(tx, old, sealer); err != nil {
+				return err
+			}
+		}
+		wrapped, err := kek.Seal(dataKey)
+		if err != nil {
+			return err
+		}
+		return putDataKey(tx, wrapped)
+	})
+}
+
+// reseal re-encrypts every sealed record, in any bucket, from one data key
+// to another, and seals the records of sealedBuckets that are not sealed
+// yet. A nil from means records are not yet sealed.
+func reseal(tx *bolt.Tx, from, to *Sealer) error {
+	secret := make(map[string]bool)
+	for _, name := range sealedBuckets {
+		secret[name] = true
+	}
+	var names []string
+	tx.ForEach(func(name []byte, _ *bolt.Bucket) error {
+		if string(name) != keysBucket {
+			names = append(names, string(name))
+		}
+		return nil
+	})
+	for _, name := range names {
+		b := tx.Bucket([]byte(name))
+		updated := make(map[string][]byte)
+		err := b.ForEach(func(k, v []byte) error {
+			// Nested buckets, such as storm's indexes, have no value.
+			if v == nil {
+				return nil
+			}
+			plain := v
+			if isSealed(v) {
+				if from == nil {
+					return nil
+				}
+				var err error
+				if plain, err = from.Open(v); err != nil {
+					return fmt.Errorf("%s: %v", name, err)
+				}
+			} else if !secret[name] {
+				// Written before we were encrypted, and not secret.
+				return nil
+			}
+			sealed, err := to.Seal(plain)
+			if err != nil {
+				return err
+			}
+			updated[string(k)] = sealed
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		for k, v := range updated {
+			if err := b.Put([]byte(k), v); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func putDataKey(tx *bolt.Tx, wrapped []byte) error {
+	b, err := tx.CreateBucketIfNotExists([]byte(keysBucket))
+	if err != nil {
+		return err
+	}
+	return b.Put([]byte(dataKeyName), wrapped)
+}
+
+// dbSealer returns the Sealer db was opened with, or nil if its secrets
+// are not encrypted.
+func dbSealer(db *storm.DB) *Sealer {
+	if c, ok := db.Codec().(*sealedCodec); ok {
+		return c.sealer
+	}
+	return nil
+}
+
+// getSecret and setSecret are storm's GetBytes and SetBytes, sealing the
+// value if db is encrypted.
+func getSecret(db *storm.DB, bucket, key string) ([]byte, error) {
+	data, err := db.GetBytes(bucket, key)
+	if err != nil || !isSealed(data) {
+		return data, err
+	}
+	s := dbSealer(db)
+	if s == nil {
+		return nil, ErrEncrypted
+	}
+	return s.Open(data)
+}
+
+func setSecret(db *storm.DB, bucket, key string, value []byte) error {
+	if s := dbSealer(db); s != nil {
+		var err error
+		if value, err = s.Seal(value); err != nil {
+			return err
+		}
+	}
+	return db.SetBytes(bucket, key, value)
+}
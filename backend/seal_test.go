@@ -0,0 +1,136 @@
+package backend
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	bolt "github.com/coreos/bbolt"
+)
+
+func TestSealer(t *testing.T) {
+	key := make([]byte, 32)
+	rand.Read(key)
+	s, err := NewSealer(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sealed, err := s.Seal([]byte("private key"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(sealed, []byte("private key")) {
+		t.Fatal("expected the plaintext to be encrypted")
+	}
+	plain, err := s.Open(sealed)
+	if err != nil || string(plain) != "private key" {
+		t.Errorf("expected to open what we sealed, got %q: %v", plain, err)
+	}
+	sealed[len(sealed)-1] ^= 1
+	if _, err := s.Open(sealed); err == nil {
+		t.Errorf("expected a tampered value to fail")
+	}
+}
+
+func TestLoadMasterKey(t *testing.T) {
+	key := make([]byte, 32)
+	rand.Read(key)
+	os.Setenv(MasterKeyEnv, base64.StdEncoding.EncodeToString(key))
+	defer os.Unsetenv(MasterKeyEnv)
+	got, err := LoadMasterKey("")
+	if err != nil || !bytes.Equal(got, key) {
+		t.Errorf("expected the key from %s: %v", MasterKeyEnv, err)
+	}
+
+	os.Setenv(MasterKeyEnv, base64.StdEncoding.EncodeToString(key[:16]))
+	if _, err := LoadMasterKey(""); err == nil {
+		t.Errorf("expected a short key to fail")
+	}
+}
+
+func TestEncryptDB(t *testing.T) {
+	dir, err := ioutil.TempDir("", "testdb")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "co-chair-test.db")
+
+	// A plaintext database, with the server's KeyPair in it.
+	p, err := NewProxy(path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var kp KeyPair
+	if err := p.DB.One("Name", "server", &kp); err != nil {
+		t.Fatal(err)
+	}
+	p.DB.Close()
+
+	masterKey, newKey := make([]byte, 32), make([]byte, 32)
+	rand.Read(masterKey)
+	rand.Read(newKey)
+
+	// Opening with a master key encrypts what is there.
+	db, err := OpenDB(path, masterKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.Bolt.View(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte("KeyPair")).ForEach(func(k, v []byte) error {
+			if v != nil && bytes.Contains(v, []byte(kp.Priv)) {
+				t.Errorf("expected the private key to be encrypted in bolt")
+			}
+			return nil
+		})
+	})
+	var got KeyPair
+	if err := db.One("Name", "server", &got); err != nil || got.Priv != kp.Priv {
+		t.Errorf("expected to read the KeyPair back: %v", err)
+	}
+	// Records outside sealedBuckets are sealed as they are written.
+	if err := db.Save(&AuditEvent{Actor: "alice", Method: "Put"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Save(&IssuedCert{Serial: "01", Name: "bob"}); err != nil {
+		t.Fatal(err)
+	}
+	db.Close()
+
+	if _, err := OpenDB(path, nil); err != ErrEncrypted {
+		t.Errorf("expected ErrEncrypted without a master key, got %v", err)
+	}
+	if _, err := OpenDB(path, newKey); err != ErrWrongMasterKey {
+		t.Errorf("expected ErrWrongMasterKey, got %v", err)
+	}
+
+	for _, rotate := range []bool{false, true} {
+		if err := Rekey(path, masterKey, newKey, rotate); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := OpenDB(path, masterKey); err != ErrWrongMasterKey {
+			t.Errorf("expected the old master key to fail after rekey, got %v", err)
+		}
+		db, err := OpenDB(path, newKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := db.One("Name", "server", &got); err != nil || got.Priv != kp.Priv {
+			t.Errorf("rotate=%v: expected to read the KeyPair back: %v", rotate, err)
+		}
+		var events []AuditEvent
+		if err := db.All(&events); err != nil || len(events) != 1 || events[0].Actor != "alice" {
+			t.Errorf("rotate=%v: expected to read the AuditEvent back, got %v: %v", rotate, events, err)
+		}
+		var ic IssuedCert
+		if err := db.One("Serial", "01", &ic); err != nil || ic.Name != "bob" {
+			t.Errorf("rotate=%v: expected to read the IssuedCert back: %v", rotate, err)
+		}
+		db.Close()
+		masterKey, newKey = newKey, masterKey
+	}
+}
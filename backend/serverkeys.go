@@ -0,0 +1,246 @@
+package backend
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/Rudd-O/curvetls"
+	"github.com/anxiousmodernman/co-chair/proto/server"
+	"github.com/asdine/storm"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/status"
+)
+
+// nextServerKey is the name of the KeyPair we rotate the server's key to.
+// Until it is retired, the "server" KeyPair keeps working.
+const nextServerKey = "server-next"
+
+func isServerKey(name string) bool {
+	return name == "server" || name == nextServerKey
+}
+
+// GetServerKeys returns our public keys, including the next one during a
+// rotation.
+func (p *Proxy) GetServerKeys(_ context.Context, _ *server.ServerKeysRequest) (*server.ServerKeys, error) {
+	return serverKeys(p.DB)
+}
+
+// RotateServerKey makes our next keypair. Until RetireServerKey, clients
+// may connect with either key.
+func (p *Proxy) RotateServerKey(ctx context.Context, _ *server.ServerKeysRequest) (*server.ServerKeys, error) {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
+	var kp KeyPair
+	err := p.DB.One("Name", nextServerKey, &kp)
+	if err == nil {
+		return nil, status.Error(codes.FailedPrecondition, "a server key rotation is in progress; retire the old key first")
+	}
+	if err != storm.ErrNotFound {
+		return nil, fmt.Errorf("db error: %v", err)
+	}
+	priv, pub, err := curvetls.GenKeyPair()
+	if err != nil {
+		return nil, err
+	}
+	kp = KeyPair{Name: nextServerKey, Pub: pub.String(), Priv: priv.String(), Created: time.Now()}
+	err = p.auditOne(ctx, "RotateServerKey", "server", func(tx storm.Node) ([]FieldChange, error) {
+		if err := tx.Save(&kp); err != nil {
+			return nil, fmt.Errorf("save: %v", err)
+		}
+		return []FieldChange{{Field: "Next", After: kp.Pub}}, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return serverKeys(p.DB)
+}
+
+// RetireServerKey makes our next keypair the current one. Clients still
+// using the old key can no longer connect.
+func (p *Proxy) RetireServerKey(ctx context.Context, _ *server.ServerKeysRequest) (*server.ServerKeys, error) {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
+	var next KeyPair
+	if err := p.DB.One("Name", nextServerKey, &next); err != nil {
+		if err == storm.ErrNotFound {
+			return nil, status.Error(codes.FailedPrecondition, "no server key rotation in progress")
+		}
+		return nil, fmt.Errorf("db error: %v", err)
+	}
+	tx, err := p.DB.Begin(true)
+	if err != nil {
+		return nil, fmt.Errorf("db error: %v", err)
+	}
+	defer tx.Rollback()
+	if err := tx.DeleteStruct(&next); err != nil {
+		return nil, fmt.Errorf("delete: %v", err)
+	}
+	next.Name = "server"
+	if err := tx.Save(&next); err != nil {
+		return nil, fmt.Errorf("save: %v", err)
+	}
+	e, err := p.audit(ctx, tx, "RetireServerKey", "server", []FieldChange{{Field: "Current", After: next.Pub}})
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("commit: %v", err)
+	}
+	p.mirror(e)
+	return serverKeys(p.DB)
+}
+
+func serverKeys(db *storm.DB) (*server.ServerKeys, error) {
+	var keys server.ServerKeys
+	var kp KeyPair
+	if err := db.One("Name", "server", &kp); err != nil {
+		return nil, fmt.Errorf("server key: %v", err)
+	}
+	keys.Current = kp.Pub
+	err := db.One("Name", nextServerKey, &kp)
+	switch {
+	case err == nil:
+		keys.Next = kp.Pub
+		keys.NextCreated = kp.Created.Unix()
+	case err != storm.ErrNotFound:
+		return nil, fmt.Errorf("db error: %v", err)
+	}
+	return &keys, nil
+}
+
+// NewServerCredentials returns curvetls credentials for our gRPC API. Keys
+// are looked up for every connection, so a rotation needs no restart.
+func NewServerCredentials(db *storm.DB, keystore curvetls.KeyStore) (credentials.TransportCredentials, error) {
+	pub, priv, err := RetrieveServerKeys(db)
+	if err != nil {
+		return nil, err
+	}
+	return &serverCredentials{
+		TransportCredentials: curvetls.NewGRPCServerCredentials(pub, priv, keystore),
+		db:                   db,
+		keystore:             keystore,
+	}, nil
+}
+
+// serverCredentials tries each of our keys in turn. A client's HELLO is
+// encrypted to the key it knows, so a handshake with the other key fails
+// there, before we have sent anything that depends on our key. We replay
+// what the client sent to the next attempt.
+type serverCredentials struct {
+	credentials.TransportCredentials
+	db       *storm.DB
+	keystore curvetls.KeyStore
+}
+
+func (c *serverCredentials) ServerHandshake(raw net.Conn) (net.Conn, credentials.AuthInfo, error) {
+	var kps []KeyPair
+	for _, name := range []string{"server", nextServerKey} {
+		var kp KeyPair
+		if err := c.db.One("Name", name, &kp); err == nil {
+			kps = append(kps, kp)
+		}
+	}
+	if len(kps) == 0 {
+		return nil, nil, errors.New("no server key")
+	}
+	conn := &replayConn{Conn: raw, record: true}
+	var err error
+	for i, kp := range kps {
+		if i > 0 {
+			conn.replay()
+		}
+		pub, perr := curvetls.PubkeyFromString(kp.Pub)
+		priv, kerr := curvetls.PrivkeyFromString(kp.Priv)
+		if perr != nil || kerr != nil {
+			err = fmt.Errorf("server key %s is invalid", kp.Name)
+			continue
+		}
+		rec := &keyRecorder{keystore: c.keystore}
+		creds := curvetls.NewGRPCServerCredentials(pub, priv, rec)
+		var secure net.Conn
+		var info credentials.AuthInfo
+		secure, info, err = creds.ServerHandshake(conn)
+		if err == nil {
+			conn.done()
+			if rec.seen {
+				info = &ClientKeyInfo{AuthInfo: info, Pub: rec.pub}
+			}
+			return secure, info, nil
+		}
+	}
+	return nil, nil, err
+}
+
+func (c *serverCredentials) Clone() credentials.TransportCredentials {
+	clone := *c
+	clone.TransportCredentials = c.TransportCredentials.Clone()
+	return &clone
+}
+
+// replayConn records a handshake so it can be tried again. A replay reads
+// what was read before, and swallows what was written before, which must
+// be written the same way again.
+type replayConn struct {
+	net.Conn
+	record  bool
+	read    []byte
+	written []byte
+	// pending is yet to be read again; sent is yet to be written again.
+	pending []byte
+	sent    []byte
+}
+
+func (c *replayConn) replay() {
+	c.pending, c.read = c.read, nil
+	c.sent, c.written = c.written, nil
+}
+
+// done stops recording, once a handshake succeeds.
+func (c *replayConn) done() {
+	c.record = false
+	c.read, c.written = nil, nil
+}
+
+func (c *replayConn) Read(b []byte) (int, error) {
+	var n int
+	var err error
+	if len(c.pending) > 0 {
+		n = copy(b, c.pending)
+		c.pending = c.pending[n:]
+	} else {
+		n, err = c.Conn.Read(b)
+	}
+	if c.record {
+		c.read = append(c.read, b[:n]...)
+	}
+	return n, err
+}
+
+func (c *replayConn) Write(b []byte) (int, error) {
+	if c.record {
+		c.written = append(c.written, b...)
+	}
+	out := b
+	if len(c.sent) > 0 {
+		n := len(c.sent)
+		if n > len(out) {
+			n = len(out)
+		}
+		if !bytes.Equal(out[:n], c.sent[:n]) {
+			return 0, errors.New("handshake replay diverged")
+		}
+		c.sent, out = c.sent[n:], out[n:]
+	}
+	if len(out) == 0 {
+		return len(b), nil
+	}
+	n, err := c.Conn.Write(out)
+	return len(b) - len(out) + n, err
+}
@@ -0,0 +1,96 @@
+package backend
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"net"
+	"testing"
+
+	"github.com/anxiousmodernman/co-chair/proto/server"
+)
+
+func TestRotateServerKey(t *testing.T) {
+	p, cleanup, err := NewTestProxyCleanup()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cleanup()
+
+	keys, err := p.RotateServerKey(context.TODO(), &server.ServerKeysRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if keys.Next == "" || keys.Next == keys.Current {
+		t.Fatalf("expected a new next key, got %v", keys)
+	}
+	if _, err := p.RotateServerKey(context.TODO(), &server.ServerKeysRequest{}); err == nil {
+		t.Errorf("expected an error rotating during a rotation")
+	}
+	if _, err := p.CreateClientKey(context.TODO(), &server.ClientKeyRequest{Name: nextServerKey, Role: "admin"}); err == nil {
+		t.Errorf("expected an error creating a client key named %s", nextServerKey)
+	}
+
+	retired, err := p.RetireServerKey(context.TODO(), &server.ServerKeysRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if retired.Current != keys.Next || retired.Next != "" {
+		t.Errorf("expected the next key to be current, got %v", retired)
+	}
+	pub, _, err := RetrieveServerKeys(p.DB)
+	if err != nil || pub.String() != keys.Next {
+		t.Errorf("expected to retrieve the new key: %v", err)
+	}
+}
+
+func TestReplayConn(t *testing.T) {
+	s, c := net.Pipe()
+	defer s.Close()
+	conn := &replayConn{Conn: s, record: true}
+
+	// The client sends a greeting and a hello, and reads everything we
+	// send back.
+	go func() {
+		c.Write([]byte("greet"))
+		c.Write([]byte("hello"))
+		c.Write([]byte("data"))
+	}()
+	got := make(chan string)
+	go func() {
+		b, _ := ioutil.ReadAll(c)
+		got <- string(b)
+	}()
+
+	// A handshake that fails after reading the hello, and one that
+	// succeeds. Both send the same greeting first.
+	handshake := func(ok bool) bool {
+		conn.Write([]byte("GREET"))
+		buf := make([]byte, 10)
+		if _, err := io.ReadFull(conn, buf); err != nil || string(buf) != "greethello" {
+			t.Fatalf("expected to read the greeting and hello, got %q: %v", buf, err)
+		}
+		if ok {
+			conn.Write([]byte("WELCOME"))
+		}
+		return ok
+	}
+	if handshake(false) {
+		t.Fatal("expected the first handshake to fail")
+	}
+	conn.replay()
+	handshake(true)
+	conn.done()
+
+	buf := make([]byte, 4)
+	if _, err := io.ReadFull(conn, buf); err != nil || string(buf) != "data" {
+		t.Errorf("expected data after the handshake, got %q: %v", buf, err)
+	}
+	s.Close()
+	if sent := <-got; sent != "GREETWELCOME" {
+		t.Errorf("expected the client to see one greeting, got %q", sent)
+	}
+	if len(conn.read) != 0 || len(conn.pending) != 0 {
+		t.Errorf("expected nothing recorded after the handshake")
+	}
+}
@@ -0,0 +1,239 @@
+package backend
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/anxiousmodernman/co-chair/proto/server"
+	"github.com/asdine/storm"
+)
+
+// State reports the backends we route, filtered and sorted as req asks, a
+// page at a time. Principals limited to some domains only see those.
+func (p *Proxy) State(ctx context.Context, req *server.StateRequest) (*server.ProxyState, error) {
+	f, err := newStateFilter(req)
+	if err != nil {
+		return nil, err
+	}
+	f.principal, _ = PrincipalFrom(ctx)
+	if req.PageSize < 0 {
+		return nil, invalid("page_size", "must not be negative")
+	}
+	var after *pageToken
+	if req.PageToken != "" {
+		t, err := decodePageToken(req.PageToken)
+		if err != nil {
+			return nil, invalid("page_token", "%v", err)
+		}
+		if t.Sort != req.Sort || t.Descending != req.Descending {
+			return nil, invalid("page_token", "was made with another sort")
+		}
+		after = &t
+	}
+
+	var backends []BackendData
+	err = p.DB.Select(f).Find(&backends)
+	if err != nil && err != storm.ErrNotFound {
+		return nil, fmt.Errorf("domain: %s; db error: %v", req.Domain, err)
+	}
+
+	// Cert expiry is read from the certs themselves, so we only look when
+	// asked to.
+	needExpiry := req.CertExpiresBefore != 0 || req.Sort == server.StateRequest_CERT_EXPIRY
+	var matched []pageToken
+	byDomain := make(map[string]BackendData)
+	for _, bd := range backends {
+		t := pageToken{Sort: req.Sort, Descending: req.Descending, Domain: bd.Domain}
+		var expiry int64
+		if needExpiry {
+			expiry = p.certificateInfo(bd).NotAfter
+			if req.CertExpiresBefore != 0 && (expiry == 0 || expiry >= req.CertExpiresBefore) {
+				continue
+			}
+		}
+		switch req.Sort {
+		case server.StateRequest_REVISION:
+			t.Key = bd.Revision
+		case server.StateRequest_CERT_EXPIRY:
+			t.Key = expiry
+			if expiry == 0 {
+				// No cert yet; these never expire first.
+				t.Key = math.MaxInt64
+			}
+		}
+		matched = append(matched, t)
+		byDomain[bd.Domain] = bd
+	}
+	sort.Slice(matched, func(i, j int) bool { return matched[i].before(matched[j]) })
+
+	resp := server.ProxyState{TotalSize: int32(len(matched))}
+	page := matched
+	if after != nil {
+		i := sort.Search(len(page), func(i int) bool { return after.before(page[i]) })
+		page = page[i:]
+	}
+	if req.PageSize > 0 && len(page) > int(req.PageSize) {
+		page = page[:req.PageSize]
+		resp.NextPageToken = page[len(page)-1].encode()
+	}
+	for _, t := range page {
+		// do not leak private keys here
+		resp.Backends = append(resp.Backends, byDomain[t.Domain].AsBackend())
+	}
+	return &resp, nil
+}
+
+// healthStatus is what we last saw of a backend's health check. We do not probe
+// HealthCheck endpoints yet, so it is always unknown.
+func healthStatus(BackendData) string {
+	return "unknown"
+}
+
+// stateFilter is a storm q.Matcher for the filters of a StateRequest,
+// except cert expiry, which State checks itself.
+type stateFilter struct {
+	req       *server.StateRequest
+	protocols map[server.Backend_Protocol]bool
+	nets      []*net.IPNet
+	principal Principal
+}
+
+// newStateFilter checks the filters of req.
+func newStateFilter(req *server.StateRequest) (*stateFilter, error) {
+	f := &stateFilter{req: req}
+	var fe fieldErrors
+	if len(req.Protocols) > 0 {
+		f.protocols = make(map[server.Backend_Protocol]bool)
+		for _, proto := range req.Protocols {
+			f.protocols[proto] = true
+		}
+	}
+	if _, err := path.Match(req.DomainPattern, ""); err != nil {
+		fe.add("domain_pattern", fmt.Errorf("%q: %v", req.DomainPattern, err))
+	}
+	if req.Ip != "" {
+		nets, err := ParseCIDRs([]string{req.Ip})
+		if err != nil {
+			fe.add("ip", err)
+		}
+		f.nets = nets
+	}
+	if _, ok := server.StateRequest_Sort_name[int32(req.Sort)]; !ok {
+		fe.add("sort", fmt.Errorf("unknown sort %d", req.Sort))
+	}
+	if err := fe.err(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func (f *stateFilter) Match(i interface{}) (bool, error) {
+	var bd BackendData
+	switch v := i.(type) {
+	case *BackendData:
+		bd = *v
+	case BackendData:
+		bd = v
+	default:
+		return false, fmt.Errorf("not a backend: %T", i)
+	}
+	return f.matches(bd), nil
+}
+
+func (f *stateFilter) matches(bd BackendData) bool {
+	req := f.req
+	if req.Domain != "" && bd.Domain != req.Domain {
+		return false
+	}
+	if !f.principal.inScope(bd.Domain) {
+		return false
+	}
+	if f.protocols != nil && !f.protocols[bd.Protocol] {
+		return false
+	}
+	if req.HealthStatus != "" && !strings.EqualFold(healthStatus(bd), req.HealthStatus) {
+		return false
+	}
+	if req.DomainPattern != "" {
+		if ok, _ := path.Match(strings.ToLower(req.DomainPattern), strings.ToLower(bd.Domain)); !ok {
+			return false
+		}
+	}
+	if f.nets != nil && !upstreamIn(bd.IPs, f.nets) {
+		return false
+	}
+	for k, v := range req.Labels {
+		got, ok := bd.Labels[k]
+		if !ok || v != "" && got != v {
+			return false
+		}
+	}
+	return true
+}
+
+// upstreamIn is true if one of ips, each a host:port, is in nets. Upstreams
+// named by hostname are never in them.
+func upstreamIn(ips []string, nets []*net.IPNet) bool {
+	for _, addr := range ips {
+		host, _, err := net.SplitHostPort(strings.TrimSpace(addr))
+		if err != nil {
+			host = strings.TrimSpace(addr)
+		}
+		ip := net.ParseIP(host)
+		if ip == nil {
+			continue
+		}
+		for _, n := range nets {
+			if n.Contains(ip) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// pageToken is where a page of State ended: the sort key and domain of its
+// last backend. Clients get it base64 encoded, and pass it back as is.
+type pageToken struct {
+	Sort       server.StateRequest_Sort `json:"s"`
+	Descending bool                     `json:"d,omitempty"`
+	Key        int64                    `json:"k,omitempty"`
+	Domain     string                   `json:"n"`
+}
+
+// before is true if t sorts before u. Ties in the sort key are broken by
+// domain, which is unique, so every backend has one place in the order.
+func (t pageToken) before(u pageToken) bool {
+	if t.Descending {
+		t, u = u, t
+	}
+	if t.Key != u.Key {
+		return t.Key < u.Key
+	}
+	return t.Domain < u.Domain
+}
+
+func (t pageToken) encode() string {
+	data, _ := json.Marshal(t)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodePageToken(s string) (pageToken, error) {
+	var t pageToken
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return t, errors.New("not a page token")
+	}
+	if err := json.Unmarshal(data, &t); err != nil || t.Domain == "" {
+		return t, errors.New("not a page token")
+	}
+	return t, nil
+}
@@ -0,0 +1,118 @@
+package backend
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/anxiousmodernman/co-chair/proto/server"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestStateFilter(t *testing.T) {
+	bd := BackendData{Domain: "api.example.com", Protocol: server.Backend_GRPC,
+		IPs: []string{"10.0.1.5:443", "backend.internal:443"}, Labels: map[string]string{"team": "payments"}}
+	cases := []struct {
+		req  server.StateRequest
+		want bool
+	}{
+		{server.StateRequest{}, true},
+		{server.StateRequest{Protocols: []server.Backend_Protocol{server.Backend_HTTP1, server.Backend_GRPC}}, true},
+		{server.StateRequest{Protocols: []server.Backend_Protocol{server.Backend_HTTP1}}, false},
+		{server.StateRequest{DomainPattern: "*.EXAMPLE.com"}, true},
+		{server.StateRequest{DomainPattern: "*.example.org"}, false},
+		{server.StateRequest{Ip: "10.0.0.0/16"}, true},
+		{server.StateRequest{Ip: "10.0.1.6"}, false},
+		{server.StateRequest{Labels: map[string]string{"team": ""}}, true},
+		{server.StateRequest{Labels: map[string]string{"team": "search"}}, false},
+		{server.StateRequest{HealthStatus: "unknown"}, true},
+		{server.StateRequest{HealthStatus: "healthy"}, false},
+	}
+	for _, c := range cases {
+		f, err := newStateFilter(&c.req)
+		if err != nil {
+			t.Fatalf("%v: %v", c.req, err)
+		}
+		if got := f.matches(bd); got != c.want {
+			t.Errorf("%v: expected %v, got %v", c.req, c.want, got)
+		}
+	}
+
+	f, _ := newStateFilter(&server.StateRequest{})
+	f.principal = Principal{Domains: []string{"*.example.org"}}
+	if f.matches(bd) {
+		t.Error("expected backends out of the principal's scope to be filtered")
+	}
+
+	for _, req := range []*server.StateRequest{{DomainPattern: "[a-"}, {Ip: "10.0.0.0/33"}, {Sort: 9}} {
+		_, err := newStateFilter(req)
+		if s, _ := status.FromError(err); s.Code() != codes.InvalidArgument {
+			t.Errorf("%v: expected InvalidArgument, got %v", req, err)
+		}
+	}
+}
+
+func TestPageToken(t *testing.T) {
+	tok := pageToken{Sort: server.StateRequest_REVISION, Key: 4, Domain: "b.example.com"}
+	got, err := decodePageToken(tok.encode())
+	if err != nil || got != tok {
+		t.Fatalf("expected %v back, got %v: %v", tok, got, err)
+	}
+	if _, err := decodePageToken("not a token"); err == nil {
+		t.Error("expected an error for a bad token")
+	}
+
+	// Ties in the key sort by domain; descending reverses both.
+	a := pageToken{Key: 4, Domain: "a.example.com"}
+	if !a.before(tok) || tok.before(a) {
+		t.Error("expected a.example.com first")
+	}
+	a.Descending, tok.Descending = true, true
+	if a.before(tok) || !tok.before(a) {
+		t.Error("expected b.example.com first when descending")
+	}
+}
+
+func TestStatePages(t *testing.T) {
+	p, cleanup, err := NewTestProxyCleanup()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cleanup()
+	for _, d := range []string{"c.example.com", "a.example.com", "b.example.com", "d.example.org"} {
+		b := &server.Backend{Domain: d, Ips: []string{"10.0.0.1:443"}, Labels: map[string]string{"env": "prod"}}
+		if _, err := p.Put(context.TODO(), b); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var domains []string
+	req := &server.StateRequest{PageSize: 2, DomainPattern: "*.example.com", Descending: true}
+	for {
+		state, err := p.State(context.TODO(), req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if state.TotalSize != 3 {
+			t.Errorf("expected 3 matches, got %d", state.TotalSize)
+		}
+		for _, b := range state.Backends {
+			domains = append(domains, b.Domain)
+		}
+		if state.NextPageToken == "" {
+			break
+		}
+		req.PageToken = state.NextPageToken
+	}
+	want := "c.example.com b.example.com a.example.com"
+	if got := fmt.Sprint(domains); got != "["+want+"]" {
+		t.Errorf("expected [%s], got %s", want, got)
+	}
+
+	req.Descending = false
+	_, err = p.State(context.TODO(), req)
+	if s, _ := status.FromError(err); s.Code() != codes.InvalidArgument {
+		t.Errorf("expected a token from another sort to be invalid, got %v", err)
+	}
+}
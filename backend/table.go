@@ -0,0 +1,207 @@
+package backend
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"strings"
+	"sync/atomic"
+)
+
+// Route is a BackendData compiled for serving. Its certificate is parsed once,
+// when the Route is built, instead of on every handshake.
+type Route struct {
+	BackendData
+	// Certificate is nil if the backend has no cert or if the cert failed
+	// to parse, in which case CertErr says why.
+	Certificate *tls.Certificate
+	CertErr     error
+	// Certificates is Certificate followed by the parsed ExtraCerts.
+	Certificates []*tls.Certificate
+
+	allow, deny []*net.IPNet
+	aclErr      error
+
+	clientCAs *x509.CertPool
+	clientErr error
+
+	policy    tlsPolicy
+	policyErr error
+}
+
+func compileRoute(bd BackendData) *Route {
+	r := &Route{BackendData: bd}
+	r.allow, r.aclErr = ParseCIDRs(bd.AllowCIDRs)
+	if r.aclErr == nil {
+		r.deny, r.aclErr = ParseCIDRs(bd.DenyCIDRs)
+	}
+	r.compileClientAuth()
+	r.policy, r.policyErr = parseTLSPolicy(bd.TLSMinVersion, bd.TLSCipherSuites, bd.TLSCurves)
+	if len(bd.BackendCert) == 0 {
+		r.CertErr = fmt.Errorf("%s has no certificate", bd.Domain)
+		return r
+	}
+	cert, err := parsePair(bd.BackendCert, bd.BackendKey)
+	if err != nil {
+		r.CertErr = fmt.Errorf("%s certificate: %v", bd.Domain, err)
+		return r
+	}
+	r.Certificate = cert
+	r.Certificates = append(r.Certificates, cert)
+	// Put validates extra certs, so one that fails here came from a bad
+	// route table. We serve the rest rather than fail the domain.
+	for _, c := range bd.ExtraCerts {
+		if cert, err := parsePair(c.Cert, c.Key); err == nil {
+			r.Certificates = append(r.Certificates, cert)
+		}
+	}
+	return r
+}
+
+// parsePair is tls.X509KeyPair with Leaf set, which SupportsCertificate
+// would otherwise parse on every handshake.
+func parsePair(certPEM, keyPEM []byte) (*tls.Certificate, error) {
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		return nil, err
+	}
+	cert.Leaf, err = x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		return nil, err
+	}
+	return &cert, nil
+}
+
+// CertificateFor picks the first of our certificates that the client
+// supports, e.g. RSA for clients without ECDSA. If none fit, we offer the
+// first and let the handshake fail with the client's error.
+func (r *Route) CertificateFor(hi *tls.ClientHelloInfo) *tls.Certificate {
+	if len(r.Certificates) > 1 {
+		for _, c := range r.Certificates {
+			if hi.SupportsCertificate(c) == nil {
+				return c
+			}
+		}
+	}
+	return r.Certificate
+}
+
+// Allowed reports whether a client at ip may connect to the Route. A Route
+// with unparseable CIDRs allows no one.
+func (r *Route) Allowed(ip net.IP) bool {
+	if r.aclErr != nil || ip == nil {
+		return false
+	}
+	if len(r.allow) > 0 && !containsIP(r.allow, ip) {
+		return false
+	}
+	return !containsIP(r.deny, ip)
+}
+
+// Table is an immutable snapshot of the routing configuration. Tables are
+// never modified in place; With and Without return new Tables that share
+// the unchanged Routes.
+type Table struct {
+	routes map[string]*Route
+}
+
+// NewTable compiles backends into a Table.
+func NewTable(backends []BackendData) *Table {
+	routes := make(map[string]*Route, len(backends))
+	for _, bd := range backends {
+		routes[strings.ToLower(bd.Domain)] = compileRoute(bd)
+	}
+	return &Table{routes}
+}
+
+// Lookup matches domain exactly, then against a wildcard domain for its
+// parent, e.g. "*.example.com" matches "www.example.com".
+func (t *Table) Lookup(domain string) (*Route, bool) {
+	domain = strings.ToLower(domain)
+	if r, ok := t.routes[domain]; ok {
+		return r, true
+	}
+	if w, ok := wildcard(domain); ok {
+		r, ok := t.routes[w]
+		return r, ok
+	}
+	return nil, false
+}
+
+// With returns a copy of the Table with bd added or replaced.
+func (t *Table) With(bd BackendData) *Table {
+	routes := t.copyRoutes()
+	routes[strings.ToLower(bd.Domain)] = compileRoute(bd)
+	return &Table{routes}
+}
+
+// Without returns a copy of the Table with domain removed.
+func (t *Table) Without(domain string) *Table {
+	routes := t.copyRoutes()
+	delete(routes, strings.ToLower(domain))
+	return &Table{routes}
+}
+
+// Len is the number of routes in the Table.
+func (t *Table) Len() int {
+	return len(t.routes)
+}
+
+// Routes lists the Routes in the Table, in no particular order.
+func (t *Table) Routes() []*Route {
+	routes := make([]*Route, 0, len(t.routes))
+	for _, r := range t.routes {
+		routes = append(routes, r)
+	}
+	return routes
+}
+
+func (t *Table) copyRoutes() map[string]*Route {
+	routes := make(map[string]*Route, len(t.routes)+1)
+	for k, v := range t.routes {
+		routes[k] = v
+	}
+	return routes
+}
+
+// wildcard returns the wildcard domain that would match domain, if any.
+func wildcard(domain string) (string, bool) {
+	i := strings.IndexByte(domain, '.')
+	if i <= 0 || i == len(domain)-1 {
+		return "", false
+	}
+	return "*" + domain[i:], true
+}
+
+// TableRouter routes from an in-memory Table. Lookups never block; Swap
+// replaces the whole Table atomically.
+type TableRouter struct {
+	v atomic.Value
+}
+
+// NewTableRouter returns a TableRouter serving t.
+func NewTableRouter(t *Table) *TableRouter {
+	var r TableRouter
+	r.v.Store(t)
+	return &r
+}
+
+// Route looks up domain in the current Table.
+func (r *TableRouter) Route(domain string) (*Route, error) {
+	route, ok := r.Table().Lookup(domain)
+	if !ok {
+		return nil, ErrNoRoute
+	}
+	return route, nil
+}
+
+// Table returns the current Table.
+func (r *TableRouter) Table() *Table {
+	return r.v.Load().(*Table)
+}
+
+// Swap replaces the current Table.
+func (r *TableRouter) Swap(t *Table) {
+	r.v.Store(t)
+}
@@ -0,0 +1,119 @@
+package backend
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/anxiousmodernman/co-chair/proto/server"
+)
+
+func TestTableLookup(t *testing.T) {
+	_, signed := testNewCAAndCert(t)
+
+	table := NewTable([]BackendData{
+		{Domain: "example.com", BackendCert: signed.Cert, BackendKey: signed.PrivateKey},
+		{Domain: "*.example.com"},
+	})
+
+	cases := []struct {
+		domain   string
+		expected string
+	}{
+		{"example.com", "example.com"},
+		{"EXAMPLE.com", "example.com"},
+		{"www.example.com", "*.example.com"},
+		{"a.b.example.com", ""},
+		{"example.org", ""},
+	}
+	for _, c := range cases {
+		r, ok := table.Lookup(c.domain)
+		if c.expected == "" {
+			if ok {
+				t.Errorf("%s: expected no match, got %s", c.domain, r.Domain)
+			}
+			continue
+		}
+		if !ok || r.Domain != c.expected {
+			t.Errorf("%s: expected %s got %v", c.domain, c.expected, r)
+		}
+	}
+
+	r, _ := table.Lookup("example.com")
+	if r.Certificate == nil {
+		t.Errorf("expected parsed certificate, got error: %v", r.CertErr)
+	}
+	r, _ = table.Lookup("www.example.com")
+	if r.CertErr == nil {
+		t.Errorf("expected CertErr for route without certificate")
+	}
+
+	// With and Without never modify the original
+	added := table.With(BackendData{Domain: "example.org"})
+	removed := table.Without("example.com")
+	if table.Len() != 2 || added.Len() != 3 || removed.Len() != 1 {
+		t.Errorf("unexpected lengths: %d %d %d", table.Len(), added.Len(), removed.Len())
+	}
+}
+
+func TestProxyRouterFollowsWrites(t *testing.T) {
+	p, cleanup, err := NewTestProxyCleanup()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cleanup()
+
+	_, signed := testNewCAAndCert(t)
+	b := makeBackend(server.Backend_HTTP1, "server1", "127.0.0.1:9999", signed.Cert, signed.PrivateKey)
+	if _, err := p.Put(context.TODO(), b); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.Router().Route("server1"); err != nil {
+		t.Errorf("expected route after Put: %v", err)
+	}
+	if _, err := p.Remove(context.TODO(), b); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.Router().Route("server1"); err != ErrNoRoute {
+		t.Errorf("expected ErrNoRoute after Remove, got %v", err)
+	}
+}
+
+// benchmarkRouter looks up and serves a certificate for one of 100 domains,
+// which is the per-connection work the TCPForwarder asks of its Router.
+func benchmarkRouter(b *testing.B, router func(p *Proxy) Router) {
+	p, cleanup, err := NewTestProxyCleanup()
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer cleanup()
+
+	for i := 0; i < 100; i++ {
+		// Our CA issues each domain its cert.
+		domain := fmt.Sprintf("server%d", i)
+		be := &server.Backend{Domain: domain, Ips: []string{"127.0.0.1:9999"}}
+		if _, err := p.Put(context.TODO(), be); err != nil {
+			b.Fatal(err)
+		}
+	}
+	r := router(p)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		route, err := r.Route(fmt.Sprintf("server%d", i%100))
+		if err != nil {
+			b.Fatal(err)
+		}
+		if route.Certificate == nil {
+			b.Fatal(route.CertErr)
+		}
+	}
+}
+
+func BenchmarkStormRouter(b *testing.B) {
+	benchmarkRouter(b, func(p *Proxy) Router { return &StormRouter{DB: p.DB} })
+}
+
+func BenchmarkTableRouter(b *testing.B) {
+	benchmarkRouter(b, func(p *Proxy) Router { return p.Router() })
+}
@@ -0,0 +1,154 @@
+package backend
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/anxiousmodernman/co-chair/proto/server"
+)
+
+// DefaultTicketRotation is how often we make a new session ticket key.
+const DefaultTicketRotation = 24 * time.Hour
+
+// ticketKeysKept is how many keys we keep. Tickets encrypted with a key
+// still resume until it is dropped, two rotations later.
+const ticketKeysKept = 3
+
+// TicketKey is a TLS session ticket key. Keys are kept in bolt so that
+// tickets survive restarts, and so every forwarder can share them.
+type TicketKey struct {
+	ID      int `storm:"id,increment"`
+	Key     []byte
+	Created time.Time
+}
+
+// SessionTicketKeys returns our session ticket keys, newest first. If the
+// newest is older than TicketRotation, we rotate first.
+func (p *Proxy) SessionTicketKeys(_ context.Context, _ *server.TicketKeysRequest) (*server.TicketKeys, error) {
+	keys, err := p.rotateTicketKeys(time.Now())
+	if err != nil {
+		return nil, err
+	}
+	var resp server.TicketKeys
+	for _, k := range keys {
+		resp.Keys = append(resp.Keys, k.Key)
+	}
+	return &resp, nil
+}
+
+func (p *Proxy) rotateTicketKeys(now time.Time) ([]TicketKey, error) {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
+	var keys []TicketKey
+	if err := p.DB.All(&keys); err != nil {
+		return nil, fmt.Errorf("db error: %v", err)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].Created.After(keys[j].Created) })
+
+	rotation := p.TicketRotation
+	if rotation <= 0 {
+		rotation = DefaultTicketRotation
+	}
+	if len(keys) > 0 && now.Sub(keys[0].Created) < rotation {
+		return keys, nil
+	}
+	k := TicketKey{Key: make([]byte, 32), Created: now}
+	if _, err := rand.Read(k.Key); err != nil {
+		return nil, err
+	}
+	if err := p.DB.Save(&k); err != nil {
+		return nil, fmt.Errorf("save: %v", err)
+	}
+	keys = append([]TicketKey{k}, keys...)
+	for len(keys) > ticketKeysKept {
+		old := keys[len(keys)-1]
+		if err := p.DB.DeleteStruct(&old); err != nil {
+			return nil, fmt.Errorf("delete: %v", err)
+		}
+		keys = keys[:len(keys)-1]
+	}
+	return keys, nil
+}
+
+// TicketKeySource returns session ticket keys, newest first.
+type TicketKeySource func(context.Context) ([][32]byte, error)
+
+// LocalTicketKeys gets keys from p.
+func LocalTicketKeys(p *Proxy) TicketKeySource {
+	return func(ctx context.Context) ([][32]byte, error) {
+		resp, err := p.SessionTicketKeys(ctx, &server.TicketKeysRequest{})
+		if err != nil {
+			return nil, err
+		}
+		return ticketKeys(resp)
+	}
+}
+
+// RemoteTicketKeys gets keys from a control plane, for forwarders.
+func RemoteTicketKeys(pc server.ProxyClient) TicketKeySource {
+	return func(ctx context.Context) ([][32]byte, error) {
+		resp, err := pc.SessionTicketKeys(ctx, &server.TicketKeysRequest{})
+		if err != nil {
+			return nil, err
+		}
+		return ticketKeys(resp)
+	}
+}
+
+func ticketKeys(resp *server.TicketKeys) ([][32]byte, error) {
+	var keys [][32]byte
+	for _, k := range resp.Keys {
+		if len(k) != 32 {
+			return nil, fmt.Errorf("session ticket key is %d bytes, not 32", len(k))
+		}
+		var key [32]byte
+		copy(key[:], k)
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// syncTicketKeys applies the keys from f's TicketKeySource to the config
+// our listener serves with. Configs from GetConfigForClient use its keys.
+func (f *TCPForwarder) syncTicketKeys(conf *tls.Config) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	keys, err := f.tickets(ctx)
+	if err != nil {
+		f.logger.Errorf("session ticket keys: %v", err)
+		return
+	}
+	if len(keys) == 0 {
+		return
+	}
+	conf.SetSessionTicketKeys(keys)
+}
+
+// startTicketSync syncs our ticket keys now, then every ticketInterval
+// until Stop is called.
+func (f *TCPForwarder) startTicketSync(conf *tls.Config) {
+	f.syncTicketKeys(conf)
+	interval := f.ticketInterval
+	if interval <= 0 {
+		interval = DefaultSyncInterval
+	}
+	f.stop = make(chan struct{})
+	stop := f.stop
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				f.syncTicketKeys(conf)
+			}
+		}
+	}()
+}
@@ -0,0 +1,87 @@
+package backend
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/anxiousmodernman/co-chair/proto/server"
+	"github.com/sirupsen/logrus"
+)
+
+func TestRotateTicketKeys(t *testing.T) {
+	p, cleanup, err := NewTestProxyCleanup()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cleanup()
+
+	resp, err := p.SessionTicketKeys(context.TODO(), &server.TicketKeysRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Keys) != 1 || len(resp.Keys[0]) != 32 {
+		t.Fatalf("expected one 32 byte key, got %d", len(resp.Keys))
+	}
+	again, _ := p.SessionTicketKeys(context.TODO(), &server.TicketKeysRequest{})
+	if len(again.Keys) != 1 || string(again.Keys[0]) != string(resp.Keys[0]) {
+		t.Errorf("expected the same key before rotation is due")
+	}
+
+	now := time.Now()
+	for i := 1; i <= 4; i++ {
+		keys, err := p.rotateTicketKeys(now.Add(time.Duration(i) * DefaultTicketRotation))
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := i + 1
+		if want > ticketKeysKept {
+			want = ticketKeysKept
+		}
+		if len(keys) != want {
+			t.Errorf("rotation %d: expected %d keys, got %d", i, want, len(keys))
+		}
+	}
+}
+
+func TestSharedTicketKeys(t *testing.T) {
+	var key [32]byte
+	copy(key[:], "a shared session ticket key.....")
+	src := func(context.Context) ([][32]byte, error) { return [][32]byte{key}, nil }
+
+	pair := testRSACert(t, "www.example.com")
+	cert, err := parsePair(pair.Cert, pair.Key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Two forwarders with the same source, like two instances behind one
+	// load balancer.
+	newServerConf := func() *tls.Config {
+		f, _ := NewTCPForwarder(WithSessionTickets(src, time.Minute), WithLogger(logrus.New()))
+		conf := &tls.Config{Certificates: []tls.Certificate{*cert}, MaxVersion: tls.VersionTLS12}
+		f.syncTicketKeys(conf)
+		return conf
+	}
+	clientConf := &tls.Config{
+		InsecureSkipVerify: true,
+		ClientSessionCache: tls.NewLRUClientSessionCache(1),
+	}
+	handshake := func(serverConf *tls.Config) bool {
+		s, c := net.Pipe()
+		defer c.Close()
+		go tls.Server(s, serverConf).Handshake()
+		tc := tls.Client(c, clientConf)
+		if err := tc.Handshake(); err != nil {
+			t.Fatal(err)
+		}
+		return tc.ConnectionState().DidResume
+	}
+	if handshake(newServerConf()) {
+		t.Fatal("expected a full first handshake")
+	}
+	if !handshake(newServerConf()) {
+		t.Errorf("expected to resume on another instance with the same keys")
+	}
+}
@@ -0,0 +1,124 @@
+package backend
+
+import (
+	"crypto/tls"
+	"fmt"
+
+	"github.com/anxiousmodernman/co-chair/proto/server"
+	"golang.org/x/crypto/acme"
+)
+
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+var tlsCurves = map[string]tls.CurveID{
+	"X25519": tls.X25519,
+	"P256":   tls.CurveP256,
+	"P384":   tls.CurveP384,
+	"P521":   tls.CurveP521,
+}
+
+// tlsPolicy is a TLSPolicy parsed for crypto/tls.
+type tlsPolicy struct {
+	minVersion   uint16
+	cipherSuites []uint16
+	curves       []tls.CurveID
+}
+
+// parseTLSPolicy checks a policy's names. Only cipher suites Go considers
+// secure are allowed.
+func parseTLSPolicy(minVersion string, suites, curves []string) (tlsPolicy, error) {
+	var pol tlsPolicy
+	if minVersion != "" {
+		v, ok := tlsVersions[minVersion]
+		if !ok {
+			return pol, fmt.Errorf("tls policy: unknown version %q", minVersion)
+		}
+		pol.minVersion = v
+	}
+	for _, name := range suites {
+		id, ok := cipherSuiteID(name)
+		if !ok {
+			return pol, fmt.Errorf("tls policy: unknown or insecure cipher suite %q", name)
+		}
+		pol.cipherSuites = append(pol.cipherSuites, id)
+	}
+	for _, name := range curves {
+		id, ok := tlsCurves[name]
+		if !ok {
+			return pol, fmt.Errorf("tls policy: unknown curve %q", name)
+		}
+		pol.curves = append(pol.curves, id)
+	}
+	return pol, nil
+}
+
+func cipherSuiteID(name string) (uint16, bool) {
+	for _, cs := range tls.CipherSuites() {
+		if cs.Name == name {
+			return cs.ID, true
+		}
+	}
+	return 0, false
+}
+
+// nextProtos is the ALPN list for a backend's protocol. HTTP2 and gRPC
+// backends are reached over h2 only, so we do not offer http/1.1 for them.
+func nextProtos(p server.Backend_Protocol) []string {
+	switch p {
+	case server.Backend_HTTP2, server.Backend_GRPC:
+		return []string{"h2"}
+	}
+	return []string{"http/1.1"}
+}
+
+// hstsHeader is the Strict-Transport-Security value for a backend, or "" if
+// it has none.
+func (bd BackendData) hstsHeader() string {
+	if bd.HSTSMaxAge <= 0 {
+		return ""
+	}
+	v := fmt.Sprintf("max-age=%d", bd.HSTSMaxAge)
+	if bd.HSTSIncludeSubdomains {
+		v += "; includeSubDomains"
+	}
+	return v
+}
+
+// GetConfigForClient picks the TLS config for a handshake by SNI: the
+// Route's TLS policy and ALPN list, and client certificate checks if the
+// Route requires them. Unknown domains get our base config.
+func (f *TCPForwarder) GetConfigForClient(hi *tls.ClientHelloInfo) (*tls.Config, error) {
+	route, err := f.Router.Route(hi.ServerName)
+	if err != nil {
+		// GetCertificate reports routing errors.
+		return nil, nil
+	}
+	if route.policyErr != nil {
+		return nil, route.policyErr
+	}
+	conf := f.tlsConfig()
+	conf.NextProtos = nextProtos(route.Protocol)
+	if f.ACME != nil {
+		conf.NextProtos = append(conf.NextProtos, acme.ALPNProto)
+	}
+	conf.MinVersion = route.policy.minVersion
+	conf.CipherSuites = route.policy.cipherSuites
+	conf.CurvePreferences = route.policy.curves
+
+	// ACME validators have no client certs.
+	if !route.RequiresClientCert() || route.ACME && isACMEChallenge(hi) {
+		return conf, nil
+	}
+	if route.clientErr != nil {
+		return nil, route.clientErr
+	}
+	conf.ClientAuth = tls.RequireAndVerifyClientCert
+	conf.ClientCAs = route.clientCAs
+	conf.VerifyConnection = route.VerifyClient
+	return conf, nil
+}
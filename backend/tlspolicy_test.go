@@ -0,0 +1,164 @@
+package backend
+
+import (
+	"bufio"
+	"crypto/tls"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/anxiousmodernman/co-chair/proto/server"
+)
+
+func TestParseTLSPolicy(t *testing.T) {
+	cases := []struct {
+		name       string
+		minVersion string
+		suites     []string
+		curves     []string
+		ok         bool
+	}{
+		{"defaults", "", nil, nil, true},
+		{"full", "1.2", []string{"TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256"}, []string{"X25519", "P256"}, true},
+		{"bad version", "1.4", nil, nil, false},
+		{"insecure suite", "", []string{"TLS_RSA_WITH_RC4_128_SHA"}, nil, false},
+		{"bad curve", "", nil, []string{"P224"}, false},
+	}
+	for _, c := range cases {
+		_, err := parseTLSPolicy(c.minVersion, c.suites, c.curves)
+		if (err == nil) != c.ok {
+			t.Errorf("%s: expected ok %v, got %v", c.name, c.ok, err)
+		}
+	}
+}
+
+func TestGetConfigForClient(t *testing.T) {
+	router := NewTableRouter(NewTable([]BackendData{
+		{Domain: "web.example.com", Protocol: server.Backend_HTTP1, TLSMinVersion: "1.3"},
+		{Domain: "grpc.example.com", Protocol: server.Backend_GRPC, TLSCurves: []string{"P384"}},
+		{Domain: "broken.example.com", TLSMinVersion: "9"},
+	}))
+	f, _ := NewTCPForwarder(WithRouter(router))
+
+	conf, err := f.GetConfigForClient(&tls.ClientHelloInfo{ServerName: "web.example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if conf.MinVersion != tls.VersionTLS13 || len(conf.NextProtos) != 1 || conf.NextProtos[0] != "http/1.1" {
+		t.Errorf("unexpected config for HTTP1 backend: %v %v", conf.MinVersion, conf.NextProtos)
+	}
+	conf, err = f.GetConfigForClient(&tls.ClientHelloInfo{ServerName: "grpc.example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(conf.CurvePreferences) != 1 || conf.CurvePreferences[0] != tls.CurveP384 || conf.NextProtos[0] != "h2" {
+		t.Errorf("unexpected config for gRPC backend: %v %v", conf.CurvePreferences, conf.NextProtos)
+	}
+	if _, err := f.GetConfigForClient(&tls.ClientHelloInfo{ServerName: "broken.example.com"}); err == nil {
+		t.Errorf("expected a bad policy to fail the handshake")
+	}
+	if conf, _ := f.GetConfigForClient(&tls.ClientHelloInfo{ServerName: "unknown.example.com"}); conf != nil {
+		t.Errorf("expected the base config for unknown domains")
+	}
+}
+
+func TestRewriteResponses(t *testing.T) {
+	client, proxy := net.Pipe()
+	defer client.Close()
+
+	w := &httpRewriter{hsts: "max-age=60", methods: make(chan string, 3)}
+	w.methods <- "GET"
+	w.methods <- "HEAD"
+	w.methods <- "GET"
+	close(w.methods)
+	resps := "HTTP/1.1 200 OK\r\nContent-Length: 5\r\n\r\nhello" +
+		"HTTP/1.1 200 OK\r\nContent-Length: 5\r\n\r\n" +
+		"HTTP/1.1 100 Continue\r\n\r\n" +
+		"HTTP/1.1 200 OK\r\nStrict-Transport-Security: max-age=1\r\nContent-Length: 0\r\n\r\n"
+	go func() {
+		w.rewriteResponses(proxy, strings.NewReader(resps), w.methods)
+		proxy.Close()
+	}()
+
+	br := bufio.NewReader(client)
+	for i, c := range []struct {
+		method, hsts, body string
+		status             int
+	}{
+		{"GET", "max-age=60", "hello", 200},
+		{"HEAD", "max-age=60", "", 200},
+		{"GET", "max-age=60", "", 100},
+		{"GET", "max-age=1", "", 200},
+	} {
+		resp, err := http.ReadResponse(br, &http.Request{Method: c.method})
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := ioutil.ReadAll(resp.Body)
+		if resp.StatusCode != c.status || resp.Header.Get("Strict-Transport-Security") != c.hsts || string(body) != c.body {
+			t.Errorf("response %d: got %d %q %q", i, resp.StatusCode, resp.Header.Get("Strict-Transport-Security"), body)
+		}
+	}
+}
+
+// tcpPair returns the two ends of a TCP conn.
+func tcpPair(t *testing.T) (net.Conn, net.Conn) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	a, err := net.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := l.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return a, b
+}
+
+func TestHTTPTunnelBackendDone(t *testing.T) {
+	client, conn := tcpPair(t)
+	bConn, backend := tcpPair(t)
+	defer client.Close()
+	defer backend.Close()
+
+	w := newHTTPRewriter(&BackendData{Protocol: server.Backend_HTTP1, HSTSMaxAge: 60}, "")
+	tunnelErr := make(chan error, 1)
+	go func() { tunnelErr <- runHTTPTunnel(conn, bConn, nil, w, 0) }()
+
+	// The backend answers one request, and is then done sending, though
+	// it still reads.
+	go func() {
+		br := bufio.NewReader(backend)
+		if _, err := http.ReadRequest(br); err != nil {
+			return
+		}
+		backend.Write([]byte("HTTP/1.1 200 OK\r\nContent-Length: 0\r\n\r\n"))
+		backend.(*net.TCPConn).CloseWrite()
+		io.Copy(ioutil.Discard, br)
+	}()
+	// The client pipelines more requests than we queue methods for.
+	go func() {
+		for i := 0; i < 200; i++ {
+			if _, err := client.Write([]byte("GET / HTTP/1.1\r\nHost: www.example.com\r\n\r\n")); err != nil {
+				return
+			}
+		}
+	}()
+
+	select {
+	case err := <-tunnelErr:
+		if err != nil {
+			t.Errorf("expected the tunnel to finish cleanly, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the tunnel to finish once the backend was done")
+	}
+}
@@ -0,0 +1,190 @@
+package backend
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// tunnelBufSize is the size of the pooled buffers we copy through.
+const tunnelBufSize = 32 * 1024
+
+var bufPool = sync.Pool{
+	New: func() interface{} {
+		b := make([]byte, tunnelBufSize)
+		return &b
+	},
+}
+
+// A Tunnel streams data between a client conn and a backend conn, in both
+// directions, until both sides are done sending.
+//
+// When one side finishes writing, the write half of the other side is
+// closed, so protocols that half-close keep working. If either direction
+// fails, both conns are closed and Run returns the first error. Both conns
+// should support CloseWrite, as *net.TCPConn and *tls.Conn do; otherwise a
+// Tunnel runs until the conns are closed.
+//
+// We copy through pooled buffers. The client conn is always a *tls.Conn,
+// as we terminate TLS to route, so the kernel cannot splice for us.
+type Tunnel struct {
+	Client  net.Conn
+	Backend net.Conn
+	// IdleTimeout, if positive, closes the Tunnel once neither side has
+	// sent anything for that long. Run then returns errIdle.
+	IdleTimeout time.Duration
+}
+
+// errIdle is returned by a Tunnel closed for being idle.
+var errIdle = errors.New("tunnel idle")
+
+// Run blocks until the Tunnel is done.
+func (t Tunnel) Run() error {
+	t, timer := t.withIdleTimeout()
+	return timer.done(t.run(
+		func() error { return pipe(t.Backend, t.Client, "conn->bConn") },
+		func() error { return pipe(t.Client, t.Backend, "bConn->conn") },
+	))
+}
+
+// withIdleTimeout returns t with its conns wrapped to enforce IdleTimeout,
+// and their timer, which is nil if t has no IdleTimeout.
+func (t Tunnel) withIdleTimeout() (Tunnel, *idleTimer) {
+	if t.IdleTimeout <= 0 {
+		return t, nil
+	}
+	timer := newIdleTimer(t.IdleTimeout, t.Client, t.Backend)
+	t.Client = idleConn{Conn: t.Client, timer: timer}
+	t.Backend = idleConn{Conn: t.Backend, timer: timer}
+	return t, timer
+}
+
+// run is Run with the copies swapped out: upstream from client to backend,
+// and downstream back.
+func (t Tunnel) run(upstream, downstream func() error) error {
+	errs := make(chan error, 2)
+	go func() { errs <- upstream() }()
+	go func() { errs <- downstream() }()
+
+	var first error
+	for i := 0; i < 2; i++ {
+		if err := <-errs; err != nil && first == nil {
+			first = err
+			// unblock the other direction
+			t.Client.Close()
+			t.Backend.Close()
+		}
+	}
+	return first
+}
+
+type closeWriter interface {
+	CloseWrite() error
+}
+
+// idleTimer closes a Tunnel's conns once neither has read or written for
+// timeout. Reads and writes only note the time; one goroutine per Tunnel
+// checks it, waking when the timeout would pass.
+type idleTimer struct {
+	timeout time.Duration
+	conns   []net.Conn
+	last    int64 // UnixNano of the last read or write
+	expired int32
+	stop    chan struct{}
+}
+
+func newIdleTimer(timeout time.Duration, conns ...net.Conn) *idleTimer {
+	it := &idleTimer{timeout: timeout, conns: conns, stop: make(chan struct{})}
+	it.seen()
+	go it.run()
+	return it
+}
+
+func (it *idleTimer) seen() {
+	atomic.StoreInt64(&it.last, time.Now().UnixNano())
+}
+
+func (it *idleTimer) run() {
+	t := time.NewTimer(it.timeout)
+	defer t.Stop()
+	for {
+		select {
+		case <-it.stop:
+			return
+		case <-t.C:
+		}
+		idle := time.Since(time.Unix(0, atomic.LoadInt64(&it.last)))
+		if idle < it.timeout {
+			t.Reset(it.timeout - idle)
+			continue
+		}
+		atomic.StoreInt32(&it.expired, 1)
+		for _, c := range it.conns {
+			c.Close()
+		}
+		return
+	}
+}
+
+// done stops the timer, and returns err, or errIdle if the Tunnel failed
+// because the timer closed it.
+func (it *idleTimer) done(err error) error {
+	if it == nil {
+		return err
+	}
+	close(it.stop)
+	if err != nil && atomic.LoadInt32(&it.expired) == 1 {
+		return errIdle
+	}
+	return err
+}
+
+// idleConn is a conn of a Tunnel with an idle timeout.
+type idleConn struct {
+	net.Conn
+	timer *idleTimer
+}
+
+func (c idleConn) Read(p []byte) (int, error) {
+	n, err := c.Conn.Read(p)
+	if n > 0 {
+		c.timer.seen()
+	}
+	return n, err
+}
+
+func (c idleConn) Write(p []byte) (int, error) {
+	n, err := c.Conn.Write(p)
+	if n > 0 {
+		c.timer.seen()
+	}
+	return n, err
+}
+
+func (c idleConn) CloseWrite() error {
+	if cw, ok := c.Conn.(closeWriter); ok {
+		return cw.CloseWrite()
+	}
+	return nil
+}
+
+// pipe copies src to dst until src reaches EOF, then closes the write half
+// of dst so that its peer sees EOF, too.
+func pipe(dst, src net.Conn, dir string) error {
+	buf := bufPool.Get().(*[]byte)
+	_, err := io.CopyBuffer(dst, src, *buf)
+	bufPool.Put(buf)
+	if err != nil {
+		return fmt.Errorf("%s: %v", dir, err)
+	}
+	if cw, ok := dst.(closeWriter); ok {
+		if err := cw.CloseWrite(); err != nil {
+			return fmt.Errorf("%s close write: %v", dir, err)
+		}
+	}
+	return nil
+}
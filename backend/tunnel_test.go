@@ -0,0 +1,158 @@
+package backend
+
+import (
+	"bytes"
+	"crypto/tls"
+	"io"
+	"io/ioutil"
+	"net"
+	"testing"
+	"time"
+)
+
+// tunnelPair returns a client conn and a backend conn joined by a Tunnel.
+// If clientTLS is set, the client side of the Tunnel speaks TLS, as it does
+// when we proxy. The Tunnel closes after idle, if set.
+func tunnelPair(tb testing.TB, clientTLS bool, idle time.Duration) (client, backend net.Conn, tunnelErr chan error) {
+	bl, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		tb.Fatal(err)
+	}
+	defer bl.Close()
+	pl, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		tb.Fatal(err)
+	}
+	defer pl.Close()
+
+	var serverConf, clientConf *tls.Config
+	if clientTLS {
+		_, signed := testNewCAAndCert(tb)
+		cert, err := tls.X509KeyPair(signed.Cert, signed.PrivateKey)
+		if err != nil {
+			tb.Fatal(err)
+		}
+		serverConf = &tls.Config{Certificates: []tls.Certificate{cert}}
+		clientConf = &tls.Config{InsecureSkipVerify: true}
+	}
+
+	tunnelErr = make(chan error, 1)
+	go func() {
+		c, err := pl.Accept()
+		if err != nil {
+			tunnelErr <- err
+			return
+		}
+		if serverConf != nil {
+			c = tls.Server(c, serverConf)
+		}
+		b, err := net.Dial("tcp", bl.Addr().String())
+		if err != nil {
+			tunnelErr <- err
+			return
+		}
+		tunnelErr <- Tunnel{Client: c, Backend: b, IdleTimeout: idle}.Run()
+	}()
+
+	client, err = net.Dial("tcp", pl.Addr().String())
+	if err != nil {
+		tb.Fatal(err)
+	}
+	if clientConf != nil {
+		client = tls.Client(client, clientConf)
+	}
+	backend, err = bl.Accept()
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return client, backend, tunnelErr
+}
+
+func TestTunnelHalfClose(t *testing.T) {
+	for _, clientTLS := range []bool{false, true} {
+		client, backend, tunnelErr := tunnelPair(t, clientTLS, 0)
+
+		// The backend answers only after the client is done sending.
+		go func() {
+			req, _ := ioutil.ReadAll(backend)
+			backend.Write(append([]byte("pong:"), req...))
+			backend.Close()
+		}()
+
+		if _, err := client.Write([]byte("ping")); err != nil {
+			t.Fatal(err)
+		}
+		if err := client.(closeWriter).CloseWrite(); err != nil {
+			t.Fatal(err)
+		}
+		resp, err := ioutil.ReadAll(client)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(resp, []byte("pong:ping")) {
+			t.Errorf("tls: %v: expected pong:ping got %q", clientTLS, resp)
+		}
+		client.Close()
+		if err := <-tunnelErr; err != nil {
+			t.Errorf("tls: %v: tunnel error: %v", clientTLS, err)
+		}
+	}
+}
+
+func TestTunnelIdleTimeout(t *testing.T) {
+	client, backend, tunnelErr := tunnelPair(t, false, 100*time.Millisecond)
+	defer client.Close()
+	defer backend.Close()
+
+	// Traffic in either direction keeps the tunnel open past the timeout.
+	buf := make([]byte, 4)
+	for i := 0; i < 4; i++ {
+		time.Sleep(50 * time.Millisecond)
+		src, dst := client, backend
+		if i%2 == 1 {
+			src, dst = backend, client
+		}
+		if _, err := src.Write([]byte("ping")); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := io.ReadFull(dst, buf); err != nil {
+			t.Fatalf("expected the tunnel open after %d writes: %v", i, err)
+		}
+	}
+
+	select {
+	case err := <-tunnelErr:
+		if err != errIdle {
+			t.Errorf("expected errIdle, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected an idle tunnel to close")
+	}
+	if _, err := client.Read(buf); err == nil {
+		t.Error("expected the client conn closed")
+	}
+}
+
+func benchmarkTunnel(b *testing.B, clientTLS bool) {
+	client, backend, _ := tunnelPair(b, clientTLS, time.Minute)
+	defer client.Close()
+	defer backend.Close()
+	go io.Copy(ioutil.Discard, backend)
+
+	chunk := make([]byte, 64*1024)
+	b.SetBytes(int64(len(chunk)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := client.Write(chunk); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkTunnelTCP(b *testing.B) {
+	benchmarkTunnel(b, false)
+}
+
+func BenchmarkTunnelTLS(b *testing.B) {
+	benchmarkTunnel(b, true)
+}
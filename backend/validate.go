@@ -0,0 +1,144 @@
+package backend
+
+import (
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/anxiousmodernman/co-chair/proto/server"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// fieldErrors collects what is wrong with the fields of a request, to
+// return as one InvalidArgument error. Each field is also in the error's
+// details, as a BadRequest.
+type fieldErrors []*errdetails.BadRequest_FieldViolation
+
+func (fe *fieldErrors) add(field string, err error) {
+	*fe = append(*fe, &errdetails.BadRequest_FieldViolation{Field: field, Description: err.Error()})
+}
+
+// checkIPs adds an error for each of ips that is not a host:port.
+func (fe *fieldErrors) checkIPs(field string, ips []string) {
+	for i, ip := range ips {
+		if err := validHostPort(ip); err != nil {
+			fe.add(fmt.Sprintf("%s[%d]", field, i), err)
+		}
+	}
+}
+
+// err is nil if there are no errors.
+func (fe fieldErrors) err() error {
+	if len(fe) == 0 {
+		return nil
+	}
+	msgs := make([]string, 0, len(fe))
+	for _, v := range fe {
+		msgs = append(msgs, v.Field+": "+v.Description)
+	}
+	s := status.New(codes.InvalidArgument, strings.Join(msgs, "; "))
+	if detailed, err := s.WithDetails(&errdetails.BadRequest{FieldViolations: fe}); err == nil {
+		s = detailed
+	}
+	return s.Err()
+}
+
+// invalid is an InvalidArgument error for one field.
+func invalid(field string, format string, args ...interface{}) error {
+	var fe fieldErrors
+	fe.add(field, fmt.Errorf(format, args...))
+	return fe.err()
+}
+
+var domainLabel = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?$`)
+
+// validDomain checks that domain is a hostname, or a wildcard like
+// *.example.com.
+func validDomain(domain string) error {
+	if domain == "" {
+		return errors.New("is required")
+	}
+	if len(domain) > 253 {
+		return fmt.Errorf("%q is longer than 253 characters", domain)
+	}
+	for _, label := range strings.Split(strings.TrimPrefix(strings.ToLower(domain), "*."), ".") {
+		if !domainLabel.MatchString(label) {
+			return fmt.Errorf("%q is not a valid domain", domain)
+		}
+	}
+	return nil
+}
+
+// validHostPort checks that addr is a host or IP, and a port.
+func validHostPort(addr string) error {
+	host, port, err := net.SplitHostPort(strings.TrimSpace(addr))
+	if err != nil {
+		return fmt.Errorf("%q is not host:port", addr)
+	}
+	if host == "" {
+		return fmt.Errorf("%q has no host", addr)
+	}
+	if n, err := strconv.Atoi(port); err != nil || n < 1 || n > 65535 {
+		return fmt.Errorf("%q has an invalid port", addr)
+	}
+	return nil
+}
+
+// validateBackend checks the fields of b that Put, Apply and PatchBackend
+// set, except IPs, which callers check themselves. Field names start with
+// prefix.
+func validateBackend(prefix string, b *server.Backend) fieldErrors {
+	var fe fieldErrors
+	if err := validDomain(b.Domain); err != nil {
+		fe.add(prefix+"domain", err)
+	}
+	if _, err := ParseCIDRs(b.AllowCidrs); err != nil {
+		fe.add(prefix+"allow_cidrs", err)
+	}
+	if _, err := ParseCIDRs(b.DenyCidrs); err != nil {
+		fe.add(prefix+"deny_cidrs", err)
+	}
+	for k := range b.Labels {
+		if k == "" || strings.ContainsAny(k, "=, ") {
+			fe.add(prefix+"labels", fmt.Errorf("%q is not a valid label name", k))
+		}
+	}
+	if b.Acme && strings.HasPrefix(b.Domain, "*.") {
+		fe.add(prefix+"acme", errors.New("wildcard domains need DNS-01, which we do not support"))
+	}
+	if ca := b.ClientAuth; ca != nil {
+		if len(ca.CaBundle) > 0 && !x509.NewCertPool().AppendCertsFromPEM(ca.CaBundle) {
+			fe.add(prefix+"client_auth.ca_bundle", errors.New("no certificates in CA bundle"))
+		}
+		if ca.Forward == server.ClientAuth_HEADER && b.Protocol != server.Backend_HTTP1 {
+			fe.add(prefix+"client_auth.forward", errors.New("header forwarding needs an HTTP1 backend"))
+		}
+	}
+	if pol := b.TlsPolicy; pol != nil {
+		if _, err := parseTLSPolicy(pol.MinVersion, pol.CipherSuites, pol.Curves); err != nil {
+			fe.add(prefix+"tls_policy", err)
+		}
+		if pol.HstsMaxAge > 0 && b.Protocol != server.Backend_HTTP1 {
+			fe.add(prefix+"tls_policy.hsts_max_age", errors.New("HSTS needs an HTTP1 backend"))
+		}
+	}
+	if b.BackendCert != nil {
+		if err := validateCert(b.Domain, b.BackendCert.Cert, b.BackendCert.Key); err != nil {
+			fe.add(prefix+"backend_cert", err)
+		}
+		for i, c := range b.ExtraCerts {
+			if err := validateCert(b.Domain, c.Cert, c.Key); err != nil {
+				fe.add(fmt.Sprintf("%sextra_certs[%d]", prefix, i), err)
+			}
+		}
+	} else if len(b.ExtraCerts) > 0 {
+		fe.add(prefix+"extra_certs", errors.New("extra certs need a backend cert"))
+	}
+	return fe
+}
@@ -0,0 +1,102 @@
+package backend
+
+import (
+	"context"
+	"testing"
+
+	"github.com/anxiousmodernman/co-chair/proto/server"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestValidDomain(t *testing.T) {
+	for domain, valid := range map[string]bool{
+		"www.example.com":   true,
+		"WWW.Example.com":   true,
+		"*.example.com":     true,
+		"localhost":         true,
+		"":                  false,
+		"a..example.com":    false,
+		"-a.example.com":    false,
+		"a.*.example.com":   false,
+		"www.example.com:8": false,
+	} {
+		if err := validDomain(domain); (err == nil) != valid {
+			t.Errorf("%q: expected valid=%v, got %v", domain, valid, err)
+		}
+	}
+}
+
+func TestValidHostPort(t *testing.T) {
+	for addr, valid := range map[string]bool{
+		"10.0.0.1:443":      true,
+		"[::1]:8443":        true,
+		"upstream.local:80": true,
+		"10.0.0.1":          false,
+		":443":              false,
+		"10.0.0.1:http":     false,
+		"10.0.0.1:70000":    false,
+		"garbage":           false,
+	} {
+		if err := validHostPort(addr); (err == nil) != valid {
+			t.Errorf("%q: expected valid=%v, got %v", addr, valid, err)
+		}
+	}
+}
+
+func TestValidateBackend(t *testing.T) {
+	b := &server.Backend{Domain: "*.example.com", Acme: true, AllowCidrs: []string{"10.0.0.0/33"},
+		ExtraCerts: []*server.X509Cert{{Cert: []byte("cert")}}}
+	fe := validateBackend("backends[0].", b)
+	fe.checkIPs("backends[0].ips", []string{"10.0.0.1:443", "10.0.0.2"})
+
+	s, _ := status.FromError(fe.err())
+	if s.Code() != codes.InvalidArgument {
+		t.Fatalf("expected InvalidArgument, got %v", s.Code())
+	}
+	var fields []string
+	for _, d := range s.Details() {
+		for _, v := range d.(*errdetails.BadRequest).FieldViolations {
+			fields = append(fields, v.Field)
+		}
+	}
+	want := []string{"backends[0].allow_cidrs", "backends[0].acme", "backends[0].extra_certs", "backends[0].ips[1]"}
+	if len(fields) != len(want) {
+		t.Fatalf("expected fields %v, got %v", want, fields)
+	}
+	for i := range want {
+		if fields[i] != want[i] {
+			t.Errorf("expected fields %v, got %v", want, fields)
+			break
+		}
+	}
+
+	if err := validateBackend("", &server.Backend{Domain: "www.example.com"}).err(); err != nil {
+		t.Errorf("expected a valid backend, got %v", err)
+	}
+}
+
+func TestStatusCodes(t *testing.T) {
+	p, cleanup, err := NewTestProxyCleanup()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cleanup()
+
+	_, err = p.Put(context.TODO(), &server.Backend{Ips: []string{"10.0.0.1:443"}})
+	if s, _ := status.FromError(err); s.Code() != codes.InvalidArgument {
+		t.Errorf("expected InvalidArgument for a blank domain, got %v", err)
+	}
+	_, err = p.Remove(context.TODO(), &server.Backend{Domain: "nope.example.com"})
+	if s, _ := status.FromError(err); s.Code() != codes.NotFound {
+		t.Errorf("expected NotFound, got %v", err)
+	}
+	if _, err := p.CreateClientKey(context.TODO(), &server.ClientKeyRequest{Name: "deploy", Role: "operator"}); err != nil {
+		t.Fatal(err)
+	}
+	_, err = p.CreateClientKey(context.TODO(), &server.ClientKeyRequest{Name: "deploy", Role: "operator"})
+	if s, _ := status.FromError(err); s.Code() != codes.AlreadyExists {
+		t.Errorf("expected AlreadyExists, got %v", err)
+	}
+}
@@ -23,6 +23,7 @@ func FromCLIOpts(ctx *cli.Context) (Config, error) {
 		return c, nil
 	}
 	c.DBPath = ctx.String("db")
+	c.MasterKeyFile = ctx.String("masterKeyFile")
 	c.APICert = ctx.String("apiCert")
 	c.APIKey = ctx.String("apiKey")
 	c.APIClientValidation = ctx.BoolT("apiClientValidation")
@@ -36,10 +37,26 @@ func FromCLIOpts(ctx *cli.Context) (Config, error) {
 	c.ProxyKey = ctx.String("proxyKey")
 	c.ProxyPort = ctx.String("proxyPort")
 	c.ProxyInsecurePort = ctx.String("proxyInsecurePort")
+	c.ProxyMaxConns = ctx.Int("proxyMaxConns")
+	c.ProxyClientRate = ctx.Float64("proxyClientRate")
+	c.ProxyClientBurst = ctx.Int("proxyClientBurst")
+	c.ProxyIdleSeconds = ctx.Int("proxyIdleSeconds")
+	c.ProxyDeny = ctx.StringSlice("proxyDeny")
+	c.ProxyProtocolFrom = ctx.StringSlice("proxyProtocolFrom")
+	c.ACMEDirectoryURL = ctx.String("acmeDirectoryURL")
+	c.ACMECACert = ctx.String("acmeCACert")
+	c.ACMEEmail = ctx.String("acmeEmail")
+	c.ACMERenewDays = ctx.Int("acmeRenewDays")
+	c.CertWarnDays = ctx.Int("certWarnDays")
+	c.CertWebhook = ctx.String("certWebhook")
+	c.TicketRotationHours = ctx.Int("ticketRotationHours")
+	c.AuditLogFile = ctx.String("auditLogFile")
+	c.HistoryRevisions = ctx.Int("historyRevisions")
 	c.Auth0ClientID = ctx.String("auth0ClientID")
 	c.Auth0Secret = ctx.String("auth0Secret")
 	c.Auth0Domain = ctx.String("auth0Domain")
 	c.BypassAuth0 = ctx.BoolT("bypassAuth0")
+	c.WebUIDefaultRole = ctx.String("webUIDefaultRole")
 	return c, nil
 }
 
@@ -51,6 +68,12 @@ type Config struct {
 	// Filepath to the boltdb file.
 	DBPath string `toml:"db_path"`
 
+	// MasterKeyFile is a path to a base64-encoded 32 byte key. If set,
+	// private keys and other secrets in the boltdb file are encrypted.
+	// If blank, we look in $COCHAIR_MASTER_KEY, then in the systemd
+	// credential cochair-master-key.
+	MasterKeyFile string `toml:"master_key_file"`
+
 	// APICert and APIKey are paths to PEM-encoded TLS assets
 	// for our pure gRPC api
 	APICert             string `toml:"api_cert"`
@@ -71,19 +94,73 @@ type Config struct {
 	// from the frontend directory. This is useful during ui development.
 	WebAssetsPath string `toml:"web_assets_path"`
 
-	// ProxyCert and  are paths to PEM-encoded TLS assets
-	// for our GopherJS-over-websockets UI. This UI is wrapped with
-	// auth0 handlers.
+	// ProxyCert and ProxyKey are paths to the PEM-encoded certificate
+	// our proxy serves to clients whose SNI matches no domain, such as
+	// clients connecting by IP. Changes to the files are picked up.
 	ProxyCert         string `toml:"proxy_cert"`
 	ProxyKey          string `toml:"proxy_key"`
 	ProxyPort         string `toml:"proxy_port"`
 	ProxyInsecurePort string `toml:"proxy_insecure_port"`
 
+	// Listener-wide connection limits for our proxy; zero means no limit.
+	// ProxyMaxConns caps concurrent connections. ProxyClientRate is new
+	// connections per second per client IP, with bursts of ProxyClientBurst.
+	ProxyMaxConns    int     `toml:"proxy_max_conns"`
+	ProxyClientRate  float64 `toml:"proxy_client_rate"`
+	ProxyClientBurst int     `toml:"proxy_client_burst"`
+
+	// ProxyIdleSeconds closes tunnels that have carried nothing for that
+	// long. Zero means never.
+	ProxyIdleSeconds int `toml:"proxy_idle_seconds"`
+
+	// ProxyDeny lists client CIDRs refused on every domain. ProxyProtocolFrom
+	// lists the CIDRs of load balancers we accept PROXY protocol headers from.
+	ProxyDeny         []string `toml:"proxy_deny"`
+	ProxyProtocolFrom []string `toml:"proxy_protocol_from"`
+
+	// ACME settings, for domains whose certificates we obtain automatically.
+	// A blank directory URL means Let's Encrypt. ACMECACert is a path to a
+	// PEM bundle to trust for the directory, for testing against Pebble.
+	ACMEDirectoryURL string `toml:"acme_directory_url"`
+	ACMECACert       string `toml:"acme_ca_cert"`
+	ACMEEmail        string `toml:"acme_email"`
+	ACMERenewDays    int    `toml:"acme_renew_days"`
+
+	// We warn about certificates expiring within CertWarnDays and, if
+	// CertWebhook is set, post a JSON event to it.
+	CertWarnDays int    `toml:"cert_warn_days"`
+	CertWebhook  string `toml:"cert_webhook"`
+
+	// TicketRotationHours is how often we make a new TLS session ticket
+	// key. Forwarders share our keys, so clients resume on any of them.
+	TicketRotationHours int `toml:"ticket_rotation_hours"`
+
+	// Every configuration change is kept in the DB's audit log. If
+	// AuditLogFile is set, changes are appended to it too, as JSON lines.
+	AuditLogFile string `toml:"audit_log_file"`
+
+	// HistoryRevisions is how many revisions of our routing configuration
+	// we keep, to roll back to.
+	HistoryRevisions int `toml:"history_revisions"`
+
 	// Auth0 config values
 	Auth0ClientID string `toml:"auth0_client_id"`
 	Auth0Secret   string `toml:"auth0_secret"`
 	Auth0Domain   string `toml:"auth0_domain"`
 	BypassAuth0   bool   `toml:"bypass_auth0"`
+
+	// WebUIUsers gives web UI users roles, by email. Other users get
+	// WebUIDefaultRole; if that is blank, they are admins.
+	WebUIUsers       []WebUIUser `toml:"webui_user"`
+	WebUIDefaultRole string      `toml:"webui_default_role"`
+}
+
+// WebUIUser is the role of a web UI user, optionally limited to domains
+// such as "*.team.example.com".
+type WebUIUser struct {
+	Email   string   `toml:"email"`
+	Role    string   `toml:"role"`
+	Domains []string `toml:"domains"`
 }
 
 // ExampleConfig is a default template. See the systemd-install command.
@@ -94,6 +171,12 @@ var ExampleConfig = `
 # Filepath to the boltdb file.
 db_path = ""
 
+# Path to a base64-encoded 32 byte key (e.g. "head -c32 /dev/urandom |
+# base64"). If set, secrets in the boltdb file are encrypted with it. If
+# blank, $COCHAIR_MASTER_KEY or the systemd credential cochair-master-key
+# is used, if present.
+master_key_file = ""
+
 # Paths to PEM-encoded TLS assets
 # for our pure gRPC api
 api_cert = ""
@@ -116,19 +199,68 @@ webui_port = "2016"
 web_assets_path = ""
 
 
-# ProxyCert and  are paths to PEM-encoded TLS assets
-# for our GopherJS-over-websockets UI. This UI is wrapped with
-# auth0 handlers.
+# ProxyCert and ProxyKey are paths to the PEM-encoded certificate
+# our proxy serves to clients whose SNI matches no domain, such as
+# clients connecting by IP. Changes to the files are picked up.
 proxy_cert = ""
 proxy_key = ""
 proxy_port = "443"
 
+# Listener-wide connection limits for the proxy; 0 means no limit.
+# proxy_client_rate is new connections per second per client IP.
+proxy_max_conns = 0
+proxy_client_rate = 0.0
+proxy_client_burst = 0
+
+# Close tunnels idle this many seconds, e.g. 300; 0 means never.
+proxy_idle_seconds = 0
+
+# Client CIDRs refused on every domain, e.g. ["203.0.113.0/24"].
+proxy_deny = []
+
+# CIDRs of load balancers in front of us that send PROXY protocol headers.
+proxy_protocol_from = []
+
+# ACME settings, for domains with acme set. TLS-ALPN-01 challenges are
+# answered on proxy_port, HTTP-01 challenges on proxy_insecure_port. Leave
+# acme_directory_url blank for Let's Encrypt. To test against Pebble, use
+# "https://localhost:14000/dir" and set acme_ca_cert to pebble.minica.pem.
+acme_directory_url = ""
+acme_ca_cert = ""
+acme_email = ""
+acme_renew_days = 30
+
+# Warn about certificates expiring within cert_warn_days. If cert_webhook is
+# set, a JSON event is posted to it, at most once a day per domain.
+cert_warn_days = 21
+cert_webhook = ""
+
+# How often to make a new TLS session ticket key. The last three are kept,
+# and shared with forwarders.
+ticket_rotation_hours = 24
+
+# Every configuration change is kept in the boltdb file's audit log. If set,
+# changes are appended to this file too, one JSON object per line.
+audit_log_file = ""
+
+# How many revisions of the routing configuration to keep for rollback.
+history_revisions = 100
+
 # Auth0 config values
 auth0_client_id = ""
 auth0_secret = ""
 auth0_domain = ""
 bypass_auth0 = false
 
+# Roles of web UI users: read-only, forwarder, operator or admin. Users not
+# listed get webui_default_role, or admin if it is blank.
+webui_default_role = "read-only"
+
+# [[webui_user]]
+# email = "team-lead@example.com"
+# role = "operator"
+# domains = ["*.team.example.com"]
+
 `
 
 // UnitFile can be written to /etc/systemd/system/co-chair.service
@@ -143,6 +275,10 @@ ExecStart=/usr/local/bin/co-chair serve --conf /opt/co-chair/conf.toml
 RestartSec=3
 Restart=on-failure
 
+# To encrypt secrets in the database, give co-chair a master key as a
+# systemd credential, e.g. one made with systemd-creds encrypt.
+#LoadCredentialEncrypted=cochair-master-key:/etc/credstore.encrypted/cochair-master-key
+
 # Every 10 min, try to restart the dead service.
 StartLimitInterval=10min
 
@@ -3,7 +3,12 @@ package grpcclient
 import (
 	"context"
 	"fmt"
+	"io/ioutil"
 	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
 
 	"github.com/BurntSushi/toml"
 	"github.com/Rudd-O/curvetls"
@@ -34,7 +39,8 @@ func NewCoChairClient(conf ClientConfig) (*CoChairClient, error) {
 		return nil, err
 	}
 	creds := curvetls.NewGRPCClientCredentials(spub, pub, priv)
-	conn, err := grpc.Dial(conf.ServerIP, grpc.WithTransportCredentials(creds))
+	conn, err := grpc.Dial(conf.ServerIP, grpc.WithTransportCredentials(creds),
+		grpc.WithUnaryInterceptor(describeErrors))
 	if err != nil {
 		return nil, err
 	}
@@ -44,15 +50,14 @@ func NewCoChairClient(conf ClientConfig) (*CoChairClient, error) {
 	return &CoChairClient{conf, pc}, nil
 }
 
-// Put ...
-func (c *CoChairClient) Put(domain string, ips []string) error {
-	req := server.Backend{
-		Domain: domain,
-		Ips:    ips,
-		// healthcheck todo
-	}
+// ProxyClient returns the underlying server.ProxyClient.
+func (c *CoChairClient) ProxyClient() server.ProxyClient {
+	return c.pc
+}
 
-	result, err := c.pc.Put(context.TODO(), &req)
+// Put ...
+func (c *CoChairClient) Put(req *server.Backend) error {
+	result, err := c.pc.Put(context.TODO(), req)
 	if err != nil {
 		return err
 	}
@@ -60,13 +65,20 @@ func (c *CoChairClient) Put(domain string, ips []string) error {
 	return nil
 }
 
-// State reports on the state of the proxy.
-func (c *CoChairClient) State(domain string) error {
-	req := server.StateRequest{
-		Domain: domain,
+// Patch changes some settings of an upstream, printing its new revision.
+func (c *CoChairClient) Patch(req *server.PatchRequest) error {
+	b, err := c.pc.PatchBackend(context.TODO(), req)
+	if err != nil {
+		return err
 	}
+	fmt.Printf("%s is at revision %d\n", b.Domain, b.Revision)
+	return nil
+}
 
-	proxyState, err := c.pc.State(context.TODO(), &req)
+// State reports on the state of the proxy: the upstreams req matches, or a
+// page of them if req.PageSize is set.
+func (c *CoChairClient) State(req *server.StateRequest) error {
+	proxyState, err := c.pc.State(context.TODO(), req)
 	if err != nil {
 		return err
 	}
@@ -74,14 +86,428 @@ func (c *CoChairClient) State(domain string) error {
 	fmt.Println("---")
 	printUpstream := func(be *server.Backend) {
 		fmt.Println("domain:", be.Domain)
+		fmt.Println("revision:", be.Revision)
+		if len(be.Labels) > 0 {
+			var labels []string
+			for k, v := range be.Labels {
+				labels = append(labels, k+"="+v)
+			}
+			sort.Strings(labels)
+			fmt.Println("labels:", strings.Join(labels, ", "))
+		}
 		for _, ip := range be.Ips {
 			fmt.Println("\t", ip)
 		}
+		if be.MaxConns > 0 || be.ClientRate > 0 {
+			fmt.Printf("limits: max_conns=%d client_rate=%g client_burst=%d\n",
+				be.MaxConns, be.ClientRate, be.ClientBurst)
+		}
+		if len(be.AllowCidrs) > 0 {
+			fmt.Println("allow:", strings.Join(be.AllowCidrs, ", "))
+		}
+		if len(be.DenyCidrs) > 0 {
+			fmt.Println("deny:", strings.Join(be.DenyCidrs, ", "))
+		}
+		if be.Acme {
+			fmt.Println("certificate: acme")
+		}
+		if ca := be.ClientAuth; ca != nil {
+			fmt.Printf("client certs: required; names=%s forward=%v\n",
+				strings.Join(ca.AllowedNames, ","), ca.Forward)
+		}
+		if pol := be.TlsPolicy; pol != nil {
+			fmt.Printf("tls: min_version=%s ciphers=%s curves=%s hsts_max_age=%d\n",
+				pol.MinVersion, strings.Join(pol.CipherSuites, ","), strings.Join(pol.Curves, ","), pol.HstsMaxAge)
+		}
 		fmt.Println("---")
 	}
 	for _, be := range proxyState.Backends {
 		printUpstream(be)
 	}
+	if proxyState.NextPageToken != "" {
+		fmt.Printf("showing %d of %d; next page: --pageToken %s\n",
+			len(proxyState.Backends), proxyState.TotalSize, proxyState.NextPageToken)
+	}
+	return nil
+}
+
+// CA prints our internal CA's bundle, or its revocation list if crl is set.
+func (c *CoChairClient) CA(crl bool) error {
+	info, err := c.pc.GetCA(context.TODO(), &server.CARequest{})
+	if err != nil {
+		return err
+	}
+	if crl {
+		fmt.Print(string(info.Crl))
+		return nil
+	}
+	fmt.Print(string(info.Bundle))
+	return nil
+}
+
+// IssueClientCert prints a new client certificate and its private key.
+func (c *CoChairClient) IssueClientCert(name string) error {
+	cert, err := c.pc.IssueClientCert(context.TODO(), &server.IssueRequest{Name: name})
+	if err != nil {
+		return err
+	}
+	fmt.Print(string(cert.Cert))
+	fmt.Print(string(cert.Key))
+	return nil
+}
+
+// Revoke ...
+func (c *CoChairClient) Revoke(serial string) error {
+	result, err := c.pc.Revoke(context.TODO(), &server.RevokeRequest{Serial: serial})
+	if err != nil {
+		return err
+	}
+	fmt.Println("status:", result.Status)
+	return nil
+}
+
+// CreateClientKey prints a new client keypair.
+func (c *CoChairClient) CreateClientKey(name, role string, domains []string) error {
+	req := &server.ClientKeyRequest{Name: name, Role: role, Domains: domains}
+	ck, err := c.pc.CreateClientKey(context.TODO(), req)
+	if err != nil {
+		return err
+	}
+	PrintClientKey(ck)
+	return nil
+}
+
+// RotateClientKey prints a client's new keypair.
+func (c *CoChairClient) RotateClientKey(name string) error {
+	ck, err := c.pc.RotateClientKey(context.TODO(), &server.ClientKeyRequest{Name: name})
+	if err != nil {
+		return err
+	}
+	PrintClientKey(ck)
+	return nil
+}
+
+// SetClientKeyRole changes what a client may do.
+func (c *CoChairClient) SetClientKeyRole(name, role string, domains []string) error {
+	req := &server.ClientKeyRequest{Name: name, Role: role, Domains: domains}
+	ck, err := c.pc.SetClientKeyRole(context.TODO(), req)
+	if err != nil {
+		return err
+	}
+	printClientKey(ck)
+	return nil
+}
+
+// RevokeClientKey ...
+func (c *CoChairClient) RevokeClientKey(name string) error {
+	result, err := c.pc.RevokeClientKey(context.TODO(), &server.ClientKeyRequest{Name: name})
+	if err != nil {
+		return err
+	}
+	fmt.Println("status:", result.Status)
+	return nil
+}
+
+// ListClientKeys reports on every client key.
+func (c *CoChairClient) ListClientKeys() error {
+	list, err := c.pc.ListClientKeys(context.TODO(), &server.ClientKeysRequest{})
+	if err != nil {
+		return err
+	}
+	for _, ck := range list.Keys {
+		printClientKey(ck)
+	}
+	return nil
+}
+
+func printClientKey(ck *server.ClientKey) {
+	fmt.Println("name:", ck.Name)
+	fmt.Println("\tpub:", ck.Pub)
+	role := ck.Role
+	if role == "" {
+		role = "admin (no role set)"
+	}
+	fmt.Println("\trole:", role)
+	if len(ck.Domains) > 0 {
+		fmt.Println("\tdomains:", strings.Join(ck.Domains, ", "))
+	}
+	if ck.Created != 0 {
+		fmt.Println("\tcreated:", time.Unix(ck.Created, 0).UTC().Format(time.RFC3339))
+	}
+	if ck.Revoked != 0 {
+		fmt.Println("\trevoked:", time.Unix(ck.Revoked, 0).UTC().Format(time.RFC3339))
+	}
+}
+
+// PrintClientKey prints a new client keypair. The private key is not kept
+// anywhere else.
+func PrintClientKey(ck *server.ClientKey) {
+	fmt.Println("The following are new curveTLS public and private keys.")
+	fmt.Println()
+	fmt.Println("client public key (new):\t", ck.Pub)
+	fmt.Println("client private key (new):\t", ck.Priv)
+	fmt.Println("server public key:\t", ck.ServerPub)
+}
+
+// Certificates reports on the certificate of domain, or of every domain if
+// domain is blank.
+func (c *CoChairClient) Certificates(domain string) error {
+	var certs []*server.CertificateInfo
+	if domain == "" {
+		list, err := c.pc.ListCertificates(context.TODO(), &server.CertificatesRequest{})
+		if err != nil {
+			return err
+		}
+		certs = list.Certificates
+	} else {
+		info, err := c.pc.GetCertificate(context.TODO(), &server.CertificateRequest{Domain: domain})
+		if err != nil {
+			return err
+		}
+		certs = append(certs, info)
+	}
+	for _, info := range certs {
+		fmt.Println("domain:", info.Domain)
+		fmt.Println("source:", info.Source)
+		if info.Staged {
+			fmt.Println("staged: true")
+		}
+		if info.Error != "" {
+			fmt.Println("error:", info.Error)
+			fmt.Println("---")
+			continue
+		}
+		fmt.Println("subject:", info.Subject)
+		fmt.Println("sans:", strings.Join(info.Sans, ", "))
+		fmt.Println("issuer:", info.Issuer)
+		fmt.Println("not after:", time.Unix(info.NotAfter, 0).Format(time.RFC3339))
+		fmt.Println("key:", info.KeyType)
+		fmt.Println("fingerprint:", info.Fingerprint)
+		fmt.Println("---")
+	}
+	return nil
+}
+
+// StageCertificates ...
+func (c *CoChairClient) StageCertificates(req *server.StageRequest) error {
+	result, err := c.pc.StageCertificates(context.TODO(), req)
+	if err != nil {
+		return err
+	}
+	fmt.Println("status:", result.Status)
+	return nil
+}
+
+// PromoteCertificates ...
+func (c *CoChairClient) PromoteCertificates(domain string) error {
+	result, err := c.pc.PromoteCertificates(context.TODO(), &server.PromoteRequest{Domain: domain})
+	if err != nil {
+		return err
+	}
+	fmt.Println("status:", result.Status)
+	return nil
+}
+
+// ServerKeys reports our server's public keys.
+func (c *CoChairClient) ServerKeys() error {
+	keys, err := c.pc.GetServerKeys(context.TODO(), &server.ServerKeysRequest{})
+	if err != nil {
+		return err
+	}
+	printServerKeys(keys)
+	return nil
+}
+
+// RotateServerKey starts a rotation of our server's key.
+func (c *CoChairClient) RotateServerKey() error {
+	keys, err := c.pc.RotateServerKey(context.TODO(), &server.ServerKeysRequest{})
+	if err != nil {
+		return err
+	}
+	printServerKeys(keys)
+	return nil
+}
+
+// RetireServerKey ends a rotation of our server's key.
+func (c *CoChairClient) RetireServerKey() error {
+	keys, err := c.pc.RetireServerKey(context.TODO(), &server.ServerKeysRequest{})
+	if err != nil {
+		return err
+	}
+	printServerKeys(keys)
+	return nil
+}
+
+func printServerKeys(keys *server.ServerKeys) {
+	fmt.Println("server public key:\t", keys.Current)
+	if keys.Next != "" {
+		fmt.Println("next public key:\t", keys.Next)
+		fmt.Println("rotating since:\t", time.Unix(keys.NextCreated, 0).UTC().Format(time.RFC3339))
+	}
+}
+
+// UpdateServerKey sets server_public_key in the client config at path to
+// the server's next key during a rotation, or its current key. It returns
+// the key.
+func (c *CoChairClient) UpdateServerKey(path string) (string, error) {
+	keys, err := c.pc.GetServerKeys(context.TODO(), &server.ServerKeysRequest{})
+	if err != nil {
+		return "", err
+	}
+	pub := keys.Current
+	if keys.Next != "" {
+		pub = keys.Next
+	}
+	if pub == c.conf.ServerPubKey {
+		return pub, nil
+	}
+	return pub, setServerPubKey(configPath(path), pub)
+}
+
+var serverPubKeyLine = regexp.MustCompile(`(?m)^\s*server_public_key\s*=.*$`)
+
+// setServerPubKey rewrites one line of the config, keeping the rest of the
+// file as it is.
+func setServerPubKey(path, pub string) error {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	line := fmt.Sprintf("server_public_key = %q", pub)
+	if serverPubKeyLine.Match(data) {
+		data = serverPubKeyLine.ReplaceAllLiteral(data, []byte(line))
+	} else {
+		data = append(data, []byte("\n"+line+"\n")...)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, data, info.Mode())
+}
+
+// AuditEvents reports configuration changes, newest first.
+func (c *CoChairClient) AuditEvents(req *server.AuditRequest) error {
+	list, err := c.pc.ListAuditEvents(context.TODO(), req)
+	if err != nil {
+		return err
+	}
+	for _, e := range list.Events {
+		actor := e.Actor
+		if e.Role != "" {
+			actor += " (" + e.Role + ")"
+		}
+		fmt.Printf("%s %s %s by %s", time.Unix(e.Time, 0).UTC().Format(time.RFC3339), e.Method, e.Target, actor)
+		if e.Source != "" {
+			fmt.Printf(" from %s", e.Source)
+		}
+		fmt.Println()
+		for _, ch := range e.Changes {
+			fmt.Printf("\t%s: %q -> %q\n", ch.Field, ch.Before, ch.After)
+		}
+	}
+	return nil
+}
+
+// Revisions lists revisions of the routing configuration, newest first.
+func (c *CoChairClient) Revisions(limit int) error {
+	list, err := c.pc.ListRevisions(context.TODO(), &server.RevisionsRequest{Limit: int32(limit)})
+	if err != nil {
+		return err
+	}
+	for _, r := range list.Revisions {
+		fmt.Printf("%d\t%s\t%s %s by %s, %d domains\n", r.Id,
+			time.Unix(r.Time, 0).UTC().Format(time.RFC3339), r.Method, r.Target, r.Actor, r.Domains)
+	}
+	return nil
+}
+
+// DiffRevisions prints what changed between two revisions. A to of 0
+// means now.
+func (c *CoChairClient) DiffRevisions(from, to int64) error {
+	diff, err := c.pc.DiffRevisions(context.TODO(), &server.DiffRequest{From: from, To: to})
+	if err != nil {
+		return err
+	}
+	printDomainDiffs(diff.Domains)
+	return nil
+}
+
+func printDomainDiffs(domains []*server.DomainDiff) {
+	if len(domains) == 0 {
+		fmt.Println("no changes")
+	}
+	for _, d := range domains {
+		fmt.Println("domain:", d.Domain)
+		for _, ch := range d.Changes {
+			fmt.Printf("\t%s: %q -> %q\n", ch.Field, ch.Before, ch.After)
+		}
+	}
+}
+
+// Apply sets the routing configuration to backends, printing what
+// changed. With dryRun, the server only validates backends and says what
+// would change.
+func (c *CoChairClient) Apply(backends []*server.Backend, prune, dryRun bool) error {
+	result, err := c.pc.Apply(context.TODO(), &server.ApplyRequest{
+		Backends: backends, Prune: prune, DryRun: dryRun})
+	if err != nil {
+		return err
+	}
+	printDomainDiffs(result.Changes)
+	switch {
+	case dryRun:
+		fmt.Println("dry run; nothing was changed")
+	case result.Revision != 0:
+		fmt.Println("saved revision", result.Revision)
+	}
+	return nil
+}
+
+// Rollback restores the routing configuration of a revision.
+func (c *CoChairClient) Rollback(revision int64) error {
+	result, err := c.pc.Rollback(context.TODO(), &server.RollbackRequest{Revision: revision})
+	if err != nil {
+		return err
+	}
+	fmt.Println("status:", result.Status)
+	return nil
+}
+
+// Export returns our configuration as a JSON document. Private keys are
+// encrypted with passphrase, or left out if it is blank.
+func (c *CoChairClient) Export(passphrase string) ([]byte, error) {
+	doc, err := c.pc.Export(context.TODO(), &server.ExportRequest{Passphrase: passphrase})
+	if err != nil {
+		return nil, err
+	}
+	return doc.Data, nil
+}
+
+// Import adds an exported document to our configuration, printing
+// conflicts, warnings and changes.
+func (c *CoChairClient) Import(data []byte, passphrase string, overwrite, dryRun bool) error {
+	result, err := c.pc.Import(context.TODO(), &server.ImportRequest{
+		Data: data, Passphrase: passphrase, Overwrite: overwrite, DryRun: dryRun})
+	if err != nil {
+		return err
+	}
+	for _, w := range result.Warnings {
+		fmt.Println("warning:", w)
+	}
+	if len(result.Conflicts) > 0 {
+		for _, conflict := range result.Conflicts {
+			fmt.Println("conflict:", conflict)
+		}
+		return fmt.Errorf("%d conflicts; nothing was imported; pass --overwrite to replace ours", len(result.Conflicts))
+	}
+	printDomainDiffs(result.Changes)
+	switch {
+	case dryRun:
+		fmt.Println("dry run; nothing was changed")
+	case result.Revision != 0:
+		fmt.Println("saved revision", result.Revision)
+	}
 	return nil
 }
 
@@ -96,12 +522,16 @@ type ClientConfig struct {
 // NewClientConfig ...
 func NewClientConfig(path string) (ClientConfig, error) {
 	var conf ClientConfig
-	if path == "" {
-		// default client config path
-		path = os.ExpandEnv("$HOME/.config/co-chair/client.toml")
-	}
-	if _, err := toml.DecodeFile(path, &conf); err != nil {
+	if _, err := toml.DecodeFile(configPath(path), &conf); err != nil {
 		return conf, err
 	}
 	return conf, nil
 }
+
+func configPath(path string) string {
+	if path == "" {
+		// default client config path
+		return os.ExpandEnv("$HOME/.config/co-chair/client.toml")
+	}
+	return path
+}
@@ -0,0 +1,73 @@
+package grpcclient
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Error is an error returned by co-chair: its status code, message, and
+// the request fields it found invalid, if any.
+type Error struct {
+	Code    codes.Code
+	Message string
+	Fields  []*errdetails.BadRequest_FieldViolation
+}
+
+func (e *Error) Error() string {
+	if len(e.Fields) == 0 {
+		return fmt.Sprintf("%s: %s", codeName(e.Code), e.Message)
+	}
+	lines := []string{codeName(e.Code) + ":"}
+	for _, f := range e.Fields {
+		lines = append(lines, fmt.Sprintf("\t%s: %s", f.Field, f.Description))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// codeName is a status code as words, e.g. "invalid argument".
+func codeName(c codes.Code) string {
+	var words []rune
+	for i, r := range c.String() {
+		if i > 0 && r >= 'A' && r <= 'Z' {
+			words = append(words, ' ')
+		}
+		words = append(words, r)
+	}
+	return strings.ToLower(string(words))
+}
+
+// FromError returns err as an *Error, if it is a status from co-chair.
+func FromError(err error) (*Error, bool) {
+	if e, ok := err.(*Error); ok {
+		return e, true
+	}
+	s, ok := status.FromError(err)
+	if !ok || s.Code() == codes.OK {
+		return nil, false
+	}
+	e := &Error{Code: s.Code(), Message: s.Message()}
+	for _, d := range s.Details() {
+		if br, ok := d.(*errdetails.BadRequest); ok {
+			e.Fields = append(e.Fields, br.FieldViolations...)
+		}
+	}
+	return e, true
+}
+
+// describeErrors makes the status errors of unary calls *Errors, which
+// print more plainly.
+func describeErrors(ctx context.Context, method string, req, reply interface{},
+	cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+
+	err := invoker(ctx, method, req, reply, cc, opts...)
+	if e, ok := FromError(err); ok {
+		return e
+	}
+	return err
+}
@@ -0,0 +1,144 @@
+package grpcclient
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v2"
+
+	"github.com/anxiousmodernman/co-chair/proto/server"
+)
+
+// Routes is a routing configuration file for Apply, in TOML, YAML or
+// JSON. Paths to certs and keys are relative to the file.
+type Routes struct {
+	Backends []RouteBackend `toml:"backend" yaml:"backend" json:"backend"`
+}
+
+// RouteBackend is a backend in a Routes file. Its fields are the flags of
+// co-chair put.
+type RouteBackend struct {
+	Domain                string            `toml:"domain" yaml:"domain" json:"domain"`
+	IPs                   []string          `toml:"ips" yaml:"ips" json:"ips"`
+	Protocol              string            `toml:"protocol" yaml:"protocol" json:"protocol"`
+	MatchHeaders          map[string]string `toml:"match_headers" yaml:"match_headers" json:"match_headers"`
+	MaxConns              int32             `toml:"max_conns" yaml:"max_conns" json:"max_conns"`
+	ClientRate            float64           `toml:"client_rate" yaml:"client_rate" json:"client_rate"`
+	ClientBurst           int32             `toml:"client_burst" yaml:"client_burst" json:"client_burst"`
+	Allow                 []string          `toml:"allow" yaml:"allow" json:"allow"`
+	Deny                  []string          `toml:"deny" yaml:"deny" json:"deny"`
+	ACME                  bool              `toml:"acme" yaml:"acme" json:"acme"`
+	Cert                  string            `toml:"cert" yaml:"cert" json:"cert"`
+	Key                   string            `toml:"key" yaml:"key" json:"key"`
+	ClientCA              string            `toml:"client_ca" yaml:"client_ca" json:"client_ca"`
+	ClientNames           []string          `toml:"client_names" yaml:"client_names" json:"client_names"`
+	ForwardIdentity       string            `toml:"forward_identity" yaml:"forward_identity" json:"forward_identity"`
+	TLSMinVersion         string            `toml:"tls_min_version" yaml:"tls_min_version" json:"tls_min_version"`
+	TLSCipherSuites       []string          `toml:"tls_cipher_suites" yaml:"tls_cipher_suites" json:"tls_cipher_suites"`
+	TLSCurves             []string          `toml:"tls_curves" yaml:"tls_curves" json:"tls_curves"`
+	HSTSMaxAge            int64             `toml:"hsts_max_age" yaml:"hsts_max_age" json:"hsts_max_age"`
+	HSTSIncludeSubdomains bool              `toml:"hsts_include_subdomains" yaml:"hsts_include_subdomains" json:"hsts_include_subdomains"`
+	Labels                map[string]string `toml:"labels" yaml:"labels" json:"labels"`
+}
+
+// LoadRoutes reads a Routes file, picking its format by extension.
+func LoadRoutes(path string) ([]*server.Backend, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var routes Routes
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".toml":
+		_, err = toml.Decode(string(data), &routes)
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &routes)
+	case ".json":
+		err = json.Unmarshal(data, &routes)
+	default:
+		return nil, fmt.Errorf("%s: unknown format %q; use .toml, .yaml or .json", path, ext)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+
+	dir := filepath.Dir(path)
+	var backends []*server.Backend
+	for _, rb := range routes.Backends {
+		b, err := rb.backend(dir)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s: %v", path, rb.Domain, err)
+		}
+		backends = append(backends, b)
+	}
+	return backends, nil
+}
+
+func (rb RouteBackend) backend(dir string) (*server.Backend, error) {
+	b := &server.Backend{
+		Domain:       rb.Domain,
+		Ips:          rb.IPs,
+		MatchHeaders: rb.MatchHeaders,
+		MaxConns:     rb.MaxConns,
+		ClientRate:   rb.ClientRate,
+		ClientBurst:  rb.ClientBurst,
+		AllowCidrs:   rb.Allow,
+		DenyCidrs:    rb.Deny,
+		Acme:         rb.ACME,
+		Labels:       rb.Labels,
+	}
+	if rb.Protocol != "" {
+		proto, ok := server.Backend_Protocol_value[strings.ToUpper(rb.Protocol)]
+		if !ok {
+			return nil, fmt.Errorf("unknown protocol: %s", rb.Protocol)
+		}
+		b.Protocol = server.Backend_Protocol(proto)
+	}
+	read := func(name string) ([]byte, error) {
+		if !filepath.IsAbs(name) {
+			name = filepath.Join(dir, name)
+		}
+		return ioutil.ReadFile(name)
+	}
+	if rb.Cert != "" || rb.Key != "" {
+		cert, err := read(rb.Cert)
+		if err != nil {
+			return nil, err
+		}
+		key, err := read(rb.Key)
+		if err != nil {
+			return nil, err
+		}
+		b.BackendCert = &server.X509Cert{Cert: cert, Key: key}
+	}
+	if rb.ClientCA != "" {
+		bundle, err := read(rb.ClientCA)
+		if err != nil {
+			return nil, err
+		}
+		fwd, ok := server.ClientAuth_Forward_value[strings.ToUpper(rb.ForwardIdentity)]
+		if rb.ForwardIdentity != "" && !ok {
+			return nil, fmt.Errorf("unknown forward_identity: %s", rb.ForwardIdentity)
+		}
+		b.ClientAuth = &server.ClientAuth{
+			CaBundle:     bundle,
+			AllowedNames: rb.ClientNames,
+			Forward:      server.ClientAuth_Forward(fwd),
+		}
+	}
+	if rb.TLSMinVersion != "" || len(rb.TLSCipherSuites) > 0 || len(rb.TLSCurves) > 0 ||
+		rb.HSTSMaxAge != 0 || rb.HSTSIncludeSubdomains {
+		b.TlsPolicy = &server.TLSPolicy{
+			MinVersion:            rb.TLSMinVersion,
+			CipherSuites:          rb.TLSCipherSuites,
+			Curves:                rb.TLSCurves,
+			HstsMaxAge:            rb.HSTSMaxAge,
+			HstsIncludeSubdomains: rb.HSTSIncludeSubdomains,
+		}
+	}
+	return b, nil
+}
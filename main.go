@@ -4,13 +4,19 @@ import (
 	"crypto/tls"
 	"encoding/gob"
 	"errors"
+	"expvar"
 	"fmt"
+	"io/ioutil"
 	"log"
 	"math"
 	"net"
 	"net/http"
 	"os"
+	"os/signal"
 	"regexp"
+	"strconv"
+	"strings"
+	"syscall"
 	"time"
 
 	"google.golang.org/grpc"
@@ -96,6 +102,27 @@ func main() {
 		Value: "co-chair.db",
 	}
 
+	clientRole := cli.StringFlag{
+		Name:  "role",
+		Usage: "for client keys: read-only, forwarder, operator or admin",
+		Value: "read-only",
+	}
+
+	clientScope := cli.StringSliceFlag{
+		Name:  "scope",
+		Usage: "for client keys: a domain the client may manage, e.g. *.team.example.com; repeatable",
+	}
+
+	masterKeyFile := cli.StringFlag{
+		Name:  "masterKeyFile",
+		Usage: "path to the key that encrypts secrets in the boltdb file",
+	}
+
+	passphraseFile := cli.StringFlag{
+		Name:  "passphraseFile",
+		Usage: "file with the passphrase that encrypts private keys in exported documents",
+	}
+
 	apiCert := cli.StringFlag{
 		Name:  "apiCert",
 		Usage: "for grpc mgmt api: path to pem encoded tls certificate",
@@ -160,6 +187,16 @@ func main() {
 		Value: "./key.pem",
 	}
 
+	backendClientCert := cli.StringFlag{
+		Name:  "backendClientCert",
+		Usage: "for forwarder: path to the pem encoded client certificate we present to backends",
+	}
+
+	backendClientKey := cli.StringFlag{
+		Name:  "backendClientKey",
+		Usage: "for forwarder: path to the pem encoded private key of backendClientCert",
+	}
+
 	proxyPort := cli.StringFlag{
 		Name:  "proxyPort",
 		Usage: "port number for http proxy",
@@ -171,6 +208,85 @@ func main() {
 		Usage: "if provided, start a plaintext HTTP proxy on this port",
 	}
 
+	proxyMaxConns := cli.IntFlag{
+		Name:  "proxyMaxConns",
+		Usage: "for proxy: max concurrent connections; 0 means no limit",
+	}
+
+	proxyClientRate := cli.Float64Flag{
+		Name:  "proxyClientRate",
+		Usage: "for proxy: new connections per second per client IP; 0 means no limit",
+	}
+
+	proxyClientBurst := cli.IntFlag{
+		Name:  "proxyClientBurst",
+		Usage: "for proxy: connections a client IP may open in a burst",
+	}
+
+	proxyIdleSeconds := cli.IntFlag{
+		Name:  "proxyIdleSeconds",
+		Usage: "for proxy: close tunnels idle this many seconds; 0 means never",
+	}
+
+	proxyDeny := cli.StringSliceFlag{
+		Name:  "proxyDeny",
+		Usage: "for proxy: client CIDRs refused on every domain",
+	}
+
+	acmeDirectoryURL := cli.StringFlag{
+		Name:  "acmeDirectoryURL",
+		Usage: "ACME directory for domains with acme set; defaults to Let's Encrypt",
+	}
+
+	acmeCACert := cli.StringFlag{
+		Name:  "acmeCACert",
+		Usage: "path to a PEM CA bundle to trust for the ACME directory, e.g. Pebble's",
+	}
+
+	acmeEmail := cli.StringFlag{
+		Name:  "acmeEmail",
+		Usage: "contact email for our ACME account",
+	}
+
+	acmeRenewDays := cli.IntFlag{
+		Name:  "acmeRenewDays",
+		Usage: "renew ACME certificates this many days before they expire",
+		Value: 30,
+	}
+
+	certWarnDays := cli.IntFlag{
+		Name:  "certWarnDays",
+		Usage: "warn about certificates expiring within this many days",
+		Value: 21,
+	}
+
+	certWebhook := cli.StringFlag{
+		Name:  "certWebhook",
+		Usage: "URL to post certificate expiry events to",
+	}
+
+	historyRevisions := cli.IntFlag{
+		Name:  "historyRevisions",
+		Usage: "how many revisions of the routing configuration to keep for rollback",
+		Value: backend.DefaultRevisionsKept,
+	}
+
+	auditLogFile := cli.StringFlag{
+		Name:  "auditLogFile",
+		Usage: "file to append audit events to, as JSON lines",
+	}
+
+	ticketRotationHours := cli.IntFlag{
+		Name:  "ticketRotationHours",
+		Usage: "how often to make a new TLS session ticket key",
+		Value: 24,
+	}
+
+	proxyProtocolFrom := cli.StringSliceFlag{
+		Name:  "proxyProtocolFrom",
+		Usage: "for proxy: CIDRs of load balancers we accept PROXY protocol headers from",
+	}
+
 	auth0ClientID := cli.StringFlag{
 		Name:   "auth0ClientID",
 		Usage:  "Auth0 Client ID for this co-chair instance",
@@ -188,11 +304,22 @@ func main() {
 		EnvVar: "COCHAIR_AUTH0_SECRET",
 	}
 
+	webUIDefaultRole := cli.StringFlag{
+		Name:  "webUIDefaultRole",
+		Usage: "role of web UI users: read-only, forwarder, operator or admin; blank means admin",
+	}
+
 	bypassAuth0 := cli.BoolFlag{
 		Name:  "bypassAuth0",
 		Usage: "totally bypass auth0; insecure development mode",
 	}
 
+	syncInterval := cli.DurationFlag{
+		Name:  "syncInterval",
+		Usage: "for forwarders: how often to sync routes from the control plane",
+		Value: backend.DefaultSyncInterval,
+	}
+
 	// client-only flags
 	var (
 		upstreamDomain = cli.StringFlag{
@@ -204,6 +331,87 @@ func main() {
 			Name:  "ips",
 			Usage: "comma-separated list of the real host:port of an upstream; pair with --\"domain\"",
 		}
+
+		upstreamMaxConns = cli.IntFlag{
+			Name:  "maxConns",
+			Usage: "max concurrent connections to an upstream; 0 means no limit",
+		}
+
+		upstreamClientRate = cli.Float64Flag{
+			Name:  "clientRate",
+			Usage: "new connections per second per client IP to an upstream; 0 means no limit",
+		}
+
+		upstreamClientBurst = cli.IntFlag{
+			Name:  "clientBurst",
+			Usage: "connections a client IP may open to an upstream in a burst",
+		}
+
+		upstreamAllow = cli.StringSliceFlag{
+			Name:  "allow",
+			Usage: "client CIDRs allowed to reach an upstream; if set, all others are refused",
+		}
+
+		upstreamDeny = cli.StringSliceFlag{
+			Name:  "deny",
+			Usage: "client CIDRs refused by an upstream",
+		}
+
+		upstreamClientCA = cli.StringFlag{
+			Name:  "clientCA",
+			Usage: "path to a PEM CA bundle; if set, clients need a certificate from it",
+		}
+
+		upstreamClientName = cli.StringSliceFlag{
+			Name:  "clientName",
+			Usage: "client certificate CN or SAN allowed to connect, e.g. \"*.ops.example.com\"",
+		}
+
+		upstreamACME = cli.BoolFlag{
+			Name:  "acme",
+			Usage: "obtain and renew the upstream's certificate over ACME",
+		}
+
+		upstreamTLSMinVersion = cli.StringFlag{
+			Name:  "tlsMinVersion",
+			Usage: "minimum TLS version clients may use: 1.0, 1.1, 1.2, or 1.3",
+		}
+
+		upstreamTLSCipherSuites = cli.StringSliceFlag{
+			Name:  "tlsCipherSuite",
+			Usage: "TLS 1.2 cipher suite clients may use, by Go name; repeat for more",
+		}
+
+		upstreamTLSCurves = cli.StringSliceFlag{
+			Name:  "tlsCurve",
+			Usage: "key exchange curve, in order of preference: X25519, P256, P384, or P521",
+		}
+
+		upstreamHSTSMaxAge = cli.Int64Flag{
+			Name:  "hstsMaxAge",
+			Usage: "add Strict-Transport-Security with this max-age in seconds to HTTP1 responses",
+		}
+
+		upstreamHSTSIncludeSubdomains = cli.BoolFlag{
+			Name:  "hstsIncludeSubdomains",
+			Usage: "add includeSubDomains to Strict-Transport-Security",
+		}
+
+		upstreamForwardIdentity = cli.StringFlag{
+			Name:  "forwardIdentity",
+			Usage: "pass client certificate identity upstream: NONE, HEADER, or PROXY_V2",
+			Value: "NONE",
+		}
+
+		upstreamRevision = cli.Int64Flag{
+			Name:  "revision",
+			Usage: "fail unless the upstream is still at this revision, as shown by state",
+		}
+
+		upstreamLabels = cli.StringSliceFlag{
+			Name:  "label",
+			Usage: "a label of an upstream, as name=value; repeatable",
+		}
 	)
 	app.Commands = []cli.Command{
 		cli.Command{
@@ -214,20 +422,87 @@ func main() {
 				return nil
 			},
 		},
+		cli.Command{
+			Name:  "forwarder",
+			Usage: "run only the proxy, syncing routes from a remote co-chair; --conf is a client config",
+			Flags: []cli.Flag{conf, proxyPort, proxyMaxConns, proxyClientRate, proxyClientBurst,
+				proxyIdleSeconds, proxyDeny, proxyProtocolFrom, syncInterval, proxyCert, proxyKey,
+				backendClientCert, backendClientKey},
+			Action: func(ctx *cli.Context) error {
+				clientConf, err := grpcclient.NewClientConfig(ctx.String("conf"))
+				if err != nil {
+					return err
+				}
+				c, err := grpcclient.NewCoChairClient(clientConf)
+				if err != nil {
+					return err
+				}
+				deny, err := backend.ParseCIDRs(ctx.StringSlice("proxyDeny"))
+				if err != nil {
+					return fmt.Errorf("proxyDeny: %v", err)
+				}
+				proxyProtocol, err := backend.ParseCIDRs(ctx.StringSlice("proxyProtocolFrom"))
+				if err != nil {
+					return fmt.Errorf("proxyProtocolFrom: %v", err)
+				}
+				opts := []backend.Opt{
+					backend.WithAddr(fmt.Sprintf("0.0.0.0:%s", ctx.String("proxyPort"))),
+					backend.WithLimits(backend.Limits{
+						MaxConns:    ctx.Int("proxyMaxConns"),
+						ClientRate:  ctx.Float64("proxyClientRate"),
+						ClientBurst: ctx.Int("proxyClientBurst"),
+					}),
+					backend.WithIdleTimeout(time.Duration(ctx.Int("proxyIdleSeconds")) * time.Second),
+					backend.WithDeny(deny),
+					backend.WithProxyProtocol(proxyProtocol),
+				}
+				// proxyCert and proxyKey have defaults for serve, which
+				// forwarders only use when asked to.
+				if ctx.IsSet("proxyCert") || ctx.IsSet("proxyKey") {
+					defaultCert, err := backend.NewCertReloader(ctx.String("proxyCert"), ctx.String("proxyKey"), logger)
+					if err != nil {
+						return err
+					}
+					defaultCert.Start(time.Minute)
+					defer defaultCert.Stop()
+					opts = append(opts, backend.WithDefaultCertificate(defaultCert))
+				}
+				if ctx.String("backendClientCert") != "" || ctx.String("backendClientKey") != "" {
+					clientCert, err := backend.NewCertReloader(ctx.String("backendClientCert"), ctx.String("backendClientKey"), logger)
+					if err != nil {
+						return fmt.Errorf("backend client cert: %v", err)
+					}
+					clientCert.Start(time.Minute)
+					defer clientCert.Stop()
+					opts = append(opts, backend.WithClientCertificate(clientCert.Certificate))
+				}
+				return forward(c.ProxyClient(), ctx.Duration("syncInterval"), opts...)
+			},
+		},
 		cli.Command{
 			Name:  "gen-client-keys",
 			Usage: "generate a (curvetls) client keypair and add public key to the keystore; co-chair cannot be running",
-			Flags: []cli.Flag{conf, dbFlag},
+			Flags: []cli.Flag{conf, dbFlag, masterKeyFile, cli.StringFlag{
+				Name:  "role",
+				Usage: "read-only, forwarder, operator or admin",
+				Value: "admin",
+			}},
 			Action: func(ctx *cli.Context) error {
 				// name is the identifier of our keypair
 				name := ctx.Args().First()
-				return genClientKeypair(name, ctx.String("db"))
+				return genClientKeypair(name, ctx.String("role"), ctx.String("db"), ctx.String("masterKeyFile"))
 			},
 		},
 		cli.Command{
 			Name:  "put",
 			Usage: "add an upstream to the proxy",
-			Flags: []cli.Flag{conf, upstreamDomain, upstreamIPs},
+			Flags: []cli.Flag{conf, upstreamDomain, upstreamIPs,
+				upstreamMaxConns, upstreamClientRate, upstreamClientBurst,
+				upstreamAllow, upstreamDeny,
+				upstreamClientCA, upstreamClientName, upstreamForwardIdentity,
+				upstreamACME, upstreamTLSMinVersion, upstreamTLSCipherSuites,
+				upstreamTLSCurves, upstreamHSTSMaxAge, upstreamHSTSIncludeSubdomains,
+				upstreamRevision, upstreamLabels},
 			Action: func(ctx *cli.Context) error {
 				clientConf, err := grpcclient.NewClientConfig(ctx.String("conf"))
 				if err != nil {
@@ -237,17 +512,135 @@ func main() {
 				if err != nil {
 					return err
 				}
-				return c.Put(ctx.String("domain"), ctx.StringSlice("ips"))
+				b, err := backendFromFlags(ctx)
+				if err != nil {
+					return err
+				}
+				return c.Put(b)
+			},
+		},
+		cli.Command{
+			Name:  "patch",
+			Usage: "change only the given settings of an upstream; add or remove IPs",
+			Flags: []cli.Flag{conf, upstreamDomain, upstreamIPs,
+				upstreamMaxConns, upstreamClientRate, upstreamClientBurst,
+				upstreamAllow, upstreamDeny,
+				upstreamClientCA, upstreamClientName, upstreamForwardIdentity,
+				upstreamACME, upstreamTLSMinVersion, upstreamTLSCipherSuites,
+				upstreamTLSCurves, upstreamHSTSMaxAge, upstreamHSTSIncludeSubdomains,
+				upstreamRevision, upstreamLabels,
+				cli.StringSliceFlag{Name: "addIP", Usage: "add this IP:port; repeatable"},
+				cli.StringSliceFlag{Name: "removeIP", Usage: "remove this IP:port; repeatable"},
+				cli.StringSliceFlag{Name: "clear", Usage: "clear a setting, e.g. client_auth or tls_policy; repeatable"}},
+			Action: func(ctx *cli.Context) error {
+				c, err := newClient(ctx)
+				if err != nil {
+					return err
+				}
+				b, err := backendFromFlags(ctx)
+				if err != nil {
+					return err
+				}
+				mask := ctx.StringSlice("clear")
+				for field, flags := range patchFlags {
+					for _, name := range flags {
+						if ctx.IsSet(name) {
+							mask = append(mask, field)
+							break
+						}
+					}
+				}
+				return c.Patch(&server.PatchRequest{
+					Domain:     b.Domain,
+					Values:     b,
+					UpdateMask: mask,
+					AddIps:     ctx.StringSlice("addIP"),
+					RemoveIps:  ctx.StringSlice("removeIP"),
+					Revision:   b.Revision,
+				})
+			},
+		},
+
+		cli.Command{
+			Name:  "apply",
+			Usage: "set every upstream from a TOML, YAML or JSON file, printing what changed",
+			Flags: []cli.Flag{conf,
+				cli.StringFlag{Name: "file, f", Usage: "routes file"},
+				cli.BoolFlag{Name: "prune", Usage: "remove upstreams not in the file"},
+				cli.BoolFlag{Name: "dry-run", Usage: "validate and print the changes, but commit nothing"}},
+			Action: func(ctx *cli.Context) error {
+				backends, err := grpcclient.LoadRoutes(ctx.String("file"))
+				if err != nil {
+					return err
+				}
+				c, err := newClient(ctx)
+				if err != nil {
+					return err
+				}
+				return c.Apply(backends, ctx.Bool("prune"), ctx.Bool("dry-run"))
+			},
+		},
+
+		cli.Command{
+			Name:  "export",
+			Usage: "print every upstream, KV pair and client public key as a JSON document",
+			Flags: []cli.Flag{conf, passphraseFile,
+				cli.StringFlag{Name: "out, o", Usage: "write to this file instead of stdout"}},
+			Action: func(ctx *cli.Context) error {
+				passphrase, err := readPassphrase(ctx.String("passphraseFile"))
+				if err != nil {
+					return err
+				}
+				c, err := newClient(ctx)
+				if err != nil {
+					return err
+				}
+				data, err := c.Export(passphrase)
+				if err != nil {
+					return err
+				}
+				if out := ctx.String("out"); out != "" {
+					return ioutil.WriteFile(out, data, 0600)
+				}
+				_, err = os.Stdout.Write(data)
+				return err
+			},
+		},
+		cli.Command{
+			Name:  "import",
+			Usage: "add the upstreams, KV pairs and client keys of an exported document",
+			Flags: []cli.Flag{conf, passphraseFile,
+				cli.StringFlag{Name: "file, f", Usage: "exported document"},
+				cli.BoolFlag{Name: "overwrite", Usage: "replace what differs from the document, instead of failing"},
+				cli.BoolFlag{Name: "dry-run", Usage: "check the document and print the changes, but commit nothing"}},
+			Action: func(ctx *cli.Context) error {
+				data, err := ioutil.ReadFile(ctx.String("file"))
+				if err != nil {
+					return err
+				}
+				passphrase, err := readPassphrase(ctx.String("passphraseFile"))
+				if err != nil {
+					return err
+				}
+				c, err := newClient(ctx)
+				if err != nil {
+					return err
+				}
+				return c.Import(data, passphrase, ctx.Bool("overwrite"), ctx.Bool("dry-run"))
 			},
 		},
 
 		cli.Command{
 			Name:  "serve",
 			Usage: "run co-chair",
-			Flags: []cli.Flag{dbFlag, apiCert, apiClientValidation, apiKey, apiPort,
+			Flags: []cli.Flag{dbFlag, masterKeyFile, apiCert, apiClientValidation, apiKey, apiPort,
 				webCert, webDomain, webKey, webPort, webAssetsPath,
 				proxyCert, proxyKey, proxyPort, proxyInsecurePort,
-				auth0ClientID, auth0Domain, auth0Secret, bypassAuth0,
+				proxyMaxConns, proxyClientRate, proxyClientBurst, proxyIdleSeconds,
+				proxyDeny, proxyProtocolFrom,
+				acmeDirectoryURL, acmeCACert, acmeEmail, acmeRenewDays,
+				certWarnDays, certWebhook, ticketRotationHours, auditLogFile, historyRevisions,
+				auth0ClientID, auth0Domain, auth0Secret, bypassAuth0, webUIDefaultRole,
 				conf},
 			Action: func(ctx *cli.Context) error {
 				conf, err := config.FromCLIOpts(ctx)
@@ -257,10 +650,337 @@ func main() {
 				return serve(conf)
 			},
 		},
+		cli.Command{
+			Name:  "ca",
+			Usage: "manage the internal CA",
+			Subcommands: []cli.Command{
+				{
+					Name:  "bundle",
+					Usage: "print the CA bundle; pass --crl for the revocation list",
+					Flags: []cli.Flag{conf, cli.BoolFlag{Name: "crl"}},
+					Action: func(ctx *cli.Context) error {
+						c, err := newClient(ctx)
+						if err != nil {
+							return err
+						}
+						return c.CA(ctx.Bool("crl"))
+					},
+				},
+				{
+					Name:  "issue-client",
+					Usage: "print a new client certificate and key; pass the common name",
+					Flags: []cli.Flag{conf},
+					Action: func(ctx *cli.Context) error {
+						c, err := newClient(ctx)
+						if err != nil {
+							return err
+						}
+						return c.IssueClientCert(ctx.Args().First())
+					},
+				},
+				{
+					Name:  "revoke",
+					Usage: "revoke a certificate; pass its hex serial",
+					Flags: []cli.Flag{conf},
+					Action: func(ctx *cli.Context) error {
+						c, err := newClient(ctx)
+						if err != nil {
+							return err
+						}
+						return c.Revoke(ctx.Args().First())
+					},
+				},
+				{
+					Name:  "import",
+					Usage: "replace the CA with a PEM cert (and chain) and key; co-chair cannot be running",
+					Flags: []cli.Flag{dbFlag, masterKeyFile,
+						cli.StringFlag{Name: "cert", Usage: "path to the CA cert, followed by its chain"},
+						cli.StringFlag{Name: "key", Usage: "path to the CA private key"}},
+					Action: func(ctx *cli.Context) error {
+						return importCA(ctx.String("db"), ctx.String("masterKeyFile"),
+							ctx.String("cert"), ctx.String("key"))
+					},
+				},
+			},
+		},
+		cli.Command{
+			Name:  "client-keys",
+			Usage: "manage the curvetls keys of gRPC API clients",
+			Subcommands: []cli.Command{
+				{
+					Name:  "create",
+					Usage: "print a new client keypair; pass the client's name",
+					Flags: []cli.Flag{conf, clientRole, clientScope},
+					Action: func(ctx *cli.Context) error {
+						c, err := newClient(ctx)
+						if err != nil {
+							return err
+						}
+						return c.CreateClientKey(ctx.Args().First(), ctx.String("role"), ctx.StringSlice("scope"))
+					},
+				},
+				{
+					Name:  "set-role",
+					Usage: "change what a client may do; pass its name",
+					Flags: []cli.Flag{conf, clientRole, clientScope},
+					Action: func(ctx *cli.Context) error {
+						c, err := newClient(ctx)
+						if err != nil {
+							return err
+						}
+						return c.SetClientKeyRole(ctx.Args().First(), ctx.String("role"), ctx.StringSlice("scope"))
+					},
+				},
+				{
+					Name:  "list",
+					Usage: "list client keys",
+					Flags: []cli.Flag{conf},
+					Action: func(ctx *cli.Context) error {
+						c, err := newClient(ctx)
+						if err != nil {
+							return err
+						}
+						return c.ListClientKeys()
+					},
+				},
+				{
+					Name:  "revoke",
+					Usage: "stop a client from connecting; pass its name",
+					Flags: []cli.Flag{conf},
+					Action: func(ctx *cli.Context) error {
+						c, err := newClient(ctx)
+						if err != nil {
+							return err
+						}
+						return c.RevokeClientKey(ctx.Args().First())
+					},
+				},
+				{
+					Name:  "rotate",
+					Usage: "print a new keypair for a client, replacing its old one; pass its name",
+					Flags: []cli.Flag{conf},
+					Action: func(ctx *cli.Context) error {
+						c, err := newClient(ctx)
+						if err != nil {
+							return err
+						}
+						return c.RotateClientKey(ctx.Args().First())
+					},
+				},
+			},
+		},
+		cli.Command{
+			Name:  "server-key",
+			Usage: "rotate the curvetls key of the gRPC API",
+			Subcommands: []cli.Command{
+				{
+					Name:  "show",
+					Usage: "print the server's public keys",
+					Flags: []cli.Flag{conf},
+					Action: func(ctx *cli.Context) error {
+						c, err := newClient(ctx)
+						if err != nil {
+							return err
+						}
+						return c.ServerKeys()
+					},
+				},
+				{
+					Name:  "rotate",
+					Usage: "make a new server key; both keys are served until retire",
+					Flags: []cli.Flag{conf},
+					Action: func(ctx *cli.Context) error {
+						c, err := newClient(ctx)
+						if err != nil {
+							return err
+						}
+						return c.RotateServerKey()
+					},
+				},
+				{
+					Name:  "retire",
+					Usage: "stop serving the old server key",
+					Flags: []cli.Flag{conf},
+					Action: func(ctx *cli.Context) error {
+						c, err := newClient(ctx)
+						if err != nil {
+							return err
+						}
+						return c.RetireServerKey()
+					},
+				},
+				{
+					Name:  "update-client",
+					Usage: "set server_public_key in the client config to the server's newest key",
+					Flags: []cli.Flag{conf},
+					Action: func(ctx *cli.Context) error {
+						c, err := newClient(ctx)
+						if err != nil {
+							return err
+						}
+						pub, err := c.UpdateServerKey(ctx.String("conf"))
+						if err != nil {
+							return err
+						}
+						fmt.Println("server public key:\t", pub)
+						return nil
+					},
+				},
+			},
+		},
+		cli.Command{
+			Name:  "audit",
+			Usage: "report configuration changes, newest first",
+			Flags: []cli.Flag{conf,
+				cli.StringFlag{Name: "target", Usage: "only changes to this domain or key name"},
+				cli.StringFlag{Name: "actor", Usage: "only changes by this client key or web UI user"},
+				cli.DurationFlag{Name: "since", Usage: "only changes in this long, e.g. 24h"},
+				cli.IntFlag{Name: "limit", Usage: "at most this many changes", Value: 100}},
+			Action: func(ctx *cli.Context) error {
+				c, err := newClient(ctx)
+				if err != nil {
+					return err
+				}
+				req := &server.AuditRequest{
+					Target: ctx.String("target"),
+					Actor:  ctx.String("actor"),
+					Limit:  int32(ctx.Int("limit")),
+				}
+				if since := ctx.Duration("since"); since > 0 {
+					req.Since = time.Now().Add(-since).Unix()
+				}
+				return c.AuditEvents(req)
+			},
+		},
+		cli.Command{
+			Name:  "history",
+			Usage: "list revisions of the routing configuration; with --from, compare two",
+			Flags: []cli.Flag{conf,
+				cli.IntFlag{Name: "limit", Usage: "at most this many revisions", Value: 20},
+				cli.Int64Flag{Name: "from", Usage: "revision to compare from"},
+				cli.Int64Flag{Name: "to", Usage: "revision to compare to; 0 means now"}},
+			Action: func(ctx *cli.Context) error {
+				c, err := newClient(ctx)
+				if err != nil {
+					return err
+				}
+				if ctx.IsSet("from") {
+					return c.DiffRevisions(ctx.Int64("from"), ctx.Int64("to"))
+				}
+				return c.Revisions(ctx.Int("limit"))
+			},
+		},
+		cli.Command{
+			Name:  "rollback",
+			Usage: "restore the routing configuration of a revision; pass its number",
+			Flags: []cli.Flag{conf},
+			Action: func(ctx *cli.Context) error {
+				rev, err := strconv.ParseInt(ctx.Args().First(), 10, 64)
+				if err != nil {
+					return fmt.Errorf("revision: %v", err)
+				}
+				c, err := newClient(ctx)
+				if err != nil {
+					return err
+				}
+				return c.Rollback(rev)
+			},
+		},
+		cli.Command{
+			Name:  "db",
+			Usage: "manage the boltdb file",
+			Subcommands: []cli.Command{
+				{
+					Name:  "rekey",
+					Usage: "encrypt the database with a new master key; co-chair cannot be running",
+					Flags: []cli.Flag{dbFlag, masterKeyFile,
+						cli.StringFlag{Name: "newMasterKeyFile", Usage: "path to the new master key"},
+						cli.BoolFlag{Name: "rotateDataKey", Usage: "also re-encrypt every secret with a new data key"}},
+					Action: func(ctx *cli.Context) error {
+						oldKey, err := backend.LoadMasterKey(ctx.String("masterKeyFile"))
+						if err != nil {
+							return err
+						}
+						if !ctx.IsSet("newMasterKeyFile") {
+							return fmt.Errorf("--newMasterKeyFile is required")
+						}
+						newKey, err := backend.LoadMasterKey(ctx.String("newMasterKeyFile"))
+						if err != nil {
+							return err
+						}
+						return backend.Rekey(ctx.String("db"), oldKey, newKey, ctx.Bool("rotateDataKey"))
+					},
+				},
+			},
+		},
+		cli.Command{
+			Name:  "certs",
+			Usage: "report the certificate of every domain, or of --domain",
+			Flags: []cli.Flag{conf, upstreamDomain},
+			Action: func(ctx *cli.Context) error {
+				c, err := newClient(ctx)
+				if err != nil {
+					return err
+				}
+				return c.Certificates(ctx.String("domain"))
+			},
+		},
+		cli.Command{
+			Name:  "stage-certs",
+			Usage: "stage certificates for --domain, to serve once promoted; pair each --cert with a --key",
+			Flags: []cli.Flag{conf, upstreamDomain,
+				cli.StringSliceFlag{Name: "cert", Usage: "path to a PEM cert and chain; the first given is served first"},
+				cli.StringSliceFlag{Name: "key", Usage: "path to the PEM private key of the matching --cert"}},
+			Action: func(ctx *cli.Context) error {
+				certs, keys := ctx.StringSlice("cert"), ctx.StringSlice("key")
+				if len(certs) != len(keys) {
+					return errors.New("pass one --key for each --cert")
+				}
+				req := &server.StageRequest{Domain: ctx.String("domain")}
+				for i := range certs {
+					cert, err := ioutil.ReadFile(certs[i])
+					if err != nil {
+						return err
+					}
+					key, err := ioutil.ReadFile(keys[i])
+					if err != nil {
+						return err
+					}
+					req.Certs = append(req.Certs, &server.X509Cert{Cert: cert, Key: key})
+				}
+				c, err := newClient(ctx)
+				if err != nil {
+					return err
+				}
+				return c.StageCertificates(req)
+			},
+		},
+		cli.Command{
+			Name:  "promote-certs",
+			Usage: "serve the certificates staged for --domain",
+			Flags: []cli.Flag{conf, upstreamDomain},
+			Action: func(ctx *cli.Context) error {
+				c, err := newClient(ctx)
+				if err != nil {
+					return err
+				}
+				return c.PromoteCertificates(ctx.String("domain"))
+			},
+		},
 		cli.Command{
 			Name:  "state",
 			Usage: "report the proxy's upstream configuration",
-			Flags: []cli.Flag{conf, upstreamDomain},
+			Flags: []cli.Flag{conf, upstreamDomain,
+				cli.StringSliceFlag{Name: "protocol", Usage: "only upstreams with this protocol; repeatable"},
+				cli.StringFlag{Name: "health", Usage: "only upstreams with this health status, or unknown"},
+				cli.StringFlag{Name: "match", Usage: "only domains matching this pattern, e.g. *.example.com"},
+				cli.StringFlag{Name: "ip", Usage: "only upstreams with an IP in this IP or CIDR"},
+				cli.StringSliceFlag{Name: "label", Usage: "only upstreams with this label, as name=value or name; repeatable"},
+				cli.DurationFlag{Name: "expiresWithin", Usage: "only upstreams whose certificate expires within this long, e.g. 720h"},
+				cli.StringFlag{Name: "sort", Value: "domain", Usage: "domain, revision or cert_expiry"},
+				cli.BoolFlag{Name: "desc", Usage: "sort in descending order"},
+				cli.IntFlag{Name: "pageSize", Usage: "show this many upstreams, and a token for the next page; 0 shows all"},
+				cli.StringFlag{Name: "pageToken", Usage: "show the page after the one that printed this token"}},
 			Action: func(ctx *cli.Context) error {
 				clientConf, err := grpcclient.NewClientConfig(ctx.String("conf"))
 				if err != nil {
@@ -270,7 +990,32 @@ func main() {
 				if err != nil {
 					return err
 				}
-				return c.State(ctx.String("domain"))
+				req := &server.StateRequest{
+					Domain:        ctx.String("domain"),
+					PageSize:      int32(ctx.Int("pageSize")),
+					PageToken:     ctx.String("pageToken"),
+					HealthStatus:  ctx.String("health"),
+					DomainPattern: ctx.String("match"),
+					Ip:            ctx.String("ip"),
+					Labels:        parseLabels(ctx.StringSlice("label")),
+					Descending:    ctx.Bool("desc"),
+				}
+				for _, p := range ctx.StringSlice("protocol") {
+					proto, ok := server.Backend_Protocol_value[strings.ToUpper(p)]
+					if !ok {
+						return fmt.Errorf("unknown protocol: %s", p)
+					}
+					req.Protocols = append(req.Protocols, server.Backend_Protocol(proto))
+				}
+				sort, ok := server.StateRequest_Sort_value[strings.ToUpper(ctx.String("sort"))]
+				if !ok {
+					return fmt.Errorf("unknown sort: %s", ctx.String("sort"))
+				}
+				req.Sort = server.StateRequest_Sort(sort)
+				if d := ctx.Duration("expiresWithin"); d > 0 {
+					req.CertExpiresBefore = time.Now().Add(d).Unix()
+				}
+				return c.State(req)
 			},
 		},
 		cli.Command{
@@ -299,10 +1044,23 @@ func serve(conf config.Config) error {
 
 	// NewProxy gives us a Proxy, our concrete implementation of the
 	// interface generated by the grpc protobuf compiler.
-	px, err := backend.NewProxy(conf.DBPath)
+	masterKey, err := backend.LoadMasterKey(conf.MasterKeyFile)
+	if err != nil {
+		log.Fatalf("proxy init: %v", err)
+	}
+	px, err := backend.NewProxy(conf.DBPath, masterKey)
 	if err != nil {
 		log.Fatalf("proxy init: %v", err)
 	}
+	if conf.AuditLogFile != "" {
+		f, err := os.OpenFile(conf.AuditLogFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+		if err != nil {
+			return fmt.Errorf("audit log: %v", err)
+		}
+		defer f.Close()
+		px.AuditLog = backend.NewAuditLog(f, logger)
+	}
+	px.RevisionsKept = conf.HistoryRevisions
 
 	// KeyStore is an interface, so it is nil if unset.
 	var keystore curvetls.KeyStore
@@ -311,17 +1069,30 @@ func serve(conf config.Config) error {
 	}
 
 	// curvetls transport security
-	serverPub, serverPriv, err := backend.RetrieveServerKeys(px.DB)
+	creds, err := backend.NewServerCredentials(px.DB, keystore)
 	if err != nil {
 		return fmt.Errorf("could not retrieve server's keypair %v", err)
 	}
-	creds := curvetls.NewGRPCServerCredentials(serverPub, serverPriv, keystore)
 
-	grpcOnlyServer := grpc.NewServer(grpc.Creds(creds))
+	// Without client validation, any key may connect, as an admin.
+	var apiDefaultRole backend.Role
+	if !conf.APIClientValidation {
+		apiDefaultRole = backend.RoleAdmin
+	}
+	apiAuth := &backend.Authorizer{Identify: backend.KeyIdentity(px.DB, apiDefaultRole)}
+	grpcOnlyServer := grpc.NewServer(grpc.Creds(creds),
+		grpc.UnaryInterceptor(apiAuth.UnaryInterceptor),
+		grpc.StreamInterceptor(apiAuth.StreamInterceptor))
 	server.RegisterProxyServer(grpcOnlyServer, px)
 
 	// gRPC over websockets management API
-	gs := grpc.NewServer()
+	webAuth, err := webAuthorizer(conf)
+	if err != nil {
+		return err
+	}
+	gs := grpc.NewServer(
+		grpc.UnaryInterceptor(webAuth.UnaryInterceptor),
+		grpc.StreamInterceptor(webAuth.StreamInterceptor))
 	server.RegisterProxyServer(gs, px)
 	wrappedServer := grpcweb.WrapServer(gs)
 
@@ -353,6 +1124,14 @@ func serve(conf config.Config) error {
 		negroni.Wrap(http.HandlerFunc(loginLink)),
 	)).Methods("GET")
 
+	// Connection limit counters, published by package expvar.
+	p.Handle("/debug/vars", negroni.New(
+		setConf(conf),
+		negroni.HandlerFunc(withLog),
+		negroni.HandlerFunc(authHandler),
+		negroni.Wrap(expvar.Handler()),
+	)).Methods("GET")
+
 	p.Handle("/auth/{provider}/callback", negroni.New(
 		setConf(conf),
 		negroni.HandlerFunc(withLog),
@@ -382,6 +1161,7 @@ func serve(conf config.Config) error {
 		setConf(conf),
 		negroni.HandlerFunc(withLog),
 		negroni.HandlerFunc(authHandler),
+		negroni.HandlerFunc(withWebUser),
 		negroni.Wrap(allowCORS(websocketsProxy(wsproxy))),
 	)).Methods("POST", "OPTIONS")
 
@@ -461,11 +1241,68 @@ func serve(conf config.Config) error {
 		grpcAPI <- httpsSrv.ListenAndServeTLS(conf.WebUICert, conf.WebUIKey)
 	}()
 
-	fwdr, err := backend.NewTCPForwarder(
+	deny, err := backend.ParseCIDRs(conf.ProxyDeny)
+	if err != nil {
+		return fmt.Errorf("proxy_deny: %v", err)
+	}
+	proxyProtocol, err := backend.ParseCIDRs(conf.ProxyProtocolFrom)
+	if err != nil {
+		return fmt.Errorf("proxy_protocol_from: %v", err)
+	}
+	acme, err := backend.NewACME(px.DB, px.Router(), backend.ACMEConfig{
+		DirectoryURL: conf.ACMEDirectoryURL,
+		CACert:       conf.ACMECACert,
+		Email:        conf.ACMEEmail,
+		RenewBefore:  time.Duration(conf.ACMERenewDays) * 24 * time.Hour,
+	}, logger)
+	if err != nil {
+		return err
+	}
+	acme.Start(time.Hour)
+	defer acme.Stop()
+
+	clientCert, err := backend.NewProxyClientCert(px.CA)
+	if err != nil {
+		return err
+	}
+	warn := time.Duration(conf.CertWarnDays) * 24 * time.Hour
+	expiry := backend.NewExpiryChecker(px, warn, conf.CertWebhook, logger)
+	expiry.ClientCert = clientCert
+	expiry.Start()
+	defer expiry.Stop()
+
+	opts := []backend.Opt{
 		backend.WithDB(px.DB),
+		backend.WithRouter(px.Router()),
 		backend.WithAddr(fmt.Sprintf("0.0.0.0:%s", conf.ProxyPort)),
 		backend.WithLogger(logger),
-	)
+		backend.WithLimits(backend.Limits{
+			MaxConns:    conf.ProxyMaxConns,
+			ClientRate:  conf.ProxyClientRate,
+			ClientBurst: conf.ProxyClientBurst,
+		}),
+		backend.WithIdleTimeout(time.Duration(conf.ProxyIdleSeconds) * time.Second),
+		backend.WithDeny(deny),
+		backend.WithProxyProtocol(proxyProtocol),
+		backend.WithACME(acme),
+		backend.WithClientCertificate(clientCert.Certificate),
+	}
+	px.TicketRotation = time.Duration(conf.TicketRotationHours) * time.Hour
+	opts = append(opts, backend.WithSessionTickets(backend.LocalTicketKeys(px), time.Minute))
+	stapler := backend.NewOCSPStapler(px.DB, px.Router(), logger)
+	stapler.Start(10 * time.Minute)
+	defer stapler.Stop()
+	opts = append(opts, backend.WithOCSPStapler(stapler))
+	if conf.ProxyCert != "" && conf.ProxyKey != "" {
+		defaultCert, err := backend.NewCertReloader(conf.ProxyCert, conf.ProxyKey, logger)
+		if err != nil {
+			return err
+		}
+		defaultCert.Start(time.Minute)
+		defer defaultCert.Stop()
+		opts = append(opts, backend.WithDefaultCertificate(defaultCert))
+	}
+	fwdr, err := backend.NewTCPForwarder(opts...)
 	if err != nil {
 		return err
 	}
@@ -474,8 +1311,13 @@ func serve(conf config.Config) error {
 		return fmt.Errorf("start TCPFowarder: %v", err)
 	}
 
+	// Our plaintext port answers HTTP-01 challenges, and redirects
+	// everything else to https.
 	if conf.ProxyInsecurePort != "" {
-		// should we even support this?
+		go func() {
+			addr := fmt.Sprintf("0.0.0.0:%s", conf.ProxyInsecurePort)
+			proxy <- http.ListenAndServe(addr, acme.HTTPHandler(nil))
+		}()
 	}
 
 	for {
@@ -490,35 +1332,201 @@ func serve(conf config.Config) error {
 	}
 }
 
-func genClientKeypair(name, dbPath string) error {
-	// NOTE this function directly accesses the database. It's a command line
-	// feature, and assumes local access to the database file.
-
-	db, err := storm.Open(dbPath)
+// forward runs a TCPForwarder with routes synced from a remote co-chair. The
+// forwarder keeps serving its cached routes if the control plane goes away.
+func forward(pc server.ProxyClient, interval time.Duration, opts ...backend.Opt) error {
+	routes := backend.NewRemoteRouter(pc, interval, logger)
+	if err := routes.Sync(); err != nil {
+		// Come up anyway. Routes appear when the control plane does.
+		logger.Errorf("initial sync: %v", err)
+	}
+	routes.Start()
+	defer routes.Stop()
+
+	// Forwarders have no DB, so they keep OCSP responses in memory.
+	stapler := backend.NewOCSPStapler(nil, routes.TableRouter, logger)
+	stapler.Start(10 * time.Minute)
+	defer stapler.Stop()
+
+	opts = append(opts, backend.WithRouter(routes), backend.WithLogger(logger),
+		backend.WithOCSPStapler(stapler),
+		backend.WithSessionTickets(backend.RemoteTicketKeys(pc), interval))
+	fwdr, err := backend.NewTCPForwarder(opts...)
 	if err != nil {
 		return err
 	}
+	if err := fwdr.Start(); err != nil {
+		return fmt.Errorf("start TCPFowarder: %v", err)
+	}
+	defer fwdr.Stop()
+
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, os.Interrupt, syscall.SIGTERM)
+	sig := <-sigs
+	logger.Infof("got %v, shutting down", sig)
+	return nil
+}
 
-	serverPub, _, err := backend.RetrieveServerKeys(db)
+// newClient returns a gRPC client configured by the --conf flag.
+func newClient(ctx *cli.Context) (*grpcclient.CoChairClient, error) {
+	clientConf, err := grpcclient.NewClientConfig(ctx.String("conf"))
 	if err != nil {
-		return err
+		return nil, err
 	}
+	return grpcclient.NewCoChairClient(clientConf)
+}
 
-	priv, pub, err := curvetls.GenKeyPair()
+func importCA(dbPath, masterKeyFile, certPath, keyPath string) error {
+	// Like genClientKeypair, this assumes local access to the database file.
+	certPEM, err := ioutil.ReadFile(certPath)
 	if err != nil {
 		return err
 	}
-	clientKP := backend.KeyPair{Name: name, Pub: pub.String()}
-	if err := db.Save(&clientKP); err != nil {
+	keyPEM, err := ioutil.ReadFile(keyPath)
+	if err != nil {
 		return err
 	}
+	db, err := openDB(dbPath, masterKeyFile)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	return backend.ImportCA(db, certPEM, keyPEM)
+}
 
-	msg := `The following are new curveTLS public and private keys.\n`
-	fmt.Println(msg)
+func genClientKeypair(name, role, dbPath, masterKeyFile string) error {
+	// NOTE this function directly accesses the database. It's a command line
+	// feature, and assumes local access to the database file. With co-chair
+	// running, use client-keys create.
 
-	fmt.Println("client public key (new):\t", pub)
-	fmt.Println("client private key (new):\t", priv)
-	fmt.Println("server public key:\t", serverPub)
+	db, err := openDB(dbPath, masterKeyFile)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
 
+	ck, err := backend.CreateClientKey(db, name, role, nil)
+	if err != nil {
+		return err
+	}
+	grpcclient.PrintClientKey(ck)
 	return nil
 }
+
+// webAuthorizer authorizes web UI users by the roles in conf.
+func webAuthorizer(conf config.Config) (*backend.Authorizer, error) {
+	users := make(map[string]backend.Principal)
+	for _, u := range conf.WebUIUsers {
+		role, err := backend.ParseRole(u.Role)
+		if err != nil {
+			return nil, fmt.Errorf("web ui user %s: %v", u.Email, err)
+		}
+		users[strings.ToLower(u.Email)] = backend.Principal{Name: u.Email, Role: role, Domains: u.Domains}
+	}
+	defaultRole := backend.RoleAdmin
+	if conf.WebUIDefaultRole != "" {
+		var err error
+		if defaultRole, err = backend.ParseRole(conf.WebUIDefaultRole); err != nil {
+			return nil, fmt.Errorf("web ui default role: %v", err)
+		}
+	}
+	return &backend.Authorizer{Identify: backend.WebIdentity(users, defaultRole)}, nil
+}
+
+// openDB opens the database for commands that access it directly.
+func openDB(dbPath, masterKeyFile string) (*storm.DB, error) {
+	masterKey, err := backend.LoadMasterKey(masterKeyFile)
+	if err != nil {
+		return nil, err
+	}
+	return backend.OpenDB(dbPath, masterKey)
+}
+
+// patchFlags are the flags of put that set each field PatchBackend may
+// change.
+var patchFlags = map[string][]string{
+	"ips":          {"ips"},
+	"max_conns":    {"maxConns"},
+	"client_rate":  {"clientRate"},
+	"client_burst": {"clientBurst"},
+	"allow_cidrs":  {"allow"},
+	"deny_cidrs":   {"deny"},
+	"acme":         {"acme"},
+	"labels":       {"label"},
+	"client_auth":  {"clientCA", "clientName", "forwardIdentity"},
+	"tls_policy":   {"tlsMinVersion", "tlsCipherSuite", "tlsCurve", "hstsMaxAge", "hstsIncludeSubdomains"},
+}
+
+// backendFromFlags makes a Backend from the flags of put.
+func backendFromFlags(ctx *cli.Context) (*server.Backend, error) {
+	b := &server.Backend{
+		Domain:      ctx.String("domain"),
+		Ips:         ctx.StringSlice("ips"),
+		MaxConns:    int32(ctx.Int("maxConns")),
+		ClientRate:  ctx.Float64("clientRate"),
+		ClientBurst: int32(ctx.Int("clientBurst")),
+		AllowCidrs:  ctx.StringSlice("allow"),
+		DenyCidrs:   ctx.StringSlice("deny"),
+		Acme:        ctx.Bool("acme"),
+		Revision:    ctx.Int64("revision"),
+		Labels:      parseLabels(ctx.StringSlice("label")),
+	}
+	if path := ctx.String("clientCA"); path != "" {
+		bundle, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		fwd, ok := server.ClientAuth_Forward_value[strings.ToUpper(ctx.String("forwardIdentity"))]
+		if !ok {
+			return nil, fmt.Errorf("unknown forwardIdentity: %s", ctx.String("forwardIdentity"))
+		}
+		b.ClientAuth = &server.ClientAuth{
+			CaBundle:     bundle,
+			AllowedNames: ctx.StringSlice("clientName"),
+			Forward:      server.ClientAuth_Forward(fwd),
+		}
+	}
+	// Like client auth, the TLS policy is only changed if passed.
+	for _, name := range []string{"tlsMinVersion", "tlsCipherSuite", "tlsCurve",
+		"hstsMaxAge", "hstsIncludeSubdomains"} {
+		if ctx.IsSet(name) {
+			b.TlsPolicy = &server.TLSPolicy{
+				MinVersion:            ctx.String("tlsMinVersion"),
+				CipherSuites:          ctx.StringSlice("tlsCipherSuite"),
+				Curves:                ctx.StringSlice("tlsCurve"),
+				HstsMaxAge:            ctx.Int64("hstsMaxAge"),
+				HstsIncludeSubdomains: ctx.Bool("hstsIncludeSubdomains"),
+			}
+			break
+		}
+	}
+	return b, nil
+}
+
+// parseLabels parses name=value labels. A name alone has an empty value.
+func parseLabels(ss []string) map[string]string {
+	if len(ss) == 0 {
+		return nil
+	}
+	labels := make(map[string]string)
+	for _, s := range ss {
+		kv := strings.SplitN(s, "=", 2)
+		if len(kv) == 1 {
+			kv = append(kv, "")
+		}
+		labels[strings.TrimSpace(kv[0])] = strings.TrimSpace(kv[1])
+	}
+	return labels
+}
+
+// readPassphrase reads an export passphrase from path, if it is set.
+func readPassphrase(path string) (string, error) {
+	if path == "" {
+		return "", nil
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("passphrase: %v", err)
+	}
+	return strings.TrimSpace(string(data)), nil
+}
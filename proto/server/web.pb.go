@@ -9,12 +9,52 @@ It is generated from these files:
 
 It has these top-level messages:
 	Backend
+	TLSPolicy
+	ClientAuth
 	X509Cert
 	Key
 	KV
 	ProxyState
 	OpResult
 	StateRequest
+	RoutesRequest
+	RouteTable
+	CARequest
+	CAInfo
+	IssueRequest
+	RevokeRequest
+	CertificatesRequest
+	CertificateRequest
+	CertificateInfo
+	CertificateList
+	StageRequest
+	PromoteRequest
+	TicketKeysRequest
+	TicketKeys
+	ClientKeyRequest
+	ClientKeysRequest
+	ClientKey
+	ClientKeyList
+	ServerKeysRequest
+	ServerKeys
+	AuditRequest
+	AuditEvent
+	FieldChange
+	AuditEventList
+	RevisionsRequest
+	Revision
+	RevisionList
+	DiffRequest
+	DomainDiff
+	RevisionDiff
+	RollbackRequest
+	ApplyRequest
+	ApplyResult
+	ExportRequest
+	ConfigDocument
+	ImportRequest
+	ImportResult
+	PatchRequest
 */
 package server
 
@@ -62,6 +102,58 @@ func (x Backend_Protocol) String() string {
 }
 func (Backend_Protocol) EnumDescriptor() ([]byte, []int) { return fileDescriptor0, []int{0, 0} }
 
+// How the verified identity is passed to the backend.
+type ClientAuth_Forward int32
+
+const (
+	ClientAuth_NONE ClientAuth_Forward = 0
+	// An X-Forwarded-Client-Cert header on every request; HTTP1 only.
+	ClientAuth_HEADER ClientAuth_Forward = 1
+	// A PROXY protocol v2 header with SSL TLVs, before the backend's
+	// TLS handshake.
+	ClientAuth_PROXY_V2 ClientAuth_Forward = 2
+)
+
+var ClientAuth_Forward_name = map[int32]string{
+	0: "NONE",
+	1: "HEADER",
+	2: "PROXY_V2",
+}
+var ClientAuth_Forward_value = map[string]int32{
+	"NONE":     0,
+	"HEADER":   1,
+	"PROXY_V2": 2,
+}
+
+func (x ClientAuth_Forward) String() string {
+	return proto.EnumName(ClientAuth_Forward_name, int32(x))
+}
+func (ClientAuth_Forward) EnumDescriptor() ([]byte, []int) { return fileDescriptor0, []int{2, 0} }
+
+type StateRequest_Sort int32
+
+const (
+	StateRequest_DOMAIN      StateRequest_Sort = 0
+	StateRequest_REVISION    StateRequest_Sort = 1
+	StateRequest_CERT_EXPIRY StateRequest_Sort = 2
+)
+
+var StateRequest_Sort_name = map[int32]string{
+	0: "DOMAIN",
+	1: "REVISION",
+	2: "CERT_EXPIRY",
+}
+var StateRequest_Sort_value = map[string]int32{
+	"DOMAIN":      0,
+	"REVISION":    1,
+	"CERT_EXPIRY": 2,
+}
+
+func (x StateRequest_Sort) String() string {
+	return proto.EnumName(StateRequest_Sort_name, int32(x))
+}
+func (StateRequest_Sort) EnumDescriptor() ([]byte, []int) { return fileDescriptor0, []int{8, 0} }
+
 type Backend struct {
 	Domain       string            `protobuf:"bytes,1,opt,name=domain" json:"domain,omitempty"`
 	Ips          []string          `protobuf:"bytes,2,rep,name=ips" json:"ips,omitempty"`
@@ -71,6 +163,32 @@ type Backend struct {
 	InternetCert *X509Cert         `protobuf:"bytes,6,opt,name=internet_cert,json=internetCert" json:"internet_cert,omitempty"`
 	BackendCert  *X509Cert         `protobuf:"bytes,7,opt,name=backend_cert,json=backendCert" json:"backend_cert,omitempty"`
 	MatchHeaders map[string]string `protobuf:"bytes,8,rep,name=match_headers,json=matchHeaders" json:"match_headers,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	// Connection limits for this backend; zero means no limit.
+	MaxConns int32 `protobuf:"varint,9,opt,name=max_conns,json=maxConns" json:"max_conns,omitempty"`
+	// New connections per second allowed from one client IP, and how
+	// many a client may open in a burst.
+	ClientRate  float64 `protobuf:"fixed64,10,opt,name=client_rate,json=clientRate" json:"client_rate,omitempty"`
+	ClientBurst int32   `protobuf:"varint,11,opt,name=client_burst,json=clientBurst" json:"client_burst,omitempty"`
+	// Client address ranges in CIDR notation. If allow_cidrs is set, only
+	// clients in it may connect. Clients in deny_cidrs never may.
+	AllowCidrs []string `protobuf:"bytes,12,rep,name=allow_cidrs,json=allowCidrs" json:"allow_cidrs,omitempty"`
+	DenyCidrs  []string `protobuf:"bytes,13,rep,name=deny_cidrs,json=denyCidrs" json:"deny_cidrs,omitempty"`
+	// If set, clients must present a certificate to connect.
+	ClientAuth *ClientAuth `protobuf:"bytes,14,opt,name=client_auth,json=clientAuth" json:"client_auth,omitempty"`
+	// If set, co-chair obtains and renews this domain's certificate over
+	// ACME, instead of serving backend_cert.
+	Acme bool `protobuf:"varint,15,opt,name=acme" json:"acme,omitempty"`
+	// More certificates to serve alongside backend_cert, e.g. an RSA
+	// fallback for an ECDSA cert. Each handshake gets the first certificate
+	// the client supports. Replaced whenever backend_cert is.
+	ExtraCerts []*X509Cert `protobuf:"bytes,16,rep,name=extra_certs,json=extraCerts" json:"extra_certs,omitempty"`
+	// The TLS this domain is served with. Unset means Go's defaults.
+	TlsPolicy *TLSPolicy `protobuf:"bytes,17,opt,name=tls_policy,json=tlsPolicy" json:"tls_policy,omitempty"`
+	// Counts changes to this backend. If set on Put, the Put fails unless
+	// the backend is still at this revision.
+	Revision int64 `protobuf:"varint,18,opt,name=revision" json:"revision,omitempty"`
+	// Free-form tags, e.g. team=payments, for finding backends with State.
+	Labels map[string]string `protobuf:"bytes,19,rep,name=labels" json:"labels,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
 }
 
 func (m *Backend) Reset()                    { *m = Backend{} }
@@ -134,6 +252,177 @@ func (m *Backend) GetMatchHeaders() map[string]string {
 	return nil
 }
 
+func (m *Backend) GetMaxConns() int32 {
+	if m != nil {
+		return m.MaxConns
+	}
+	return 0
+}
+
+func (m *Backend) GetClientRate() float64 {
+	if m != nil {
+		return m.ClientRate
+	}
+	return 0
+}
+
+func (m *Backend) GetClientBurst() int32 {
+	if m != nil {
+		return m.ClientBurst
+	}
+	return 0
+}
+
+func (m *Backend) GetAllowCidrs() []string {
+	if m != nil {
+		return m.AllowCidrs
+	}
+	return nil
+}
+
+func (m *Backend) GetDenyCidrs() []string {
+	if m != nil {
+		return m.DenyCidrs
+	}
+	return nil
+}
+
+func (m *Backend) GetClientAuth() *ClientAuth {
+	if m != nil {
+		return m.ClientAuth
+	}
+	return nil
+}
+
+func (m *Backend) GetAcme() bool {
+	if m != nil {
+		return m.Acme
+	}
+	return false
+}
+
+func (m *Backend) GetExtraCerts() []*X509Cert {
+	if m != nil {
+		return m.ExtraCerts
+	}
+	return nil
+}
+
+func (m *Backend) GetTlsPolicy() *TLSPolicy {
+	if m != nil {
+		return m.TlsPolicy
+	}
+	return nil
+}
+
+func (m *Backend) GetRevision() int64 {
+	if m != nil {
+		return m.Revision
+	}
+	return 0
+}
+
+func (m *Backend) GetLabels() map[string]string {
+	if m != nil {
+		return m.Labels
+	}
+	return nil
+}
+
+// TLSPolicy is how we negotiate TLS with a domain's clients. ALPN comes from
+// the backend's protocol. Empty fields use Go's defaults.
+type TLSPolicy struct {
+	// The minimum version: "1.0", "1.1", "1.2", or "1.3".
+	MinVersion string `protobuf:"bytes,1,opt,name=min_version,json=minVersion" json:"min_version,omitempty"`
+	// TLS 1.2 cipher suites by Go name, e.g.
+	// "TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256". TLS 1.3 suites are fixed.
+	CipherSuites []string `protobuf:"bytes,2,rep,name=cipher_suites,json=cipherSuites" json:"cipher_suites,omitempty"`
+	// Key exchange curves in order of preference: "X25519", "P256", "P384",
+	// or "P521".
+	Curves []string `protobuf:"bytes,3,rep,name=curves" json:"curves,omitempty"`
+	// If set, HTTP1 responses get a Strict-Transport-Security header with
+	// this max-age, in seconds, unless the backend sent one.
+	HstsMaxAge            int64 `protobuf:"varint,4,opt,name=hsts_max_age,json=hstsMaxAge" json:"hsts_max_age,omitempty"`
+	HstsIncludeSubdomains bool  `protobuf:"varint,5,opt,name=hsts_include_subdomains,json=hstsIncludeSubdomains" json:"hsts_include_subdomains,omitempty"`
+}
+
+func (m *TLSPolicy) Reset()                    { *m = TLSPolicy{} }
+func (m *TLSPolicy) String() string            { return proto.CompactTextString(m) }
+func (*TLSPolicy) ProtoMessage()               {}
+func (*TLSPolicy) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{1} }
+
+func (m *TLSPolicy) GetMinVersion() string {
+	if m != nil {
+		return m.MinVersion
+	}
+	return ""
+}
+
+func (m *TLSPolicy) GetCipherSuites() []string {
+	if m != nil {
+		return m.CipherSuites
+	}
+	return nil
+}
+
+func (m *TLSPolicy) GetCurves() []string {
+	if m != nil {
+		return m.Curves
+	}
+	return nil
+}
+
+func (m *TLSPolicy) GetHstsMaxAge() int64 {
+	if m != nil {
+		return m.HstsMaxAge
+	}
+	return 0
+}
+
+func (m *TLSPolicy) GetHstsIncludeSubdomains() bool {
+	if m != nil {
+		return m.HstsIncludeSubdomains
+	}
+	return false
+}
+
+// ClientAuth configures client certificate (mTLS) authentication at the edge.
+type ClientAuth struct {
+	// PEM-encoded CA certificates that client certificates must chain to.
+	// Leave empty to turn client authentication off.
+	CaBundle []byte `protobuf:"bytes,1,opt,name=ca_bundle,json=caBundle,proto3" json:"ca_bundle,omitempty"`
+	// If set, the certificate's subject common name or one of its SANs must
+	// match one of these. A leading "*." matches a single label.
+	AllowedNames []string           `protobuf:"bytes,2,rep,name=allowed_names,json=allowedNames" json:"allowed_names,omitempty"`
+	Forward      ClientAuth_Forward `protobuf:"varint,3,opt,name=forward,enum=web.ClientAuth_Forward" json:"forward,omitempty"`
+}
+
+func (m *ClientAuth) Reset()                    { *m = ClientAuth{} }
+func (m *ClientAuth) String() string            { return proto.CompactTextString(m) }
+func (*ClientAuth) ProtoMessage()               {}
+func (*ClientAuth) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{2} }
+
+func (m *ClientAuth) GetCaBundle() []byte {
+	if m != nil {
+		return m.CaBundle
+	}
+	return nil
+}
+
+func (m *ClientAuth) GetAllowedNames() []string {
+	if m != nil {
+		return m.AllowedNames
+	}
+	return nil
+}
+
+func (m *ClientAuth) GetForward() ClientAuth_Forward {
+	if m != nil {
+		return m.Forward
+	}
+	return ClientAuth_NONE
+}
+
 type X509Cert struct {
 	Cert []byte `protobuf:"bytes,1,opt,name=cert,proto3" json:"cert,omitempty"`
 	Key  []byte `protobuf:"bytes,2,opt,name=key,proto3" json:"key,omitempty"`
@@ -142,7 +431,7 @@ type X509Cert struct {
 func (m *X509Cert) Reset()                    { *m = X509Cert{} }
 func (m *X509Cert) String() string            { return proto.CompactTextString(m) }
 func (*X509Cert) ProtoMessage()               {}
-func (*X509Cert) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{1} }
+func (*X509Cert) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{3} }
 
 func (m *X509Cert) GetCert() []byte {
 	if m != nil {
@@ -165,7 +454,7 @@ type Key struct {
 func (m *Key) Reset()                    { *m = Key{} }
 func (m *Key) String() string            { return proto.CompactTextString(m) }
 func (*Key) ProtoMessage()               {}
-func (*Key) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{2} }
+func (*Key) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{4} }
 
 func (m *Key) GetPrefix() []byte {
 	if m != nil {
@@ -182,7 +471,7 @@ type KV struct {
 func (m *KV) Reset()                    { *m = KV{} }
 func (m *KV) String() string            { return proto.CompactTextString(m) }
 func (*KV) ProtoMessage()               {}
-func (*KV) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{3} }
+func (*KV) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{5} }
 
 func (m *KV) GetKey() []byte {
 	if m != nil {
@@ -204,12 +493,16 @@ type ProxyState struct {
 	Status string `protobuf:"bytes,2,opt,name=status" json:"status,omitempty"`
 	// an error code
 	Code int32 `protobuf:"varint,3,opt,name=code" json:"code,omitempty"`
+	// Pass as page_token to get the next page; empty on the last page.
+	NextPageToken string `protobuf:"bytes,4,opt,name=next_page_token,json=nextPageToken" json:"next_page_token,omitempty"`
+	// How many backends matched, across all pages.
+	TotalSize int32 `protobuf:"varint,5,opt,name=total_size,json=totalSize" json:"total_size,omitempty"`
 }
 
 func (m *ProxyState) Reset()                    { *m = ProxyState{} }
 func (m *ProxyState) String() string            { return proto.CompactTextString(m) }
 func (*ProxyState) ProtoMessage()               {}
-func (*ProxyState) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{4} }
+func (*ProxyState) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{6} }
 
 func (m *ProxyState) GetBackends() []*Backend {
 	if m != nil {
@@ -232,6 +525,23 @@ func (m *ProxyState) GetCode() int32 {
 	return 0
 }
 
+func (m *ProxyState) GetNextPageToken() string {
+	if m != nil {
+		return m.NextPageToken
+	}
+	return ""
+}
+
+func (m *ProxyState) GetTotalSize() int32 {
+	if m != nil {
+		return m.TotalSize
+	}
+	return 0
+}
+
+// OpResult is returned by calls that succeed. Failures are gRPC status
+// errors, with a google.rpc.BadRequest detail naming invalid fields; code
+// is always 200, and kept for older clients.
 type OpResult struct {
 	Code   int32  `protobuf:"varint,1,opt,name=code" json:"code,omitempty"`
 	Status string `protobuf:"bytes,2,opt,name=status" json:"status,omitempty"`
@@ -240,7 +550,7 @@ type OpResult struct {
 func (m *OpResult) Reset()                    { *m = OpResult{} }
 func (m *OpResult) String() string            { return proto.CompactTextString(m) }
 func (*OpResult) ProtoMessage()               {}
-func (*OpResult) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{5} }
+func (*OpResult) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{7} }
 
 func (m *OpResult) GetCode() int32 {
 	if m != nil {
@@ -260,12 +570,31 @@ type StateRequest struct {
 	// if domain is empty string, return "all" states, otherwise
 	// match domain DNS-style, e.g. google.com matches docs.google.com
 	Domain string `protobuf:"bytes,1,opt,name=domain" json:"domain,omitempty"`
+	// At most this many backends are returned; 0 means all of them.
+	PageSize int32 `protobuf:"varint,2,opt,name=page_size,json=pageSize" json:"page_size,omitempty"`
+	// The next_page_token of the previous page, made with the same sort.
+	PageToken string `protobuf:"bytes,3,opt,name=page_token,json=pageToken" json:"page_token,omitempty"`
+	// Filters; a backend must match all that are set.
+	Protocols []Backend_Protocol `protobuf:"varint,4,rep,name=protocols,packed,enum=web.Backend_Protocol" json:"protocols,omitempty"`
+	// e.g. "healthy"; "unknown" matches backends with no status.
+	HealthStatus string `protobuf:"bytes,5,opt,name=health_status,json=healthStatus" json:"health_status,omitempty"`
+	// A shell pattern, e.g. "*.example.com" or "api-?.internal".
+	DomainPattern string `protobuf:"bytes,6,opt,name=domain_pattern,json=domainPattern" json:"domain_pattern,omitempty"`
+	// An IP or CIDR that one of a backend's upstream IPs must be in.
+	Ip string `protobuf:"bytes,7,opt,name=ip" json:"ip,omitempty"`
+	// Labels a backend must have, with these values; an empty value
+	// matches any.
+	Labels map[string]string `protobuf:"bytes,8,rep,name=labels" json:"labels,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	// Unix seconds; only backends whose certificate expires before then.
+	CertExpiresBefore int64             `protobuf:"varint,9,opt,name=cert_expires_before,json=certExpiresBefore" json:"cert_expires_before,omitempty"`
+	Sort              StateRequest_Sort `protobuf:"varint,10,opt,name=sort,enum=web.StateRequest_Sort" json:"sort,omitempty"`
+	Descending        bool              `protobuf:"varint,11,opt,name=descending" json:"descending,omitempty"`
 }
 
 func (m *StateRequest) Reset()                    { *m = StateRequest{} }
 func (m *StateRequest) String() string            { return proto.CompactTextString(m) }
 func (*StateRequest) ProtoMessage()               {}
-func (*StateRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{6} }
+func (*StateRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{8} }
 
 func (m *StateRequest) GetDomain() string {
 	if m != nil {
@@ -274,249 +603,2173 @@ func (m *StateRequest) GetDomain() string {
 	return ""
 }
 
-func init() {
-	proto.RegisterType((*Backend)(nil), "web.Backend")
-	proto.RegisterType((*X509Cert)(nil), "web.X509Cert")
-	proto.RegisterType((*Key)(nil), "web.Key")
-	proto.RegisterType((*KV)(nil), "web.KV")
-	proto.RegisterType((*ProxyState)(nil), "web.ProxyState")
-	proto.RegisterType((*OpResult)(nil), "web.OpResult")
-	proto.RegisterType((*StateRequest)(nil), "web.StateRequest")
-	proto.RegisterEnum("web.Backend_Protocol", Backend_Protocol_name, Backend_Protocol_value)
+func (m *StateRequest) GetPageSize() int32 {
+	if m != nil {
+		return m.PageSize
+	}
+	return 0
 }
 
-// Reference imports to suppress errors if they are not otherwise used.
-var _ context.Context
-var _ grpc.ClientConn
-
-// This is a compile-time assertion to ensure that this generated file
-// is compatible with the grpc package it is being compiled against.
-const _ = grpc.SupportPackageIsVersion4
-
-// Client API for Proxy service
+func (m *StateRequest) GetPageToken() string {
+	if m != nil {
+		return m.PageToken
+	}
+	return ""
+}
 
-type ProxyClient interface {
-	State(ctx context.Context, in *StateRequest, opts ...grpc.CallOption) (*ProxyState, error)
-	Put(ctx context.Context, in *Backend, opts ...grpc.CallOption) (*OpResult, error)
-	Remove(ctx context.Context, in *Backend, opts ...grpc.CallOption) (*OpResult, error)
-	PutKVStream(ctx context.Context, opts ...grpc.CallOption) (Proxy_PutKVStreamClient, error)
-	GetKVStream(ctx context.Context, in *Key, opts ...grpc.CallOption) (Proxy_GetKVStreamClient, error)
+func (m *StateRequest) GetProtocols() []Backend_Protocol {
+	if m != nil {
+		return m.Protocols
+	}
+	return nil
 }
 
-type proxyClient struct {
-	cc *grpc.ClientConn
+func (m *StateRequest) GetHealthStatus() string {
+	if m != nil {
+		return m.HealthStatus
+	}
+	return ""
 }
 
-func NewProxyClient(cc *grpc.ClientConn) ProxyClient {
-	return &proxyClient{cc}
+func (m *StateRequest) GetDomainPattern() string {
+	if m != nil {
+		return m.DomainPattern
+	}
+	return ""
 }
 
-func (c *proxyClient) State(ctx context.Context, in *StateRequest, opts ...grpc.CallOption) (*ProxyState, error) {
-	out := new(ProxyState)
-	err := grpc.Invoke(ctx, "/web.Proxy/State", in, out, c.cc, opts...)
-	if err != nil {
-		return nil, err
+func (m *StateRequest) GetIp() string {
+	if m != nil {
+		return m.Ip
 	}
-	return out, nil
+	return ""
 }
 
-func (c *proxyClient) Put(ctx context.Context, in *Backend, opts ...grpc.CallOption) (*OpResult, error) {
-	out := new(OpResult)
-	err := grpc.Invoke(ctx, "/web.Proxy/Put", in, out, c.cc, opts...)
-	if err != nil {
-		return nil, err
+func (m *StateRequest) GetLabels() map[string]string {
+	if m != nil {
+		return m.Labels
 	}
-	return out, nil
+	return nil
 }
 
-func (c *proxyClient) Remove(ctx context.Context, in *Backend, opts ...grpc.CallOption) (*OpResult, error) {
-	out := new(OpResult)
-	err := grpc.Invoke(ctx, "/web.Proxy/Remove", in, out, c.cc, opts...)
-	if err != nil {
-		return nil, err
+func (m *StateRequest) GetCertExpiresBefore() int64 {
+	if m != nil {
+		return m.CertExpiresBefore
 	}
-	return out, nil
+	return 0
 }
 
-func (c *proxyClient) PutKVStream(ctx context.Context, opts ...grpc.CallOption) (Proxy_PutKVStreamClient, error) {
-	stream, err := grpc.NewClientStream(ctx, &_Proxy_serviceDesc.Streams[0], c.cc, "/web.Proxy/PutKVStream", opts...)
-	if err != nil {
-		return nil, err
+func (m *StateRequest) GetSort() StateRequest_Sort {
+	if m != nil {
+		return m.Sort
 	}
-	x := &proxyPutKVStreamClient{stream}
-	return x, nil
+	return StateRequest_DOMAIN
 }
 
-type Proxy_PutKVStreamClient interface {
-	Send(*KV) error
-	CloseAndRecv() (*OpResult, error)
-	grpc.ClientStream
+func (m *StateRequest) GetDescending() bool {
+	if m != nil {
+		return m.Descending
+	}
+	return false
 }
 
-type proxyPutKVStreamClient struct {
-	grpc.ClientStream
+type RoutesRequest struct {
 }
 
-func (x *proxyPutKVStreamClient) Send(m *KV) error {
-	return x.ClientStream.SendMsg(m)
+func (m *RoutesRequest) Reset()                    { *m = RoutesRequest{} }
+func (m *RoutesRequest) String() string            { return proto.CompactTextString(m) }
+func (*RoutesRequest) ProtoMessage()               {}
+func (*RoutesRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{9} }
+
+// RouteTable is the full routing configuration of a co-chair instance,
+// including certificates and keys. Forwarders sync it from the control plane.
+type RouteTable struct {
+	Backends []*Backend `protobuf:"bytes,1,rep,name=backends" json:"backends,omitempty"`
 }
 
-func (x *proxyPutKVStreamClient) CloseAndRecv() (*OpResult, error) {
-	if err := x.ClientStream.CloseSend(); err != nil {
-		return nil, err
-	}
-	m := new(OpResult)
-	if err := x.ClientStream.RecvMsg(m); err != nil {
-		return nil, err
+func (m *RouteTable) Reset()                    { *m = RouteTable{} }
+func (m *RouteTable) String() string            { return proto.CompactTextString(m) }
+func (*RouteTable) ProtoMessage()               {}
+func (*RouteTable) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{10} }
+
+func (m *RouteTable) GetBackends() []*Backend {
+	if m != nil {
+		return m.Backends
 	}
-	return m, nil
+	return nil
 }
 
-func (c *proxyClient) GetKVStream(ctx context.Context, in *Key, opts ...grpc.CallOption) (Proxy_GetKVStreamClient, error) {
-	stream, err := grpc.NewClientStream(ctx, &_Proxy_serviceDesc.Streams[1], c.cc, "/web.Proxy/GetKVStream", opts...)
-	if err != nil {
-		return nil, err
-	}
-	x := &proxyGetKVStreamClient{stream}
-	if err := x.ClientStream.SendMsg(in); err != nil {
-		return nil, err
-	}
-	if err := x.ClientStream.CloseSend(); err != nil {
-		return nil, err
-	}
-	return x, nil
+type CARequest struct {
 }
 
-type Proxy_GetKVStreamClient interface {
-	Recv() (*KV, error)
-	grpc.ClientStream
+func (m *CARequest) Reset()                    { *m = CARequest{} }
+func (m *CARequest) String() string            { return proto.CompactTextString(m) }
+func (*CARequest) ProtoMessage()               {}
+func (*CARequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{11} }
+
+// CAInfo is what clients and backends need to trust our internal CA.
+type CAInfo struct {
+	// PEM-encoded chain of the issuing CA.
+	Bundle []byte `protobuf:"bytes,1,opt,name=bundle,proto3" json:"bundle,omitempty"`
+	// PEM-encoded certificate revocation list.
+	Crl []byte `protobuf:"bytes,2,opt,name=crl,proto3" json:"crl,omitempty"`
 }
 
-type proxyGetKVStreamClient struct {
-	grpc.ClientStream
+func (m *CAInfo) Reset()                    { *m = CAInfo{} }
+func (m *CAInfo) String() string            { return proto.CompactTextString(m) }
+func (*CAInfo) ProtoMessage()               {}
+func (*CAInfo) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{12} }
+
+func (m *CAInfo) GetBundle() []byte {
+	if m != nil {
+		return m.Bundle
+	}
+	return nil
 }
 
-func (x *proxyGetKVStreamClient) Recv() (*KV, error) {
-	m := new(KV)
-	if err := x.ClientStream.RecvMsg(m); err != nil {
-		return nil, err
+func (m *CAInfo) GetCrl() []byte {
+	if m != nil {
+		return m.Crl
 	}
-	return m, nil
+	return nil
 }
 
-// Server API for Proxy service
+type IssueRequest struct {
+	// The certificate's common name.
+	Name string `protobuf:"bytes,1,opt,name=name" json:"name,omitempty"`
+}
+
+func (m *IssueRequest) Reset()                    { *m = IssueRequest{} }
+func (m *IssueRequest) String() string            { return proto.CompactTextString(m) }
+func (*IssueRequest) ProtoMessage()               {}
+func (*IssueRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{13} }
+
+func (m *IssueRequest) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+type RevokeRequest struct {
+	// Hex-encoded serial number of a certificate our CA issued.
+	Serial string `protobuf:"bytes,1,opt,name=serial" json:"serial,omitempty"`
+}
+
+func (m *RevokeRequest) Reset()                    { *m = RevokeRequest{} }
+func (m *RevokeRequest) String() string            { return proto.CompactTextString(m) }
+func (*RevokeRequest) ProtoMessage()               {}
+func (*RevokeRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{14} }
+
+func (m *RevokeRequest) GetSerial() string {
+	if m != nil {
+		return m.Serial
+	}
+	return ""
+}
+
+type CertificatesRequest struct {
+}
+
+func (m *CertificatesRequest) Reset()                    { *m = CertificatesRequest{} }
+func (m *CertificatesRequest) String() string            { return proto.CompactTextString(m) }
+func (*CertificatesRequest) ProtoMessage()               {}
+func (*CertificatesRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{15} }
+
+type CertificateRequest struct {
+	Domain string `protobuf:"bytes,1,opt,name=domain" json:"domain,omitempty"`
+}
+
+func (m *CertificateRequest) Reset()                    { *m = CertificateRequest{} }
+func (m *CertificateRequest) String() string            { return proto.CompactTextString(m) }
+func (*CertificateRequest) ProtoMessage()               {}
+func (*CertificateRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{16} }
+
+func (m *CertificateRequest) GetDomain() string {
+	if m != nil {
+		return m.Domain
+	}
+	return ""
+}
+
+// CertificateInfo describes the certificate served for a domain. It never
+// includes the private key.
+type CertificateInfo struct {
+	Domain  string   `protobuf:"bytes,1,opt,name=domain" json:"domain,omitempty"`
+	Subject string   `protobuf:"bytes,2,opt,name=subject" json:"subject,omitempty"`
+	Sans    []string `protobuf:"bytes,3,rep,name=sans" json:"sans,omitempty"`
+	Issuer  string   `protobuf:"bytes,4,opt,name=issuer" json:"issuer,omitempty"`
+	// Validity period, in unix seconds.
+	NotBefore int64 `protobuf:"varint,5,opt,name=not_before,json=notBefore" json:"not_before,omitempty"`
+	NotAfter  int64 `protobuf:"varint,6,opt,name=not_after,json=notAfter" json:"not_after,omitempty"`
+	// e.g. "ECDSA P-256" or "RSA 2048".
+	KeyType string `protobuf:"bytes,7,opt,name=key_type,json=keyType" json:"key_type,omitempty"`
+	// Hex-encoded SHA-256 of the certificate.
+	Fingerprint string `protobuf:"bytes,8,opt,name=fingerprint" json:"fingerprint,omitempty"`
+	Serial      string `protobuf:"bytes,9,opt,name=serial" json:"serial,omitempty"`
+	// Where the certificate came from: "uploaded", "ca", or "acme".
+	Source string `protobuf:"bytes,10,opt,name=source" json:"source,omitempty"`
+	// Set if the domain has no usable certificate.
+	Error string `protobuf:"bytes,11,opt,name=error" json:"error,omitempty"`
+	// Set if the certificate is staged, waiting to be promoted.
+	Staged bool `protobuf:"varint,12,opt,name=staged" json:"staged,omitempty"`
+}
+
+func (m *CertificateInfo) Reset()                    { *m = CertificateInfo{} }
+func (m *CertificateInfo) String() string            { return proto.CompactTextString(m) }
+func (*CertificateInfo) ProtoMessage()               {}
+func (*CertificateInfo) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{17} }
+
+func (m *CertificateInfo) GetDomain() string {
+	if m != nil {
+		return m.Domain
+	}
+	return ""
+}
+
+func (m *CertificateInfo) GetSubject() string {
+	if m != nil {
+		return m.Subject
+	}
+	return ""
+}
+
+func (m *CertificateInfo) GetSans() []string {
+	if m != nil {
+		return m.Sans
+	}
+	return nil
+}
+
+func (m *CertificateInfo) GetIssuer() string {
+	if m != nil {
+		return m.Issuer
+	}
+	return ""
+}
+
+func (m *CertificateInfo) GetNotBefore() int64 {
+	if m != nil {
+		return m.NotBefore
+	}
+	return 0
+}
+
+func (m *CertificateInfo) GetNotAfter() int64 {
+	if m != nil {
+		return m.NotAfter
+	}
+	return 0
+}
+
+func (m *CertificateInfo) GetKeyType() string {
+	if m != nil {
+		return m.KeyType
+	}
+	return ""
+}
+
+func (m *CertificateInfo) GetFingerprint() string {
+	if m != nil {
+		return m.Fingerprint
+	}
+	return ""
+}
+
+func (m *CertificateInfo) GetSerial() string {
+	if m != nil {
+		return m.Serial
+	}
+	return ""
+}
+
+func (m *CertificateInfo) GetSource() string {
+	if m != nil {
+		return m.Source
+	}
+	return ""
+}
+
+func (m *CertificateInfo) GetError() string {
+	if m != nil {
+		return m.Error
+	}
+	return ""
+}
+
+func (m *CertificateInfo) GetStaged() bool {
+	if m != nil {
+		return m.Staged
+	}
+	return false
+}
+
+type CertificateList struct {
+	Certificates []*CertificateInfo `protobuf:"bytes,1,rep,name=certificates" json:"certificates,omitempty"`
+}
+
+func (m *CertificateList) Reset()                    { *m = CertificateList{} }
+func (m *CertificateList) String() string            { return proto.CompactTextString(m) }
+func (*CertificateList) ProtoMessage()               {}
+func (*CertificateList) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{18} }
+
+func (m *CertificateList) GetCertificates() []*CertificateInfo {
+	if m != nil {
+		return m.Certificates
+	}
+	return nil
+}
+
+// StageRequest stages certificates for a domain, to be served once promoted.
+// The first is served first, like backend_cert.
+type StageRequest struct {
+	Domain string      `protobuf:"bytes,1,opt,name=domain" json:"domain,omitempty"`
+	Certs  []*X509Cert `protobuf:"bytes,2,rep,name=certs" json:"certs,omitempty"`
+}
+
+func (m *StageRequest) Reset()                    { *m = StageRequest{} }
+func (m *StageRequest) String() string            { return proto.CompactTextString(m) }
+func (*StageRequest) ProtoMessage()               {}
+func (*StageRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{19} }
+
+func (m *StageRequest) GetDomain() string {
+	if m != nil {
+		return m.Domain
+	}
+	return ""
+}
+
+func (m *StageRequest) GetCerts() []*X509Cert {
+	if m != nil {
+		return m.Certs
+	}
+	return nil
+}
+
+type PromoteRequest struct {
+	Domain string `protobuf:"bytes,1,opt,name=domain" json:"domain,omitempty"`
+}
+
+func (m *PromoteRequest) Reset()                    { *m = PromoteRequest{} }
+func (m *PromoteRequest) String() string            { return proto.CompactTextString(m) }
+func (*PromoteRequest) ProtoMessage()               {}
+func (*PromoteRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{20} }
+
+func (m *PromoteRequest) GetDomain() string {
+	if m != nil {
+		return m.Domain
+	}
+	return ""
+}
+
+type TicketKeysRequest struct {
+}
+
+func (m *TicketKeysRequest) Reset()                    { *m = TicketKeysRequest{} }
+func (m *TicketKeysRequest) String() string            { return proto.CompactTextString(m) }
+func (*TicketKeysRequest) ProtoMessage()               {}
+func (*TicketKeysRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{21} }
+
+// TicketKeys are the TLS session ticket keys every proxy serves with, newest
+// first. The newest encrypts new tickets; all of them decrypt.
+type TicketKeys struct {
+	Keys [][]byte `protobuf:"bytes,1,rep,name=keys,proto3" json:"keys,omitempty"`
+}
+
+func (m *TicketKeys) Reset()                    { *m = TicketKeys{} }
+func (m *TicketKeys) String() string            { return proto.CompactTextString(m) }
+func (*TicketKeys) ProtoMessage()               {}
+func (*TicketKeys) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{22} }
+
+func (m *TicketKeys) GetKeys() [][]byte {
+	if m != nil {
+		return m.Keys
+	}
+	return nil
+}
+
+type ClientKeyRequest struct {
+	// The client's name, as given when its key was created.
+	Name string `protobuf:"bytes,1,opt,name=name" json:"name,omitempty"`
+	// For CreateClientKey and SetClientKeyRole: one of "read-only",
+	// "forwarder", "operator" or "admin", and optionally the domains the
+	// client may manage, e.g. "*.team.example.com".
+	Role    string   `protobuf:"bytes,2,opt,name=role" json:"role,omitempty"`
+	Domains []string `protobuf:"bytes,3,rep,name=domains" json:"domains,omitempty"`
+}
+
+func (m *ClientKeyRequest) Reset()                    { *m = ClientKeyRequest{} }
+func (m *ClientKeyRequest) String() string            { return proto.CompactTextString(m) }
+func (*ClientKeyRequest) ProtoMessage()               {}
+func (*ClientKeyRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{23} }
+
+func (m *ClientKeyRequest) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func (m *ClientKeyRequest) GetRole() string {
+	if m != nil {
+		return m.Role
+	}
+	return ""
+}
+
+func (m *ClientKeyRequest) GetDomains() []string {
+	if m != nil {
+		return m.Domains
+	}
+	return nil
+}
+
+type ClientKeysRequest struct {
+}
+
+func (m *ClientKeysRequest) Reset()                    { *m = ClientKeysRequest{} }
+func (m *ClientKeysRequest) String() string            { return proto.CompactTextString(m) }
+func (*ClientKeysRequest) ProtoMessage()               {}
+func (*ClientKeysRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{24} }
+
+// ClientKey is a curvetls keypair for a client of our gRPC API. priv is only
+// set when the key is created or rotated; we do not keep it.
+type ClientKey struct {
+	Name string `protobuf:"bytes,1,opt,name=name" json:"name,omitempty"`
+	Pub  string `protobuf:"bytes,2,opt,name=pub" json:"pub,omitempty"`
+	Priv string `protobuf:"bytes,3,opt,name=priv" json:"priv,omitempty"`
+	// Our server's public key, which clients need to connect.
+	ServerPub string `protobuf:"bytes,4,opt,name=server_pub,json=serverPub" json:"server_pub,omitempty"`
+	// Unix seconds; revoked is 0 for keys that are allowed.
+	Created int64    `protobuf:"varint,5,opt,name=created" json:"created,omitempty"`
+	Revoked int64    `protobuf:"varint,6,opt,name=revoked" json:"revoked,omitempty"`
+	Role    string   `protobuf:"bytes,7,opt,name=role" json:"role,omitempty"`
+	Domains []string `protobuf:"bytes,8,rep,name=domains" json:"domains,omitempty"`
+}
+
+func (m *ClientKey) Reset()                    { *m = ClientKey{} }
+func (m *ClientKey) String() string            { return proto.CompactTextString(m) }
+func (*ClientKey) ProtoMessage()               {}
+func (*ClientKey) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{25} }
+
+func (m *ClientKey) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func (m *ClientKey) GetPub() string {
+	if m != nil {
+		return m.Pub
+	}
+	return ""
+}
+
+func (m *ClientKey) GetPriv() string {
+	if m != nil {
+		return m.Priv
+	}
+	return ""
+}
+
+func (m *ClientKey) GetServerPub() string {
+	if m != nil {
+		return m.ServerPub
+	}
+	return ""
+}
+
+func (m *ClientKey) GetCreated() int64 {
+	if m != nil {
+		return m.Created
+	}
+	return 0
+}
+
+func (m *ClientKey) GetRevoked() int64 {
+	if m != nil {
+		return m.Revoked
+	}
+	return 0
+}
+
+func (m *ClientKey) GetRole() string {
+	if m != nil {
+		return m.Role
+	}
+	return ""
+}
+
+func (m *ClientKey) GetDomains() []string {
+	if m != nil {
+		return m.Domains
+	}
+	return nil
+}
+
+type ClientKeyList struct {
+	Keys []*ClientKey `protobuf:"bytes,1,rep,name=keys" json:"keys,omitempty"`
+}
+
+func (m *ClientKeyList) Reset()                    { *m = ClientKeyList{} }
+func (m *ClientKeyList) String() string            { return proto.CompactTextString(m) }
+func (*ClientKeyList) ProtoMessage()               {}
+func (*ClientKeyList) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{26} }
+
+func (m *ClientKeyList) GetKeys() []*ClientKey {
+	if m != nil {
+		return m.Keys
+	}
+	return nil
+}
+
+type ServerKeysRequest struct {
+}
+
+func (m *ServerKeysRequest) Reset()                    { *m = ServerKeysRequest{} }
+func (m *ServerKeysRequest) String() string            { return proto.CompactTextString(m) }
+func (*ServerKeysRequest) ProtoMessage()               {}
+func (*ServerKeysRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{27} }
+
+// ServerKeys are the curvetls public keys of our gRPC API. While a rotation
+// is in progress, next is set, and clients may connect with either.
+type ServerKeys struct {
+	Current string `protobuf:"bytes,1,opt,name=current" json:"current,omitempty"`
+	Next    string `protobuf:"bytes,2,opt,name=next" json:"next,omitempty"`
+	// When the rotation started, in unix seconds.
+	NextCreated int64 `protobuf:"varint,3,opt,name=next_created,json=nextCreated" json:"next_created,omitempty"`
+}
+
+func (m *ServerKeys) Reset()                    { *m = ServerKeys{} }
+func (m *ServerKeys) String() string            { return proto.CompactTextString(m) }
+func (*ServerKeys) ProtoMessage()               {}
+func (*ServerKeys) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{28} }
+
+func (m *ServerKeys) GetCurrent() string {
+	if m != nil {
+		return m.Current
+	}
+	return ""
+}
+
+func (m *ServerKeys) GetNext() string {
+	if m != nil {
+		return m.Next
+	}
+	return ""
+}
+
+func (m *ServerKeys) GetNextCreated() int64 {
+	if m != nil {
+		return m.NextCreated
+	}
+	return 0
+}
+
+// AuditRequest filters the audit log. Blank fields match everything.
+type AuditRequest struct {
+	// A domain, client key name, or other target of a change.
+	Target string `protobuf:"bytes,1,opt,name=target" json:"target,omitempty"`
+	Actor  string `protobuf:"bytes,2,opt,name=actor" json:"actor,omitempty"`
+	// Unix seconds.
+	Since int64 `protobuf:"varint,3,opt,name=since" json:"since,omitempty"`
+	// The newest events are returned first; 0 means 100.
+	Limit int32 `protobuf:"varint,4,opt,name=limit" json:"limit,omitempty"`
+}
+
+func (m *AuditRequest) Reset()                    { *m = AuditRequest{} }
+func (m *AuditRequest) String() string            { return proto.CompactTextString(m) }
+func (*AuditRequest) ProtoMessage()               {}
+func (*AuditRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{29} }
+
+func (m *AuditRequest) GetTarget() string {
+	if m != nil {
+		return m.Target
+	}
+	return ""
+}
+
+func (m *AuditRequest) GetActor() string {
+	if m != nil {
+		return m.Actor
+	}
+	return ""
+}
+
+func (m *AuditRequest) GetSince() int64 {
+	if m != nil {
+		return m.Since
+	}
+	return 0
+}
+
+func (m *AuditRequest) GetLimit() int32 {
+	if m != nil {
+		return m.Limit
+	}
+	return 0
+}
+
+// AuditEvent is a change to our configuration.
+type AuditEvent struct {
+	Id int64 `protobuf:"varint,1,opt,name=id" json:"id,omitempty"`
+	// Unix seconds.
+	Time int64 `protobuf:"varint,2,opt,name=time" json:"time,omitempty"`
+	// The client key name or web UI user that made the change.
+	Actor  string `protobuf:"bytes,3,opt,name=actor" json:"actor,omitempty"`
+	Role   string `protobuf:"bytes,4,opt,name=role" json:"role,omitempty"`
+	Method string `protobuf:"bytes,5,opt,name=method" json:"method,omitempty"`
+	Target string `protobuf:"bytes,6,opt,name=target" json:"target,omitempty"`
+	// The caller's address.
+	Source  string         `protobuf:"bytes,7,opt,name=source" json:"source,omitempty"`
+	Changes []*FieldChange `protobuf:"bytes,8,rep,name=changes" json:"changes,omitempty"`
+}
+
+func (m *AuditEvent) Reset()                    { *m = AuditEvent{} }
+func (m *AuditEvent) String() string            { return proto.CompactTextString(m) }
+func (*AuditEvent) ProtoMessage()               {}
+func (*AuditEvent) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{30} }
+
+func (m *AuditEvent) GetId() int64 {
+	if m != nil {
+		return m.Id
+	}
+	return 0
+}
+
+func (m *AuditEvent) GetTime() int64 {
+	if m != nil {
+		return m.Time
+	}
+	return 0
+}
+
+func (m *AuditEvent) GetActor() string {
+	if m != nil {
+		return m.Actor
+	}
+	return ""
+}
+
+func (m *AuditEvent) GetRole() string {
+	if m != nil {
+		return m.Role
+	}
+	return ""
+}
+
+func (m *AuditEvent) GetMethod() string {
+	if m != nil {
+		return m.Method
+	}
+	return ""
+}
+
+func (m *AuditEvent) GetTarget() string {
+	if m != nil {
+		return m.Target
+	}
+	return ""
+}
+
+func (m *AuditEvent) GetSource() string {
+	if m != nil {
+		return m.Source
+	}
+	return ""
+}
+
+func (m *AuditEvent) GetChanges() []*FieldChange {
+	if m != nil {
+		return m.Changes
+	}
+	return nil
+}
+
+// FieldChange is one field's value before and after a change. Private keys
+// are never included.
+type FieldChange struct {
+	Field  string `protobuf:"bytes,1,opt,name=field" json:"field,omitempty"`
+	Before string `protobuf:"bytes,2,opt,name=before" json:"before,omitempty"`
+	After  string `protobuf:"bytes,3,opt,name=after" json:"after,omitempty"`
+}
+
+func (m *FieldChange) Reset()                    { *m = FieldChange{} }
+func (m *FieldChange) String() string            { return proto.CompactTextString(m) }
+func (*FieldChange) ProtoMessage()               {}
+func (*FieldChange) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{31} }
+
+func (m *FieldChange) GetField() string {
+	if m != nil {
+		return m.Field
+	}
+	return ""
+}
+
+func (m *FieldChange) GetBefore() string {
+	if m != nil {
+		return m.Before
+	}
+	return ""
+}
+
+func (m *FieldChange) GetAfter() string {
+	if m != nil {
+		return m.After
+	}
+	return ""
+}
+
+type AuditEventList struct {
+	Events []*AuditEvent `protobuf:"bytes,1,rep,name=events" json:"events,omitempty"`
+}
+
+func (m *AuditEventList) Reset()                    { *m = AuditEventList{} }
+func (m *AuditEventList) String() string            { return proto.CompactTextString(m) }
+func (*AuditEventList) ProtoMessage()               {}
+func (*AuditEventList) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{32} }
+
+func (m *AuditEventList) GetEvents() []*AuditEvent {
+	if m != nil {
+		return m.Events
+	}
+	return nil
+}
+
+type RevisionsRequest struct {
+	// The newest revisions are returned first; 0 means 20.
+	Limit int32 `protobuf:"varint,1,opt,name=limit" json:"limit,omitempty"`
+}
+
+func (m *RevisionsRequest) Reset()                    { *m = RevisionsRequest{} }
+func (m *RevisionsRequest) String() string            { return proto.CompactTextString(m) }
+func (*RevisionsRequest) ProtoMessage()               {}
+func (*RevisionsRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{33} }
+
+func (m *RevisionsRequest) GetLimit() int32 {
+	if m != nil {
+		return m.Limit
+	}
+	return 0
+}
+
+// Revision is our routing configuration after a change.
+type Revision struct {
+	Id int64 `protobuf:"varint,1,opt,name=id" json:"id,omitempty"`
+	// Unix seconds.
+	Time   int64  `protobuf:"varint,2,opt,name=time" json:"time,omitempty"`
+	Actor  string `protobuf:"bytes,3,opt,name=actor" json:"actor,omitempty"`
+	Method string `protobuf:"bytes,4,opt,name=method" json:"method,omitempty"`
+	Target string `protobuf:"bytes,5,opt,name=target" json:"target,omitempty"`
+	// How many domains we routed.
+	Domains int32 `protobuf:"varint,6,opt,name=domains" json:"domains,omitempty"`
+}
+
+func (m *Revision) Reset()                    { *m = Revision{} }
+func (m *Revision) String() string            { return proto.CompactTextString(m) }
+func (*Revision) ProtoMessage()               {}
+func (*Revision) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{34} }
+
+func (m *Revision) GetId() int64 {
+	if m != nil {
+		return m.Id
+	}
+	return 0
+}
+
+func (m *Revision) GetTime() int64 {
+	if m != nil {
+		return m.Time
+	}
+	return 0
+}
+
+func (m *Revision) GetActor() string {
+	if m != nil {
+		return m.Actor
+	}
+	return ""
+}
+
+func (m *Revision) GetMethod() string {
+	if m != nil {
+		return m.Method
+	}
+	return ""
+}
+
+func (m *Revision) GetTarget() string {
+	if m != nil {
+		return m.Target
+	}
+	return ""
+}
+
+func (m *Revision) GetDomains() int32 {
+	if m != nil {
+		return m.Domains
+	}
+	return 0
+}
+
+type RevisionList struct {
+	Revisions []*Revision `protobuf:"bytes,1,rep,name=revisions" json:"revisions,omitempty"`
+}
+
+func (m *RevisionList) Reset()                    { *m = RevisionList{} }
+func (m *RevisionList) String() string            { return proto.CompactTextString(m) }
+func (*RevisionList) ProtoMessage()               {}
+func (*RevisionList) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{35} }
+
+func (m *RevisionList) GetRevisions() []*Revision {
+	if m != nil {
+		return m.Revisions
+	}
+	return nil
+}
+
+// DiffRequest compares two revisions. A to of 0 means our configuration
+// now.
+type DiffRequest struct {
+	From int64 `protobuf:"varint,1,opt,name=from" json:"from,omitempty"`
+	To   int64 `protobuf:"varint,2,opt,name=to" json:"to,omitempty"`
+}
+
+func (m *DiffRequest) Reset()                    { *m = DiffRequest{} }
+func (m *DiffRequest) String() string            { return proto.CompactTextString(m) }
+func (*DiffRequest) ProtoMessage()               {}
+func (*DiffRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{36} }
+
+func (m *DiffRequest) GetFrom() int64 {
+	if m != nil {
+		return m.From
+	}
+	return 0
+}
+
+func (m *DiffRequest) GetTo() int64 {
+	if m != nil {
+		return m.To
+	}
+	return 0
+}
+
+type DomainDiff struct {
+	Domain  string         `protobuf:"bytes,1,opt,name=domain" json:"domain,omitempty"`
+	Changes []*FieldChange `protobuf:"bytes,2,rep,name=changes" json:"changes,omitempty"`
+}
+
+func (m *DomainDiff) Reset()                    { *m = DomainDiff{} }
+func (m *DomainDiff) String() string            { return proto.CompactTextString(m) }
+func (*DomainDiff) ProtoMessage()               {}
+func (*DomainDiff) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{37} }
+
+func (m *DomainDiff) GetDomain() string {
+	if m != nil {
+		return m.Domain
+	}
+	return ""
+}
+
+func (m *DomainDiff) GetChanges() []*FieldChange {
+	if m != nil {
+		return m.Changes
+	}
+	return nil
+}
+
+type RevisionDiff struct {
+	Domains []*DomainDiff `protobuf:"bytes,1,rep,name=domains" json:"domains,omitempty"`
+}
+
+func (m *RevisionDiff) Reset()                    { *m = RevisionDiff{} }
+func (m *RevisionDiff) String() string            { return proto.CompactTextString(m) }
+func (*RevisionDiff) ProtoMessage()               {}
+func (*RevisionDiff) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{38} }
+
+func (m *RevisionDiff) GetDomains() []*DomainDiff {
+	if m != nil {
+		return m.Domains
+	}
+	return nil
+}
+
+// RollbackRequest restores the routing configuration of a revision. The
+// rollback is a new revision.
+type RollbackRequest struct {
+	Revision int64 `protobuf:"varint,1,opt,name=revision" json:"revision,omitempty"`
+}
+
+func (m *RollbackRequest) Reset()                    { *m = RollbackRequest{} }
+func (m *RollbackRequest) String() string            { return proto.CompactTextString(m) }
+func (*RollbackRequest) ProtoMessage()               {}
+func (*RollbackRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{39} }
+
+func (m *RollbackRequest) GetRevision() int64 {
+	if m != nil {
+		return m.Revision
+	}
+	return 0
+}
+
+// ApplyRequest sets the whole routing configuration at once. Listed
+// backends are created or replaced; certificates, client auth and TLS
+// policy are only changed if passed, as with Put.
+type ApplyRequest struct {
+	Backends []*Backend `protobuf:"bytes,1,rep,name=backends" json:"backends,omitempty"`
+	// Remove backends that are not listed.
+	Prune bool `protobuf:"varint,2,opt,name=prune" json:"prune,omitempty"`
+	// Validate and return the changes, but commit nothing.
+	DryRun bool `protobuf:"varint,3,opt,name=dry_run,json=dryRun" json:"dry_run,omitempty"`
+}
+
+func (m *ApplyRequest) Reset()                    { *m = ApplyRequest{} }
+func (m *ApplyRequest) String() string            { return proto.CompactTextString(m) }
+func (*ApplyRequest) ProtoMessage()               {}
+func (*ApplyRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{40} }
+
+func (m *ApplyRequest) GetBackends() []*Backend {
+	if m != nil {
+		return m.Backends
+	}
+	return nil
+}
+
+func (m *ApplyRequest) GetPrune() bool {
+	if m != nil {
+		return m.Prune
+	}
+	return false
+}
+
+func (m *ApplyRequest) GetDryRun() bool {
+	if m != nil {
+		return m.DryRun
+	}
+	return false
+}
+
+type ApplyResult struct {
+	Changes []*DomainDiff `protobuf:"bytes,1,rep,name=changes" json:"changes,omitempty"`
+	// The revision saved, or 0 if nothing changed or this was a dry run.
+	Revision int64 `protobuf:"varint,2,opt,name=revision" json:"revision,omitempty"`
+}
+
+func (m *ApplyResult) Reset()                    { *m = ApplyResult{} }
+func (m *ApplyResult) String() string            { return proto.CompactTextString(m) }
+func (*ApplyResult) ProtoMessage()               {}
+func (*ApplyResult) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{41} }
+
+func (m *ApplyResult) GetChanges() []*DomainDiff {
+	if m != nil {
+		return m.Changes
+	}
+	return nil
+}
+
+func (m *ApplyResult) GetRevision() int64 {
+	if m != nil {
+		return m.Revision
+	}
+	return 0
+}
+
+// ExportRequest asks for our backends, KV data and client public keys as
+// one document. Private keys are left out, unless passphrase is set; then
+// they are encrypted with it.
+type ExportRequest struct {
+	Passphrase string `protobuf:"bytes,1,opt,name=passphrase" json:"passphrase,omitempty"`
+}
+
+func (m *ExportRequest) Reset()                    { *m = ExportRequest{} }
+func (m *ExportRequest) String() string            { return proto.CompactTextString(m) }
+func (*ExportRequest) ProtoMessage()               {}
+func (*ExportRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{42} }
+
+func (m *ExportRequest) GetPassphrase() string {
+	if m != nil {
+		return m.Passphrase
+	}
+	return ""
+}
+
+// ConfigDocument is an exported configuration, as JSON.
+type ConfigDocument struct {
+	Data []byte `protobuf:"bytes,1,opt,name=data,proto3" json:"data,omitempty"`
+}
+
+func (m *ConfigDocument) Reset()                    { *m = ConfigDocument{} }
+func (m *ConfigDocument) String() string            { return proto.CompactTextString(m) }
+func (*ConfigDocument) ProtoMessage()               {}
+func (*ConfigDocument) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{43} }
+
+func (m *ConfigDocument) GetData() []byte {
+	if m != nil {
+		return m.Data
+	}
+	return nil
+}
+
+type ImportRequest struct {
+	Data []byte `protobuf:"bytes,1,opt,name=data,proto3" json:"data,omitempty"`
+	// Required if the document's private keys are encrypted.
+	Passphrase string `protobuf:"bytes,2,opt,name=passphrase" json:"passphrase,omitempty"`
+	// Replace domains, KV pairs and client keys that differ from ours.
+	// Without it, any difference is a conflict and nothing is imported.
+	Overwrite bool `protobuf:"varint,3,opt,name=overwrite" json:"overwrite,omitempty"`
+	// Check the document and return what would change, committing nothing.
+	DryRun bool `protobuf:"varint,4,opt,name=dry_run,json=dryRun" json:"dry_run,omitempty"`
+}
+
+func (m *ImportRequest) Reset()                    { *m = ImportRequest{} }
+func (m *ImportRequest) String() string            { return proto.CompactTextString(m) }
+func (*ImportRequest) ProtoMessage()               {}
+func (*ImportRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{44} }
+
+func (m *ImportRequest) GetData() []byte {
+	if m != nil {
+		return m.Data
+	}
+	return nil
+}
+
+func (m *ImportRequest) GetPassphrase() string {
+	if m != nil {
+		return m.Passphrase
+	}
+	return ""
+}
+
+func (m *ImportRequest) GetOverwrite() bool {
+	if m != nil {
+		return m.Overwrite
+	}
+	return false
+}
+
+func (m *ImportRequest) GetDryRun() bool {
+	if m != nil {
+		return m.DryRun
+	}
+	return false
+}
+
+type ImportResult struct {
+	Conflicts []string      `protobuf:"bytes,1,rep,name=conflicts" json:"conflicts,omitempty"`
+	Changes   []*DomainDiff `protobuf:"bytes,2,rep,name=changes" json:"changes,omitempty"`
+	// Things imported in part, such as certs without their keys.
+	Warnings []string `protobuf:"bytes,3,rep,name=warnings" json:"warnings,omitempty"`
+	// The revision saved, or 0 if no domain changed.
+	Revision int64 `protobuf:"varint,4,opt,name=revision" json:"revision,omitempty"`
+}
+
+func (m *ImportResult) Reset()                    { *m = ImportResult{} }
+func (m *ImportResult) String() string            { return proto.CompactTextString(m) }
+func (*ImportResult) ProtoMessage()               {}
+func (*ImportResult) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{45} }
+
+func (m *ImportResult) GetConflicts() []string {
+	if m != nil {
+		return m.Conflicts
+	}
+	return nil
+}
+
+func (m *ImportResult) GetChanges() []*DomainDiff {
+	if m != nil {
+		return m.Changes
+	}
+	return nil
+}
+
+func (m *ImportResult) GetWarnings() []string {
+	if m != nil {
+		return m.Warnings
+	}
+	return nil
+}
+
+func (m *ImportResult) GetRevision() int64 {
+	if m != nil {
+		return m.Revision
+	}
+	return 0
+}
+
+// PatchRequest changes some fields of an existing backend and leaves the
+// rest alone.
+type PatchRequest struct {
+	Domain string `protobuf:"bytes,1,opt,name=domain" json:"domain,omitempty"`
+	// The fields of values named in update_mask are set on the backend.
+	// Names are Backend's field names, e.g. "ips" or "max_conns". A field
+	// named but unset in values is cleared.
+	Values     *Backend `protobuf:"bytes,2,opt,name=values" json:"values,omitempty"`
+	UpdateMask []string `protobuf:"bytes,3,rep,name=update_mask,json=updateMask" json:"update_mask,omitempty"`
+	// IPs to add to, and remove from, the backend's IPs, after the mask.
+	AddIps    []string `protobuf:"bytes,4,rep,name=add_ips,json=addIps" json:"add_ips,omitempty"`
+	RemoveIps []string `protobuf:"bytes,5,rep,name=remove_ips,json=removeIps" json:"remove_ips,omitempty"`
+	// If set, the patch fails unless the backend is still at this revision.
+	Revision int64 `protobuf:"varint,6,opt,name=revision" json:"revision,omitempty"`
+}
+
+func (m *PatchRequest) Reset()                    { *m = PatchRequest{} }
+func (m *PatchRequest) String() string            { return proto.CompactTextString(m) }
+func (*PatchRequest) ProtoMessage()               {}
+func (*PatchRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{46} }
+
+func (m *PatchRequest) GetDomain() string {
+	if m != nil {
+		return m.Domain
+	}
+	return ""
+}
+
+func (m *PatchRequest) GetValues() *Backend {
+	if m != nil {
+		return m.Values
+	}
+	return nil
+}
+
+func (m *PatchRequest) GetUpdateMask() []string {
+	if m != nil {
+		return m.UpdateMask
+	}
+	return nil
+}
+
+func (m *PatchRequest) GetAddIps() []string {
+	if m != nil {
+		return m.AddIps
+	}
+	return nil
+}
+
+func (m *PatchRequest) GetRemoveIps() []string {
+	if m != nil {
+		return m.RemoveIps
+	}
+	return nil
+}
+
+func (m *PatchRequest) GetRevision() int64 {
+	if m != nil {
+		return m.Revision
+	}
+	return 0
+}
+
+func init() {
+	proto.RegisterType((*Backend)(nil), "web.Backend")
+	proto.RegisterType((*TLSPolicy)(nil), "web.TLSPolicy")
+	proto.RegisterType((*ClientAuth)(nil), "web.ClientAuth")
+	proto.RegisterType((*X509Cert)(nil), "web.X509Cert")
+	proto.RegisterType((*Key)(nil), "web.Key")
+	proto.RegisterType((*KV)(nil), "web.KV")
+	proto.RegisterType((*ProxyState)(nil), "web.ProxyState")
+	proto.RegisterType((*OpResult)(nil), "web.OpResult")
+	proto.RegisterType((*StateRequest)(nil), "web.StateRequest")
+	proto.RegisterType((*RoutesRequest)(nil), "web.RoutesRequest")
+	proto.RegisterType((*RouteTable)(nil), "web.RouteTable")
+	proto.RegisterType((*CARequest)(nil), "web.CARequest")
+	proto.RegisterType((*CAInfo)(nil), "web.CAInfo")
+	proto.RegisterType((*IssueRequest)(nil), "web.IssueRequest")
+	proto.RegisterType((*RevokeRequest)(nil), "web.RevokeRequest")
+	proto.RegisterType((*CertificatesRequest)(nil), "web.CertificatesRequest")
+	proto.RegisterType((*CertificateRequest)(nil), "web.CertificateRequest")
+	proto.RegisterType((*CertificateInfo)(nil), "web.CertificateInfo")
+	proto.RegisterType((*CertificateList)(nil), "web.CertificateList")
+	proto.RegisterType((*StageRequest)(nil), "web.StageRequest")
+	proto.RegisterType((*PromoteRequest)(nil), "web.PromoteRequest")
+	proto.RegisterType((*TicketKeysRequest)(nil), "web.TicketKeysRequest")
+	proto.RegisterType((*TicketKeys)(nil), "web.TicketKeys")
+	proto.RegisterType((*ClientKeyRequest)(nil), "web.ClientKeyRequest")
+	proto.RegisterType((*ClientKeysRequest)(nil), "web.ClientKeysRequest")
+	proto.RegisterType((*ClientKey)(nil), "web.ClientKey")
+	proto.RegisterType((*ClientKeyList)(nil), "web.ClientKeyList")
+	proto.RegisterType((*ServerKeysRequest)(nil), "web.ServerKeysRequest")
+	proto.RegisterType((*ServerKeys)(nil), "web.ServerKeys")
+	proto.RegisterType((*AuditRequest)(nil), "web.AuditRequest")
+	proto.RegisterType((*AuditEvent)(nil), "web.AuditEvent")
+	proto.RegisterType((*FieldChange)(nil), "web.FieldChange")
+	proto.RegisterType((*AuditEventList)(nil), "web.AuditEventList")
+	proto.RegisterType((*RevisionsRequest)(nil), "web.RevisionsRequest")
+	proto.RegisterType((*Revision)(nil), "web.Revision")
+	proto.RegisterType((*RevisionList)(nil), "web.RevisionList")
+	proto.RegisterType((*DiffRequest)(nil), "web.DiffRequest")
+	proto.RegisterType((*DomainDiff)(nil), "web.DomainDiff")
+	proto.RegisterType((*RevisionDiff)(nil), "web.RevisionDiff")
+	proto.RegisterType((*RollbackRequest)(nil), "web.RollbackRequest")
+	proto.RegisterType((*ApplyRequest)(nil), "web.ApplyRequest")
+	proto.RegisterType((*ApplyResult)(nil), "web.ApplyResult")
+	proto.RegisterType((*ExportRequest)(nil), "web.ExportRequest")
+	proto.RegisterType((*ConfigDocument)(nil), "web.ConfigDocument")
+	proto.RegisterType((*ImportRequest)(nil), "web.ImportRequest")
+	proto.RegisterType((*ImportResult)(nil), "web.ImportResult")
+	proto.RegisterType((*PatchRequest)(nil), "web.PatchRequest")
+	proto.RegisterEnum("web.Backend_Protocol", Backend_Protocol_name, Backend_Protocol_value)
+	proto.RegisterEnum("web.ClientAuth_Forward", ClientAuth_Forward_name, ClientAuth_Forward_value)
+	proto.RegisterEnum("web.StateRequest_Sort", StateRequest_Sort_name, StateRequest_Sort_value)
+}
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ context.Context
+var _ grpc.ClientConn
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+const _ = grpc.SupportPackageIsVersion4
+
+// Client API for Proxy service
+
+type ProxyClient interface {
+	State(ctx context.Context, in *StateRequest, opts ...grpc.CallOption) (*ProxyState, error)
+	Put(ctx context.Context, in *Backend, opts ...grpc.CallOption) (*OpResult, error)
+	PatchBackend(ctx context.Context, in *PatchRequest, opts ...grpc.CallOption) (*Backend, error)
+	Remove(ctx context.Context, in *Backend, opts ...grpc.CallOption) (*OpResult, error)
+	PutKVStream(ctx context.Context, opts ...grpc.CallOption) (Proxy_PutKVStreamClient, error)
+	GetKVStream(ctx context.Context, in *Key, opts ...grpc.CallOption) (Proxy_GetKVStreamClient, error)
+	Routes(ctx context.Context, in *RoutesRequest, opts ...grpc.CallOption) (*RouteTable, error)
+	GetCA(ctx context.Context, in *CARequest, opts ...grpc.CallOption) (*CAInfo, error)
+	IssueClientCert(ctx context.Context, in *IssueRequest, opts ...grpc.CallOption) (*X509Cert, error)
+	Revoke(ctx context.Context, in *RevokeRequest, opts ...grpc.CallOption) (*OpResult, error)
+	ListCertificates(ctx context.Context, in *CertificatesRequest, opts ...grpc.CallOption) (*CertificateList, error)
+	GetCertificate(ctx context.Context, in *CertificateRequest, opts ...grpc.CallOption) (*CertificateInfo, error)
+	StageCertificates(ctx context.Context, in *StageRequest, opts ...grpc.CallOption) (*OpResult, error)
+	PromoteCertificates(ctx context.Context, in *PromoteRequest, opts ...grpc.CallOption) (*OpResult, error)
+	SessionTicketKeys(ctx context.Context, in *TicketKeysRequest, opts ...grpc.CallOption) (*TicketKeys, error)
+	CreateClientKey(ctx context.Context, in *ClientKeyRequest, opts ...grpc.CallOption) (*ClientKey, error)
+	ListClientKeys(ctx context.Context, in *ClientKeysRequest, opts ...grpc.CallOption) (*ClientKeyList, error)
+	RevokeClientKey(ctx context.Context, in *ClientKeyRequest, opts ...grpc.CallOption) (*OpResult, error)
+	RotateClientKey(ctx context.Context, in *ClientKeyRequest, opts ...grpc.CallOption) (*ClientKey, error)
+	SetClientKeyRole(ctx context.Context, in *ClientKeyRequest, opts ...grpc.CallOption) (*ClientKey, error)
+	GetServerKeys(ctx context.Context, in *ServerKeysRequest, opts ...grpc.CallOption) (*ServerKeys, error)
+	RotateServerKey(ctx context.Context, in *ServerKeysRequest, opts ...grpc.CallOption) (*ServerKeys, error)
+	RetireServerKey(ctx context.Context, in *ServerKeysRequest, opts ...grpc.CallOption) (*ServerKeys, error)
+	ListAuditEvents(ctx context.Context, in *AuditRequest, opts ...grpc.CallOption) (*AuditEventList, error)
+	ListRevisions(ctx context.Context, in *RevisionsRequest, opts ...grpc.CallOption) (*RevisionList, error)
+	DiffRevisions(ctx context.Context, in *DiffRequest, opts ...grpc.CallOption) (*RevisionDiff, error)
+	Rollback(ctx context.Context, in *RollbackRequest, opts ...grpc.CallOption) (*OpResult, error)
+	Apply(ctx context.Context, in *ApplyRequest, opts ...grpc.CallOption) (*ApplyResult, error)
+	Export(ctx context.Context, in *ExportRequest, opts ...grpc.CallOption) (*ConfigDocument, error)
+	Import(ctx context.Context, in *ImportRequest, opts ...grpc.CallOption) (*ImportResult, error)
+}
+
+type proxyClient struct {
+	cc *grpc.ClientConn
+}
+
+func NewProxyClient(cc *grpc.ClientConn) ProxyClient {
+	return &proxyClient{cc}
+}
+
+func (c *proxyClient) State(ctx context.Context, in *StateRequest, opts ...grpc.CallOption) (*ProxyState, error) {
+	out := new(ProxyState)
+	err := grpc.Invoke(ctx, "/web.Proxy/State", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *proxyClient) Put(ctx context.Context, in *Backend, opts ...grpc.CallOption) (*OpResult, error) {
+	out := new(OpResult)
+	err := grpc.Invoke(ctx, "/web.Proxy/Put", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *proxyClient) PatchBackend(ctx context.Context, in *PatchRequest, opts ...grpc.CallOption) (*Backend, error) {
+	out := new(Backend)
+	err := grpc.Invoke(ctx, "/web.Proxy/PatchBackend", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *proxyClient) Remove(ctx context.Context, in *Backend, opts ...grpc.CallOption) (*OpResult, error) {
+	out := new(OpResult)
+	err := grpc.Invoke(ctx, "/web.Proxy/Remove", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *proxyClient) PutKVStream(ctx context.Context, opts ...grpc.CallOption) (Proxy_PutKVStreamClient, error) {
+	stream, err := grpc.NewClientStream(ctx, &_Proxy_serviceDesc.Streams[0], c.cc, "/web.Proxy/PutKVStream", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &proxyPutKVStreamClient{stream}
+	return x, nil
+}
+
+type Proxy_PutKVStreamClient interface {
+	Send(*KV) error
+	CloseAndRecv() (*OpResult, error)
+	grpc.ClientStream
+}
+
+type proxyPutKVStreamClient struct {
+	grpc.ClientStream
+}
+
+func (x *proxyPutKVStreamClient) Send(m *KV) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *proxyPutKVStreamClient) CloseAndRecv() (*OpResult, error) {
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	m := new(OpResult)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *proxyClient) GetKVStream(ctx context.Context, in *Key, opts ...grpc.CallOption) (Proxy_GetKVStreamClient, error) {
+	stream, err := grpc.NewClientStream(ctx, &_Proxy_serviceDesc.Streams[1], c.cc, "/web.Proxy/GetKVStream", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &proxyGetKVStreamClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Proxy_GetKVStreamClient interface {
+	Recv() (*KV, error)
+	grpc.ClientStream
+}
+
+type proxyGetKVStreamClient struct {
+	grpc.ClientStream
+}
+
+func (x *proxyGetKVStreamClient) Recv() (*KV, error) {
+	m := new(KV)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *proxyClient) Routes(ctx context.Context, in *RoutesRequest, opts ...grpc.CallOption) (*RouteTable, error) {
+	out := new(RouteTable)
+	err := grpc.Invoke(ctx, "/web.Proxy/Routes", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *proxyClient) GetCA(ctx context.Context, in *CARequest, opts ...grpc.CallOption) (*CAInfo, error) {
+	out := new(CAInfo)
+	err := grpc.Invoke(ctx, "/web.Proxy/GetCA", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *proxyClient) IssueClientCert(ctx context.Context, in *IssueRequest, opts ...grpc.CallOption) (*X509Cert, error) {
+	out := new(X509Cert)
+	err := grpc.Invoke(ctx, "/web.Proxy/IssueClientCert", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *proxyClient) Revoke(ctx context.Context, in *RevokeRequest, opts ...grpc.CallOption) (*OpResult, error) {
+	out := new(OpResult)
+	err := grpc.Invoke(ctx, "/web.Proxy/Revoke", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *proxyClient) ListCertificates(ctx context.Context, in *CertificatesRequest, opts ...grpc.CallOption) (*CertificateList, error) {
+	out := new(CertificateList)
+	err := grpc.Invoke(ctx, "/web.Proxy/ListCertificates", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *proxyClient) GetCertificate(ctx context.Context, in *CertificateRequest, opts ...grpc.CallOption) (*CertificateInfo, error) {
+	out := new(CertificateInfo)
+	err := grpc.Invoke(ctx, "/web.Proxy/GetCertificate", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *proxyClient) StageCertificates(ctx context.Context, in *StageRequest, opts ...grpc.CallOption) (*OpResult, error) {
+	out := new(OpResult)
+	err := grpc.Invoke(ctx, "/web.Proxy/StageCertificates", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *proxyClient) PromoteCertificates(ctx context.Context, in *PromoteRequest, opts ...grpc.CallOption) (*OpResult, error) {
+	out := new(OpResult)
+	err := grpc.Invoke(ctx, "/web.Proxy/PromoteCertificates", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *proxyClient) SessionTicketKeys(ctx context.Context, in *TicketKeysRequest, opts ...grpc.CallOption) (*TicketKeys, error) {
+	out := new(TicketKeys)
+	err := grpc.Invoke(ctx, "/web.Proxy/SessionTicketKeys", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *proxyClient) CreateClientKey(ctx context.Context, in *ClientKeyRequest, opts ...grpc.CallOption) (*ClientKey, error) {
+	out := new(ClientKey)
+	err := grpc.Invoke(ctx, "/web.Proxy/CreateClientKey", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *proxyClient) ListClientKeys(ctx context.Context, in *ClientKeysRequest, opts ...grpc.CallOption) (*ClientKeyList, error) {
+	out := new(ClientKeyList)
+	err := grpc.Invoke(ctx, "/web.Proxy/ListClientKeys", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *proxyClient) RevokeClientKey(ctx context.Context, in *ClientKeyRequest, opts ...grpc.CallOption) (*OpResult, error) {
+	out := new(OpResult)
+	err := grpc.Invoke(ctx, "/web.Proxy/RevokeClientKey", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *proxyClient) RotateClientKey(ctx context.Context, in *ClientKeyRequest, opts ...grpc.CallOption) (*ClientKey, error) {
+	out := new(ClientKey)
+	err := grpc.Invoke(ctx, "/web.Proxy/RotateClientKey", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *proxyClient) SetClientKeyRole(ctx context.Context, in *ClientKeyRequest, opts ...grpc.CallOption) (*ClientKey, error) {
+	out := new(ClientKey)
+	err := grpc.Invoke(ctx, "/web.Proxy/SetClientKeyRole", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *proxyClient) GetServerKeys(ctx context.Context, in *ServerKeysRequest, opts ...grpc.CallOption) (*ServerKeys, error) {
+	out := new(ServerKeys)
+	err := grpc.Invoke(ctx, "/web.Proxy/GetServerKeys", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *proxyClient) RotateServerKey(ctx context.Context, in *ServerKeysRequest, opts ...grpc.CallOption) (*ServerKeys, error) {
+	out := new(ServerKeys)
+	err := grpc.Invoke(ctx, "/web.Proxy/RotateServerKey", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *proxyClient) RetireServerKey(ctx context.Context, in *ServerKeysRequest, opts ...grpc.CallOption) (*ServerKeys, error) {
+	out := new(ServerKeys)
+	err := grpc.Invoke(ctx, "/web.Proxy/RetireServerKey", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *proxyClient) ListAuditEvents(ctx context.Context, in *AuditRequest, opts ...grpc.CallOption) (*AuditEventList, error) {
+	out := new(AuditEventList)
+	err := grpc.Invoke(ctx, "/web.Proxy/ListAuditEvents", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *proxyClient) ListRevisions(ctx context.Context, in *RevisionsRequest, opts ...grpc.CallOption) (*RevisionList, error) {
+	out := new(RevisionList)
+	err := grpc.Invoke(ctx, "/web.Proxy/ListRevisions", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *proxyClient) DiffRevisions(ctx context.Context, in *DiffRequest, opts ...grpc.CallOption) (*RevisionDiff, error) {
+	out := new(RevisionDiff)
+	err := grpc.Invoke(ctx, "/web.Proxy/DiffRevisions", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *proxyClient) Rollback(ctx context.Context, in *RollbackRequest, opts ...grpc.CallOption) (*OpResult, error) {
+	out := new(OpResult)
+	err := grpc.Invoke(ctx, "/web.Proxy/Rollback", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *proxyClient) Apply(ctx context.Context, in *ApplyRequest, opts ...grpc.CallOption) (*ApplyResult, error) {
+	out := new(ApplyResult)
+	err := grpc.Invoke(ctx, "/web.Proxy/Apply", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *proxyClient) Export(ctx context.Context, in *ExportRequest, opts ...grpc.CallOption) (*ConfigDocument, error) {
+	out := new(ConfigDocument)
+	err := grpc.Invoke(ctx, "/web.Proxy/Export", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *proxyClient) Import(ctx context.Context, in *ImportRequest, opts ...grpc.CallOption) (*ImportResult, error) {
+	out := new(ImportResult)
+	err := grpc.Invoke(ctx, "/web.Proxy/Import", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// Server API for Proxy service
+
+type ProxyServer interface {
+	State(context.Context, *StateRequest) (*ProxyState, error)
+	Put(context.Context, *Backend) (*OpResult, error)
+	PatchBackend(context.Context, *PatchRequest) (*Backend, error)
+	Remove(context.Context, *Backend) (*OpResult, error)
+	PutKVStream(Proxy_PutKVStreamServer) error
+	GetKVStream(*Key, Proxy_GetKVStreamServer) error
+	Routes(context.Context, *RoutesRequest) (*RouteTable, error)
+	GetCA(context.Context, *CARequest) (*CAInfo, error)
+	IssueClientCert(context.Context, *IssueRequest) (*X509Cert, error)
+	Revoke(context.Context, *RevokeRequest) (*OpResult, error)
+	ListCertificates(context.Context, *CertificatesRequest) (*CertificateList, error)
+	GetCertificate(context.Context, *CertificateRequest) (*CertificateInfo, error)
+	StageCertificates(context.Context, *StageRequest) (*OpResult, error)
+	PromoteCertificates(context.Context, *PromoteRequest) (*OpResult, error)
+	SessionTicketKeys(context.Context, *TicketKeysRequest) (*TicketKeys, error)
+	CreateClientKey(context.Context, *ClientKeyRequest) (*ClientKey, error)
+	ListClientKeys(context.Context, *ClientKeysRequest) (*ClientKeyList, error)
+	RevokeClientKey(context.Context, *ClientKeyRequest) (*OpResult, error)
+	RotateClientKey(context.Context, *ClientKeyRequest) (*ClientKey, error)
+	SetClientKeyRole(context.Context, *ClientKeyRequest) (*ClientKey, error)
+	GetServerKeys(context.Context, *ServerKeysRequest) (*ServerKeys, error)
+	RotateServerKey(context.Context, *ServerKeysRequest) (*ServerKeys, error)
+	RetireServerKey(context.Context, *ServerKeysRequest) (*ServerKeys, error)
+	ListAuditEvents(context.Context, *AuditRequest) (*AuditEventList, error)
+	ListRevisions(context.Context, *RevisionsRequest) (*RevisionList, error)
+	DiffRevisions(context.Context, *DiffRequest) (*RevisionDiff, error)
+	Rollback(context.Context, *RollbackRequest) (*OpResult, error)
+	Apply(context.Context, *ApplyRequest) (*ApplyResult, error)
+	Export(context.Context, *ExportRequest) (*ConfigDocument, error)
+	Import(context.Context, *ImportRequest) (*ImportResult, error)
+}
+
+func RegisterProxyServer(s *grpc.Server, srv ProxyServer) {
+	s.RegisterService(&_Proxy_serviceDesc, srv)
+}
+
+func _Proxy_State_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(StateRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ProxyServer).State(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/web.Proxy/State",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ProxyServer).State(ctx, req.(*StateRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Proxy_Put_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Backend)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ProxyServer).Put(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/web.Proxy/Put",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ProxyServer).Put(ctx, req.(*Backend))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Proxy_PatchBackend_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PatchRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ProxyServer).PatchBackend(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/web.Proxy/PatchBackend",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ProxyServer).PatchBackend(ctx, req.(*PatchRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Proxy_Remove_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Backend)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ProxyServer).Remove(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/web.Proxy/Remove",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ProxyServer).Remove(ctx, req.(*Backend))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Proxy_PutKVStream_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(ProxyServer).PutKVStream(&proxyPutKVStreamServer{stream})
+}
+
+type Proxy_PutKVStreamServer interface {
+	SendAndClose(*OpResult) error
+	Recv() (*KV, error)
+	grpc.ServerStream
+}
+
+type proxyPutKVStreamServer struct {
+	grpc.ServerStream
+}
+
+func (x *proxyPutKVStreamServer) SendAndClose(m *OpResult) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *proxyPutKVStreamServer) Recv() (*KV, error) {
+	m := new(KV)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func _Proxy_GetKVStream_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(Key)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(ProxyServer).GetKVStream(m, &proxyGetKVStreamServer{stream})
+}
+
+type Proxy_GetKVStreamServer interface {
+	Send(*KV) error
+	grpc.ServerStream
+}
+
+type proxyGetKVStreamServer struct {
+	grpc.ServerStream
+}
+
+func (x *proxyGetKVStreamServer) Send(m *KV) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _Proxy_Routes_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RoutesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ProxyServer).Routes(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/web.Proxy/Routes",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ProxyServer).Routes(ctx, req.(*RoutesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Proxy_GetCA_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CARequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ProxyServer).GetCA(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/web.Proxy/GetCA",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ProxyServer).GetCA(ctx, req.(*CARequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Proxy_IssueClientCert_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(IssueRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ProxyServer).IssueClientCert(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/web.Proxy/IssueClientCert",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ProxyServer).IssueClientCert(ctx, req.(*IssueRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Proxy_Revoke_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RevokeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ProxyServer).Revoke(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/web.Proxy/Revoke",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ProxyServer).Revoke(ctx, req.(*RevokeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Proxy_ListCertificates_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CertificatesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ProxyServer).ListCertificates(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/web.Proxy/ListCertificates",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ProxyServer).ListCertificates(ctx, req.(*CertificatesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Proxy_GetCertificate_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CertificateRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ProxyServer).GetCertificate(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/web.Proxy/GetCertificate",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ProxyServer).GetCertificate(ctx, req.(*CertificateRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Proxy_StageCertificates_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(StageRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ProxyServer).StageCertificates(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/web.Proxy/StageCertificates",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ProxyServer).StageCertificates(ctx, req.(*StageRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
 
-type ProxyServer interface {
-	State(context.Context, *StateRequest) (*ProxyState, error)
-	Put(context.Context, *Backend) (*OpResult, error)
-	Remove(context.Context, *Backend) (*OpResult, error)
-	PutKVStream(Proxy_PutKVStreamServer) error
-	GetKVStream(*Key, Proxy_GetKVStreamServer) error
+func _Proxy_PromoteCertificates_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PromoteRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ProxyServer).PromoteCertificates(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/web.Proxy/PromoteCertificates",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ProxyServer).PromoteCertificates(ctx, req.(*PromoteRequest))
+	}
+	return interceptor(ctx, in, info, handler)
 }
 
-func RegisterProxyServer(s *grpc.Server, srv ProxyServer) {
-	s.RegisterService(&_Proxy_serviceDesc, srv)
+func _Proxy_SessionTicketKeys_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(TicketKeysRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ProxyServer).SessionTicketKeys(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/web.Proxy/SessionTicketKeys",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ProxyServer).SessionTicketKeys(ctx, req.(*TicketKeysRequest))
+	}
+	return interceptor(ctx, in, info, handler)
 }
 
-func _Proxy_State_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(StateRequest)
+func _Proxy_CreateClientKey_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ClientKeyRequest)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(ProxyServer).State(ctx, in)
+		return srv.(ProxyServer).CreateClientKey(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: "/web.Proxy/State",
+		FullMethod: "/web.Proxy/CreateClientKey",
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(ProxyServer).State(ctx, req.(*StateRequest))
+		return srv.(ProxyServer).CreateClientKey(ctx, req.(*ClientKeyRequest))
 	}
 	return interceptor(ctx, in, info, handler)
 }
 
-func _Proxy_Put_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(Backend)
+func _Proxy_ListClientKeys_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ClientKeysRequest)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(ProxyServer).Put(ctx, in)
+		return srv.(ProxyServer).ListClientKeys(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: "/web.Proxy/Put",
+		FullMethod: "/web.Proxy/ListClientKeys",
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(ProxyServer).Put(ctx, req.(*Backend))
+		return srv.(ProxyServer).ListClientKeys(ctx, req.(*ClientKeysRequest))
 	}
 	return interceptor(ctx, in, info, handler)
 }
 
-func _Proxy_Remove_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(Backend)
+func _Proxy_RevokeClientKey_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ClientKeyRequest)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(ProxyServer).Remove(ctx, in)
+		return srv.(ProxyServer).RevokeClientKey(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: "/web.Proxy/Remove",
+		FullMethod: "/web.Proxy/RevokeClientKey",
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(ProxyServer).Remove(ctx, req.(*Backend))
+		return srv.(ProxyServer).RevokeClientKey(ctx, req.(*ClientKeyRequest))
 	}
 	return interceptor(ctx, in, info, handler)
 }
 
-func _Proxy_PutKVStream_Handler(srv interface{}, stream grpc.ServerStream) error {
-	return srv.(ProxyServer).PutKVStream(&proxyPutKVStreamServer{stream})
+func _Proxy_RotateClientKey_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ClientKeyRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ProxyServer).RotateClientKey(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/web.Proxy/RotateClientKey",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ProxyServer).RotateClientKey(ctx, req.(*ClientKeyRequest))
+	}
+	return interceptor(ctx, in, info, handler)
 }
 
-type Proxy_PutKVStreamServer interface {
-	SendAndClose(*OpResult) error
-	Recv() (*KV, error)
-	grpc.ServerStream
+func _Proxy_SetClientKeyRole_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ClientKeyRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ProxyServer).SetClientKeyRole(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/web.Proxy/SetClientKeyRole",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ProxyServer).SetClientKeyRole(ctx, req.(*ClientKeyRequest))
+	}
+	return interceptor(ctx, in, info, handler)
 }
 
-type proxyPutKVStreamServer struct {
-	grpc.ServerStream
+func _Proxy_GetServerKeys_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ServerKeysRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ProxyServer).GetServerKeys(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/web.Proxy/GetServerKeys",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ProxyServer).GetServerKeys(ctx, req.(*ServerKeysRequest))
+	}
+	return interceptor(ctx, in, info, handler)
 }
 
-func (x *proxyPutKVStreamServer) SendAndClose(m *OpResult) error {
-	return x.ServerStream.SendMsg(m)
+func _Proxy_RotateServerKey_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ServerKeysRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ProxyServer).RotateServerKey(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/web.Proxy/RotateServerKey",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ProxyServer).RotateServerKey(ctx, req.(*ServerKeysRequest))
+	}
+	return interceptor(ctx, in, info, handler)
 }
 
-func (x *proxyPutKVStreamServer) Recv() (*KV, error) {
-	m := new(KV)
-	if err := x.ServerStream.RecvMsg(m); err != nil {
+func _Proxy_RetireServerKey_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ServerKeysRequest)
+	if err := dec(in); err != nil {
 		return nil, err
 	}
-	return m, nil
+	if interceptor == nil {
+		return srv.(ProxyServer).RetireServerKey(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/web.Proxy/RetireServerKey",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ProxyServer).RetireServerKey(ctx, req.(*ServerKeysRequest))
+	}
+	return interceptor(ctx, in, info, handler)
 }
 
-func _Proxy_GetKVStream_Handler(srv interface{}, stream grpc.ServerStream) error {
-	m := new(Key)
-	if err := stream.RecvMsg(m); err != nil {
-		return err
+func _Proxy_ListAuditEvents_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AuditRequest)
+	if err := dec(in); err != nil {
+		return nil, err
 	}
-	return srv.(ProxyServer).GetKVStream(m, &proxyGetKVStreamServer{stream})
+	if interceptor == nil {
+		return srv.(ProxyServer).ListAuditEvents(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/web.Proxy/ListAuditEvents",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ProxyServer).ListAuditEvents(ctx, req.(*AuditRequest))
+	}
+	return interceptor(ctx, in, info, handler)
 }
 
-type Proxy_GetKVStreamServer interface {
-	Send(*KV) error
-	grpc.ServerStream
+func _Proxy_ListRevisions_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RevisionsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ProxyServer).ListRevisions(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/web.Proxy/ListRevisions",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ProxyServer).ListRevisions(ctx, req.(*RevisionsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
 }
 
-type proxyGetKVStreamServer struct {
-	grpc.ServerStream
+func _Proxy_DiffRevisions_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DiffRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ProxyServer).DiffRevisions(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/web.Proxy/DiffRevisions",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ProxyServer).DiffRevisions(ctx, req.(*DiffRequest))
+	}
+	return interceptor(ctx, in, info, handler)
 }
 
-func (x *proxyGetKVStreamServer) Send(m *KV) error {
-	return x.ServerStream.SendMsg(m)
+func _Proxy_Rollback_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RollbackRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ProxyServer).Rollback(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/web.Proxy/Rollback",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ProxyServer).Rollback(ctx, req.(*RollbackRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Proxy_Apply_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ApplyRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ProxyServer).Apply(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/web.Proxy/Apply",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ProxyServer).Apply(ctx, req.(*ApplyRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Proxy_Export_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ExportRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ProxyServer).Export(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/web.Proxy/Export",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ProxyServer).Export(ctx, req.(*ExportRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Proxy_Import_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ImportRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ProxyServer).Import(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/web.Proxy/Import",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ProxyServer).Import(ctx, req.(*ImportRequest))
+	}
+	return interceptor(ctx, in, info, handler)
 }
 
 var _Proxy_serviceDesc = grpc.ServiceDesc{
@@ -531,10 +2784,110 @@ var _Proxy_serviceDesc = grpc.ServiceDesc{
 			MethodName: "Put",
 			Handler:    _Proxy_Put_Handler,
 		},
+		{
+			MethodName: "PatchBackend",
+			Handler:    _Proxy_PatchBackend_Handler,
+		},
 		{
 			MethodName: "Remove",
 			Handler:    _Proxy_Remove_Handler,
 		},
+		{
+			MethodName: "Routes",
+			Handler:    _Proxy_Routes_Handler,
+		},
+		{
+			MethodName: "GetCA",
+			Handler:    _Proxy_GetCA_Handler,
+		},
+		{
+			MethodName: "IssueClientCert",
+			Handler:    _Proxy_IssueClientCert_Handler,
+		},
+		{
+			MethodName: "Revoke",
+			Handler:    _Proxy_Revoke_Handler,
+		},
+		{
+			MethodName: "ListCertificates",
+			Handler:    _Proxy_ListCertificates_Handler,
+		},
+		{
+			MethodName: "GetCertificate",
+			Handler:    _Proxy_GetCertificate_Handler,
+		},
+		{
+			MethodName: "StageCertificates",
+			Handler:    _Proxy_StageCertificates_Handler,
+		},
+		{
+			MethodName: "PromoteCertificates",
+			Handler:    _Proxy_PromoteCertificates_Handler,
+		},
+		{
+			MethodName: "SessionTicketKeys",
+			Handler:    _Proxy_SessionTicketKeys_Handler,
+		},
+		{
+			MethodName: "CreateClientKey",
+			Handler:    _Proxy_CreateClientKey_Handler,
+		},
+		{
+			MethodName: "ListClientKeys",
+			Handler:    _Proxy_ListClientKeys_Handler,
+		},
+		{
+			MethodName: "RevokeClientKey",
+			Handler:    _Proxy_RevokeClientKey_Handler,
+		},
+		{
+			MethodName: "RotateClientKey",
+			Handler:    _Proxy_RotateClientKey_Handler,
+		},
+		{
+			MethodName: "SetClientKeyRole",
+			Handler:    _Proxy_SetClientKeyRole_Handler,
+		},
+		{
+			MethodName: "GetServerKeys",
+			Handler:    _Proxy_GetServerKeys_Handler,
+		},
+		{
+			MethodName: "RotateServerKey",
+			Handler:    _Proxy_RotateServerKey_Handler,
+		},
+		{
+			MethodName: "RetireServerKey",
+			Handler:    _Proxy_RetireServerKey_Handler,
+		},
+		{
+			MethodName: "ListAuditEvents",
+			Handler:    _Proxy_ListAuditEvents_Handler,
+		},
+		{
+			MethodName: "ListRevisions",
+			Handler:    _Proxy_ListRevisions_Handler,
+		},
+		{
+			MethodName: "DiffRevisions",
+			Handler:    _Proxy_DiffRevisions_Handler,
+		},
+		{
+			MethodName: "Rollback",
+			Handler:    _Proxy_Rollback_Handler,
+		},
+		{
+			MethodName: "Apply",
+			Handler:    _Proxy_Apply_Handler,
+		},
+		{
+			MethodName: "Export",
+			Handler:    _Proxy_Export_Handler,
+		},
+		{
+			MethodName: "Import",
+			Handler:    _Proxy_Import_Handler,
+		},
 	},
 	Streams: []grpc.StreamDesc{
 		{
@@ -554,42 +2907,172 @@ var _Proxy_serviceDesc = grpc.ServiceDesc{
 func init() { proto.RegisterFile("proto/web.proto", fileDescriptor0) }
 
 var fileDescriptor0 = []byte{
-	// 579 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x84, 0x94, 0x6f, 0x6b, 0xd3, 0x5e,
-	0x14, 0xc7, 0x9b, 0x66, 0xe9, 0xb2, 0x93, 0xf4, 0xb7, 0xee, 0xf2, 0x53, 0x42, 0x41, 0xa9, 0x71,
-	0x68, 0x14, 0xd7, 0x76, 0x19, 0x8a, 0xfa, 0x44, 0x58, 0x91, 0x0d, 0x8a, 0x18, 0xee, 0xc6, 0x10,
-	0x9f, 0x8c, 0x24, 0x3d, 0x9a, 0xb0, 0x26, 0xa9, 0x37, 0x37, 0xdb, 0xfa, 0x06, 0x7d, 0x09, 0xbe,
-	0x1e, 0xb9, 0x37, 0x37, 0x6b, 0xc7, 0x36, 0x7c, 0x76, 0xfe, 0x7c, 0x4e, 0xee, 0xf7, 0x7b, 0xee,
-	0x25, 0xb0, 0xbd, 0x60, 0x05, 0x2f, 0x46, 0x57, 0x18, 0x0d, 0x65, 0x44, 0xf4, 0x2b, 0x8c, 0xdc,
-	0xdf, 0x3a, 0x6c, 0x1e, 0x86, 0xf1, 0x05, 0xe6, 0x33, 0xf2, 0x18, 0x3a, 0xb3, 0x22, 0x0b, 0xd3,
-	0xdc, 0xd1, 0x06, 0x9a, 0xb7, 0x45, 0x55, 0x46, 0x7a, 0xa0, 0xa7, 0x8b, 0xd2, 0x69, 0x0f, 0x74,
-	0x6f, 0x8b, 0x8a, 0x90, 0x3c, 0x03, 0x3b, 0xc1, 0x70, 0xce, 0x93, 0xf3, 0x38, 0xc1, 0xf8, 0xc2,
-	0xd1, 0x25, 0x6f, 0xd5, 0xb5, 0x89, 0x28, 0x91, 0xe7, 0xd0, 0x55, 0x48, 0xc9, 0x43, 0x5e, 0x95,
-	0xce, 0x86, 0x64, 0xd4, 0xdc, 0x89, 0xac, 0x91, 0x7d, 0x30, 0xa5, 0x96, 0xb8, 0x98, 0x3b, 0xc6,
-	0x40, 0xf3, 0xfe, 0xf3, 0x1f, 0x0d, 0x85, 0x40, 0xa5, 0x68, 0x18, 0xa8, 0x26, 0xbd, 0xc1, 0x88,
-	0x0f, 0xdd, 0x34, 0xe7, 0xc8, 0x72, 0xe4, 0xe7, 0x31, 0x32, 0xee, 0x74, 0x06, 0x9a, 0x67, 0xf9,
-	0x5d, 0x39, 0xf7, 0xed, 0xed, 0xf8, 0xc3, 0x04, 0x19, 0xa7, 0x76, 0xc3, 0x88, 0x8c, 0x8c, 0xc1,
-	0x8e, 0xea, 0x2f, 0xd6, 0x23, 0x9b, 0xf7, 0x8d, 0x58, 0x0a, 0x91, 0x13, 0x13, 0xe8, 0x66, 0x21,
-	0x8f, 0x93, 0xf3, 0x04, 0xc3, 0x19, 0xb2, 0xd2, 0x31, 0x07, 0xba, 0x67, 0xf9, 0x4f, 0x6f, 0xa9,
-	0xfb, 0x22, 0x88, 0xe3, 0x1a, 0xf8, 0x9c, 0x73, 0xb6, 0xa4, 0x76, 0xb6, 0x56, 0xea, 0x7f, 0x82,
-	0x9d, 0x3b, 0x88, 0x58, 0xe6, 0x05, 0x2e, 0xd5, 0x86, 0x45, 0x48, 0xfe, 0x07, 0xe3, 0x32, 0x9c,
-	0x57, 0xe8, 0xb4, 0x65, 0xad, 0x4e, 0x3e, 0xb6, 0xdf, 0x6b, 0xee, 0x6b, 0x30, 0x9b, 0x0d, 0x90,
-	0x2d, 0x30, 0x8e, 0x4f, 0x4f, 0x83, 0xfd, 0x5e, 0xab, 0x09, 0xfd, 0x9e, 0x46, 0x4c, 0xd8, 0x38,
-	0xa2, 0xc1, 0xa4, 0xa7, 0xbb, 0x63, 0x30, 0x1b, 0x2b, 0x84, 0xc0, 0x86, 0xf4, 0x29, 0x0e, 0xb1,
-	0xa9, 0x8c, 0x9b, 0x73, 0xdb, 0xb2, 0x24, 0x42, 0xf7, 0x09, 0xe8, 0x53, 0x5c, 0x8a, 0x5b, 0x5f,
-	0x30, 0xfc, 0x91, 0x5e, 0x2b, 0x5c, 0x65, 0xee, 0x1b, 0x68, 0x4f, 0xcf, 0xd6, 0xe5, 0xda, 0xf7,
-	0xc8, 0xb5, 0x95, 0x5c, 0x37, 0x02, 0x08, 0x58, 0x71, 0xbd, 0x14, 0x17, 0x8b, 0xc4, 0x03, 0x53,
-	0x6d, 0xb3, 0x74, 0x34, 0xb9, 0x39, 0x7b, 0x7d, 0x73, 0xf4, 0xa6, 0x2b, 0x4e, 0x57, 0xef, 0xa3,
-	0x76, 0xaf, 0x32, 0x69, 0xa1, 0x98, 0xa1, 0x7c, 0x59, 0x06, 0x95, 0xb1, 0xfb, 0x0e, 0xcc, 0xaf,
-	0x0b, 0x8a, 0x65, 0x35, 0xe7, 0x37, 0x7d, 0x6d, 0xd5, 0x7f, 0xe8, 0x5b, 0xee, 0x0b, 0xb0, 0xa5,
-	0x2c, 0x8a, 0xbf, 0x2a, 0x2c, 0xf9, 0x43, 0xef, 0xdc, 0xff, 0xa3, 0x81, 0x21, 0x4d, 0x90, 0x3d,
-	0x30, 0x6a, 0x23, 0x3b, 0x52, 0xf6, 0xfa, 0x74, 0x7f, 0x5b, 0x96, 0x56, 0x66, 0xdd, 0x16, 0xd9,
-	0x05, 0x3d, 0xa8, 0x38, 0xb9, 0xe5, 0xb1, 0x5f, 0x3f, 0xaf, 0x46, 0xb0, 0xdb, 0x22, 0x2f, 0xa1,
-	0x43, 0x31, 0x2b, 0x2e, 0xf1, 0x5f, 0xe0, 0x2b, 0xb0, 0x82, 0x8a, 0x4f, 0xcf, 0x4e, 0x38, 0xc3,
-	0x30, 0x23, 0x9b, 0xb2, 0x3f, 0x3d, 0xbb, 0x03, 0x7a, 0x1a, 0xd9, 0x05, 0xeb, 0x08, 0x57, 0xa8,
-	0x59, 0xa3, 0xb8, 0xec, 0x37, 0x43, 0x6e, 0x6b, 0xac, 0x1d, 0x1e, 0x7c, 0xdf, 0xff, 0x99, 0xf2,
-	0xa4, 0x8a, 0x86, 0x71, 0x91, 0x8d, 0xc2, 0xfc, 0x3a, 0x2d, 0xaa, 0x32, 0x2b, 0x66, 0xc8, 0xf2,
-	0x2c, 0xcc, 0x47, 0x71, 0xb1, 0x17, 0x27, 0x61, 0xca, 0x46, 0xf5, 0x1f, 0xa2, 0x44, 0x76, 0x89,
-	0x2c, 0xea, 0xc8, 0xec, 0xe0, 0x6f, 0x00, 0x00, 0x00, 0xff, 0xff, 0xbe, 0xe1, 0xb1, 0xe2, 0x38,
-	0x04, 0x00, 0x00,
+	// 2666 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xa4, 0x59, 0xef, 0x72, 0xdb, 0xc6,
+	0xb5, 0x17, 0x48, 0x91, 0x22, 0x0f, 0x49, 0x91, 0x5a, 0xc5, 0x31, 0x2f, 0xf3, 0xe7, 0xea, 0x22,
+	0xbe, 0x89, 0x9c, 0x1b, 0xcb, 0x96, 0x3c, 0xf1, 0x8d, 0xed, 0x4e, 0x3b, 0x32, 0xad, 0x38, 0x1a,
+	0xc5, 0x36, 0xbb, 0xd2, 0x78, 0x92, 0xce, 0x74, 0x30, 0x20, 0xb0, 0x22, 0x51, 0x81, 0x00, 0xba,
+	0x58, 0xc8, 0x62, 0x1e, 0xa2, 0xfd, 0xd2, 0x3e, 0x44, 0xbf, 0xf5, 0x15, 0x3a, 0x9d, 0xc9, 0xc7,
+	0x3e, 0x49, 0x1f, 0xa2, 0xb3, 0x67, 0x77, 0x09, 0x80, 0x92, 0xa2, 0xfc, 0xf9, 0xa4, 0x3d, 0xbf,
+	0x3d, 0x8b, 0xf3, 0x67, 0x7f, 0x7b, 0xf6, 0x2c, 0x05, 0xdd, 0x84, 0xc7, 0x22, 0xbe, 0xff, 0x96,
+	0x8d, 0x77, 0x70, 0x44, 0xaa, 0x6f, 0xd9, 0xd8, 0xfe, 0xeb, 0x1a, 0xac, 0x3d, 0x73, 0xbd, 0x33,
+	0x16, 0xf9, 0xe4, 0x5d, 0xa8, 0xfb, 0xf1, 0xcc, 0x0d, 0xa2, 0xbe, 0xb5, 0x65, 0x6d, 0x37, 0xa9,
+	0x96, 0x48, 0x0f, 0xaa, 0x41, 0x92, 0xf6, 0x2b, 0x5b, 0xd5, 0xed, 0x26, 0x95, 0x43, 0xf2, 0x3f,
+	0xd0, 0x9e, 0x32, 0x37, 0x14, 0x53, 0xc7, 0x9b, 0x32, 0xef, 0xac, 0x5f, 0x45, 0xfd, 0x96, 0xc2,
+	0x86, 0x12, 0x22, 0x1f, 0x41, 0x47, 0xab, 0xa4, 0xc2, 0x15, 0x59, 0xda, 0x5f, 0x45, 0x1d, 0xbd,
+	0xee, 0x18, 0x31, 0xb2, 0x0b, 0x0d, 0xf4, 0xc5, 0x8b, 0xc3, 0x7e, 0x6d, 0xcb, 0xda, 0x5e, 0xdf,
+	0xbb, 0xb5, 0x23, 0x1d, 0xd4, 0x1e, 0xed, 0x8c, 0xf4, 0x24, 0x5d, 0xa8, 0x91, 0x3d, 0xe8, 0x04,
+	0x91, 0x60, 0x3c, 0x62, 0xc2, 0xf1, 0x18, 0x17, 0xfd, 0xfa, 0x96, 0xb5, 0xdd, 0xda, 0xeb, 0xe0,
+	0xba, 0x6f, 0x3e, 0x7f, 0xf0, 0x78, 0xc8, 0xb8, 0xa0, 0x6d, 0xa3, 0x23, 0x25, 0xf2, 0x00, 0xda,
+	0x63, 0xf5, 0x45, 0xb5, 0x64, 0xed, 0xaa, 0x25, 0x2d, 0xad, 0x82, 0x2b, 0x86, 0xd0, 0x99, 0xb9,
+	0xc2, 0x9b, 0x3a, 0x53, 0xe6, 0xfa, 0x8c, 0xa7, 0xfd, 0xc6, 0x56, 0x75, 0xbb, 0xb5, 0xf7, 0x61,
+	0xc9, 0xbb, 0x97, 0x52, 0xe3, 0x2b, 0xa5, 0x70, 0x10, 0x09, 0x3e, 0xa7, 0xed, 0x59, 0x01, 0x22,
+	0xef, 0x41, 0x73, 0xe6, 0x5e, 0x38, 0x5e, 0x1c, 0x45, 0x69, 0xbf, 0xb9, 0x65, 0x6d, 0xd7, 0x68,
+	0x63, 0xe6, 0x5e, 0x0c, 0xa5, 0x4c, 0xfe, 0x1b, 0x5a, 0x5e, 0x18, 0xb0, 0x48, 0x38, 0xdc, 0x15,
+	0xac, 0x0f, 0x5b, 0xd6, 0xb6, 0x45, 0x41, 0x41, 0xd4, 0x15, 0x4c, 0xe6, 0x58, 0x2b, 0x8c, 0x33,
+	0x9e, 0x8a, 0x7e, 0x0b, 0x3f, 0xa0, 0x17, 0x3d, 0x93, 0x90, 0xfc, 0x86, 0x1b, 0x86, 0xf1, 0x5b,
+	0xc7, 0x0b, 0x7c, 0x9e, 0xf6, 0xdb, 0xb8, 0x41, 0x80, 0xd0, 0x50, 0x22, 0xe4, 0x03, 0x00, 0x9f,
+	0x45, 0x73, 0x3d, 0xdf, 0xc1, 0xf9, 0xa6, 0x44, 0xd4, 0xf4, 0x83, 0x85, 0x0f, 0x6e, 0x26, 0xa6,
+	0xfd, 0x75, 0x4c, 0x4b, 0x17, 0x63, 0x1c, 0x22, 0xbe, 0x9f, 0x89, 0xa9, 0x71, 0x4a, 0x8e, 0x09,
+	0x81, 0x55, 0xd7, 0x9b, 0xb1, 0x7e, 0x77, 0xcb, 0xda, 0x6e, 0x50, 0x1c, 0x93, 0x1d, 0x68, 0xb1,
+	0x0b, 0xc1, 0x5d, 0xcc, 0x6d, 0xda, 0xef, 0x6d, 0x55, 0x2f, 0x27, 0x17, 0x50, 0x43, 0x0e, 0x53,
+	0x72, 0x0f, 0x40, 0x84, 0xa9, 0x93, 0xc4, 0x61, 0xe0, 0xcd, 0xfb, 0x1b, 0x68, 0x74, 0x1d, 0xd5,
+	0x4f, 0xbe, 0x3e, 0x1e, 0x21, 0x4a, 0x9b, 0x22, 0x4c, 0xd5, 0x90, 0x0c, 0xa0, 0xc1, 0xd9, 0x79,
+	0x90, 0x06, 0x71, 0xd4, 0x27, 0x5b, 0xd6, 0x76, 0x95, 0x2e, 0x64, 0xf2, 0x00, 0xea, 0xa1, 0x3b,
+	0x66, 0x61, 0xda, 0xdf, 0x44, 0xab, 0xfd, 0xd2, 0xfe, 0x7c, 0x8d, 0x53, 0x6a, 0x67, 0xb4, 0xde,
+	0xe0, 0x37, 0xb0, 0x71, 0x69, 0xdb, 0x24, 0xc1, 0xcf, 0xd8, 0x5c, 0xb3, 0x5e, 0x0e, 0xc9, 0x3b,
+	0x50, 0x3b, 0x77, 0xc3, 0x8c, 0xf5, 0x2b, 0x88, 0x29, 0xe1, 0x49, 0xe5, 0x0b, 0x6b, 0xf0, 0x18,
+	0x5a, 0x85, 0xef, 0xfe, 0x94, 0xa5, 0xf6, 0xa7, 0xd0, 0x30, 0x84, 0x26, 0x4d, 0xa8, 0x7d, 0x75,
+	0x72, 0x32, 0xda, 0xed, 0xad, 0x98, 0xe1, 0x5e, 0xcf, 0x22, 0x0d, 0x58, 0x7d, 0x41, 0x47, 0xc3,
+	0x5e, 0xd5, 0xfe, 0xa7, 0x05, 0xcd, 0x45, 0x3a, 0xe4, 0x46, 0xcf, 0x82, 0xc8, 0x39, 0x67, 0x1c,
+	0xd3, 0xa0, 0xac, 0xc1, 0x2c, 0x88, 0xde, 0x28, 0x44, 0x9e, 0x36, 0x2f, 0x48, 0xa6, 0x8c, 0x3b,
+	0x69, 0x16, 0x08, 0x66, 0x0e, 0x6b, 0x5b, 0x81, 0xc7, 0x88, 0xc9, 0xf3, 0xed, 0x65, 0xfc, 0x9c,
+	0xa5, 0xfd, 0x2a, 0xce, 0x6a, 0x89, 0x6c, 0x41, 0x7b, 0x9a, 0x8a, 0xd4, 0x91, 0x64, 0x75, 0x27,
+	0x0c, 0x4f, 0x6a, 0x95, 0x82, 0xc4, 0x5e, 0xba, 0x17, 0xfb, 0x13, 0x46, 0x1e, 0xc1, 0x6d, 0xd4,
+	0x08, 0x22, 0x2f, 0xcc, 0x7c, 0xe6, 0xa4, 0xd9, 0x58, 0xd5, 0x86, 0x14, 0x8f, 0x6d, 0x83, 0xde,
+	0x92, 0xd3, 0x87, 0x6a, 0xf6, 0x78, 0x31, 0x69, 0xff, 0xdd, 0x02, 0xc8, 0x99, 0x24, 0x0f, 0x84,
+	0xe7, 0x3a, 0xe3, 0x2c, 0xf2, 0x43, 0x86, 0x41, 0xb4, 0x69, 0xc3, 0x73, 0x9f, 0xa1, 0x2c, 0x43,
+	0x40, 0xe6, 0x32, 0xdf, 0x89, 0xdc, 0x59, 0x1e, 0x82, 0x06, 0x5f, 0x49, 0x8c, 0xec, 0xc2, 0xda,
+	0x69, 0xcc, 0xdf, 0xba, 0xdc, 0xc7, 0x9a, 0xb3, 0xbe, 0x77, 0x7b, 0x89, 0xad, 0x3b, 0x5f, 0xaa,
+	0x69, 0x6a, 0xf4, 0xec, 0x7b, 0xb0, 0xa6, 0x31, 0x99, 0xde, 0x57, 0xaf, 0x5f, 0x1d, 0xf4, 0x56,
+	0x08, 0x40, 0xfd, 0xab, 0x83, 0xfd, 0xe7, 0x07, 0xb4, 0x67, 0x91, 0x36, 0x34, 0x46, 0xf4, 0xf5,
+	0x37, 0xdf, 0x3a, 0x6f, 0xf6, 0x7a, 0x15, 0xfb, 0x01, 0x34, 0x0c, 0x6b, 0x25, 0xdb, 0xb1, 0x5e,
+	0x28, 0x57, 0x71, 0x6c, 0x36, 0xbc, 0x82, 0x90, 0x1c, 0xda, 0x1f, 0x40, 0xf5, 0x88, 0xcd, 0x65,
+	0x76, 0x13, 0xce, 0x4e, 0x83, 0x0b, 0xad, 0xae, 0x25, 0xfb, 0x33, 0xa8, 0x1c, 0xbd, 0x29, 0xf2,
+	0xa4, 0x7d, 0x05, 0x4f, 0xda, 0x9a, 0x27, 0xf6, 0xdf, 0x2c, 0x80, 0x11, 0x8f, 0x2f, 0xe6, 0xb2,
+	0x42, 0x32, 0xb2, 0x0d, 0x0d, 0x5d, 0x96, 0xd2, 0xbe, 0x85, 0x14, 0x6f, 0x17, 0x29, 0x4e, 0x17,
+	0xb3, 0xd2, 0xbc, 0x2e, 0xb4, 0x8a, 0x77, 0x5a, 0xc2, 0x18, 0x62, 0x9f, 0x61, 0xba, 0x6a, 0x14,
+	0xc7, 0xe4, 0x63, 0xe8, 0x46, 0xec, 0x42, 0x38, 0x89, 0x3b, 0x61, 0x8e, 0x88, 0xcf, 0x58, 0xa4,
+	0xab, 0x73, 0x47, 0xc2, 0x23, 0x77, 0xc2, 0x4e, 0x24, 0x28, 0xcb, 0x87, 0x88, 0x85, 0x1b, 0x3a,
+	0x69, 0xf0, 0x1d, 0xc3, 0x9d, 0xae, 0xd1, 0x26, 0x22, 0xc7, 0xc1, 0x77, 0xcc, 0x7e, 0x04, 0x8d,
+	0xd7, 0x09, 0x65, 0x69, 0x16, 0x8a, 0x85, 0x19, 0xab, 0x60, 0xe6, 0x1a, 0x97, 0xec, 0xbf, 0xac,
+	0x42, 0x1b, 0xc3, 0xa3, 0xec, 0x8f, 0x19, 0x4b, 0xc5, 0xb5, 0x17, 0xcf, 0x7b, 0xd0, 0x44, 0x17,
+	0xd1, 0x7c, 0x45, 0x15, 0x50, 0x09, 0x48, 0xeb, 0xd2, 0xb9, 0x82, 0xff, 0xea, 0x06, 0x6a, 0x26,
+	0x0b, 0xdf, 0x1f, 0x42, 0xd3, 0xdc, 0x19, 0xf2, 0xee, 0xa9, 0x5e, 0x7f, 0xb7, 0xe4, 0x7a, 0x97,
+	0x2f, 0xad, 0xda, 0x15, 0x97, 0xd6, 0xff, 0xc2, 0xba, 0xf2, 0xcf, 0x49, 0x5c, 0x21, 0x6f, 0x19,
+	0xbc, 0x82, 0x9a, 0xb4, 0xa3, 0xd0, 0x91, 0x02, 0xc9, 0x3a, 0x54, 0x82, 0x04, 0xaf, 0x9a, 0x26,
+	0xad, 0x04, 0x09, 0xf9, 0x7c, 0x51, 0xab, 0xd4, 0x5d, 0xf2, 0x01, 0x7a, 0x53, 0xcc, 0xc3, 0x55,
+	0x05, 0x8b, 0xec, 0xc0, 0xa6, 0xe4, 0x9d, 0xc3, 0x2e, 0x92, 0x80, 0xb3, 0xd4, 0x19, 0xb3, 0xd3,
+	0x98, 0x33, 0xbc, 0x4e, 0xaa, 0x74, 0x43, 0x4e, 0x1d, 0xa8, 0x99, 0x67, 0x38, 0x41, 0x3e, 0x85,
+	0xd5, 0x34, 0xe6, 0x02, 0x2f, 0x94, 0xf5, 0xbd, 0x77, 0x2f, 0x1b, 0x39, 0x8e, 0xb9, 0xa0, 0xa8,
+	0x43, 0x3e, 0x94, 0xd7, 0x43, 0xea, 0xb1, 0xc8, 0x0f, 0xa2, 0x09, 0x5e, 0x30, 0x0d, 0x5a, 0x40,
+	0x7e, 0x49, 0xad, 0xdb, 0x85, 0x55, 0x69, 0x48, 0x1e, 0xb4, 0xe7, 0xaf, 0x5f, 0xee, 0x1f, 0xbe,
+	0xea, 0xad, 0xc8, 0x83, 0x46, 0x0f, 0xde, 0x1c, 0x1e, 0x1f, 0xbe, 0x7e, 0xd5, 0xb3, 0x48, 0x17,
+	0x5a, 0xc3, 0x03, 0x7a, 0xe2, 0x1c, 0x7c, 0x33, 0x3a, 0xa4, 0xdf, 0xf6, 0x2a, 0x76, 0x17, 0x3a,
+	0x34, 0xce, 0x04, 0x4b, 0xb5, 0xa7, 0xf6, 0x23, 0x00, 0x04, 0x4e, 0xdc, 0x71, 0xf8, 0x13, 0x8e,
+	0x82, 0xdd, 0x82, 0xe6, 0x70, 0xdf, 0x7c, 0x64, 0x0f, 0xea, 0xc3, 0xfd, 0xc3, 0xe8, 0x34, 0x96,
+	0x2c, 0x2b, 0x95, 0x1e, 0x2d, 0xc9, 0xb0, 0x3c, 0x1e, 0x9a, 0x13, 0xed, 0xf1, 0xd0, 0xb6, 0xa1,
+	0x7d, 0x98, 0xa6, 0xd9, 0x82, 0x9f, 0x04, 0x56, 0x65, 0x49, 0xd2, 0x91, 0xe3, 0xd8, 0xfe, 0x04,
+	0x3a, 0x94, 0x9d, 0xc7, 0x67, 0x45, 0x12, 0xa7, 0x8c, 0x07, 0x6e, 0x68, 0x48, 0xac, 0x24, 0xfb,
+	0x16, 0x6c, 0xca, 0x62, 0x12, 0x9c, 0x06, 0x9e, 0x5b, 0x08, 0xee, 0x33, 0x20, 0x05, 0xf8, 0x86,
+	0x93, 0x60, 0x7f, 0x5f, 0x81, 0x6e, 0x41, 0xdd, 0xc4, 0x73, 0xe5, 0xa9, 0xe9, 0xc3, 0x5a, 0x9a,
+	0x8d, 0xff, 0xc0, 0x3c, 0xa1, 0xb7, 0xc5, 0x88, 0x32, 0x8e, 0xd4, 0x8d, 0x4c, 0xf9, 0xc7, 0xb1,
+	0xfc, 0x4a, 0x20, 0x63, 0xe5, 0xba, 0x04, 0x68, 0x49, 0x1e, 0xaf, 0x28, 0x16, 0x86, 0x6e, 0x35,
+	0xa4, 0x5b, 0x33, 0x8a, 0x85, 0xa6, 0xd9, 0x7b, 0x20, 0x05, 0xc7, 0x3d, 0x15, 0x8c, 0x23, 0xff,
+	0xab, 0xb4, 0x11, 0xc5, 0x62, 0x5f, 0xca, 0xe4, 0xbf, 0xa0, 0x71, 0xc6, 0xe6, 0x8e, 0x98, 0x27,
+	0x4c, 0x1f, 0x80, 0xb5, 0x33, 0x36, 0x3f, 0x99, 0x27, 0x8c, 0x6c, 0x41, 0xeb, 0x34, 0x88, 0x26,
+	0x8c, 0x27, 0x3c, 0x88, 0x44, 0xbf, 0x81, 0xb3, 0x45, 0xa8, 0x90, 0xc7, 0x66, 0x31, 0x8f, 0x88,
+	0xc7, 0x19, 0xf7, 0x54, 0xaf, 0xd4, 0xa4, 0x5a, 0x92, 0x1c, 0x64, 0x9c, 0xc7, 0x1c, 0xf9, 0xdb,
+	0xa4, 0x4a, 0xd0, 0xb5, 0x67, 0xc2, 0xfc, 0x7e, 0x1b, 0x69, 0xad, 0x25, 0xfb, 0xa8, 0x94, 0xc7,
+	0xaf, 0x83, 0x54, 0x90, 0x2f, 0xa0, 0xed, 0xe5, 0x90, 0x21, 0xd7, 0x3b, 0xea, 0x62, 0x29, 0xe7,
+	0x9c, 0x96, 0x34, 0xed, 0x23, 0xac, 0x63, 0x93, 0x1b, 0xeb, 0xd8, 0x47, 0x50, 0x53, 0xbd, 0x51,
+	0xe5, 0xaa, 0xde, 0x48, 0xcd, 0xd9, 0xdb, 0xb0, 0x3e, 0xe2, 0xf1, 0x2c, 0xbe, 0x99, 0x0c, 0x9b,
+	0xb0, 0x71, 0x12, 0x78, 0x67, 0x4c, 0x1c, 0xb1, 0xf9, 0x82, 0x4f, 0x5b, 0x00, 0x39, 0x28, 0x77,
+	0xfa, 0x8c, 0xcd, 0x55, 0x2c, 0x6d, 0x8a, 0x63, 0xfb, 0x04, 0x7a, 0xea, 0x9e, 0x3c, 0x62, 0xf3,
+	0x1f, 0x60, 0xb6, 0xc4, 0x78, 0x1c, 0x9a, 0x33, 0x8d, 0x63, 0xc9, 0x29, 0x73, 0xe1, 0x2b, 0xf2,
+	0x18, 0x51, 0x3a, 0xb3, 0xf8, 0xea, 0xc2, 0x99, 0xef, 0x2d, 0x68, 0x2e, 0xd0, 0x2b, 0x8d, 0xf4,
+	0xa0, 0x9a, 0x64, 0x63, 0x6d, 0x43, 0x0e, 0xa5, 0x56, 0xc2, 0x83, 0x73, 0x5d, 0xc9, 0x71, 0x2c,
+	0x49, 0x98, 0x32, 0x7e, 0xce, 0xb8, 0x23, 0x95, 0x15, 0x41, 0x9b, 0x0a, 0x19, 0x65, 0x63, 0xe9,
+	0x95, 0xc7, 0x99, 0x2b, 0x98, 0xaf, 0x09, 0x6a, 0x44, 0x39, 0xc3, 0xf1, 0x74, 0xfa, 0x9a, 0x9c,
+	0x46, 0x5c, 0x44, 0xb7, 0x76, 0x75, 0x74, 0x8d, 0x72, 0x74, 0x0f, 0xa1, 0xb3, 0x88, 0x03, 0xc9,
+	0x62, 0x17, 0x12, 0x6b, 0xda, 0xd6, 0x3c, 0xab, 0x2a, 0xd1, 0x9b, 0xb0, 0x71, 0x8c, 0x3e, 0x16,
+	0x53, 0xf2, 0x7b, 0x80, 0x1c, 0x44, 0xcf, 0x33, 0xce, 0x59, 0x24, 0x74, 0x56, 0x8c, 0x88, 0xc9,
+	0x62, 0x17, 0xe6, 0xe8, 0xe2, 0x58, 0x3e, 0x05, 0xe4, 0x5f, 0xc7, 0x04, 0x5b, 0xc5, 0x90, 0x5a,
+	0x12, 0x1b, 0x2a, 0xc8, 0x9e, 0x42, 0x7b, 0x3f, 0xf3, 0x03, 0x51, 0xe0, 0x8e, 0x70, 0xf9, 0x84,
+	0x99, 0xef, 0x6b, 0x49, 0x9e, 0x16, 0xd7, 0x13, 0x31, 0x37, 0x15, 0x1b, 0x05, 0x89, 0xa6, 0x41,
+	0xe4, 0x31, 0xfd, 0x65, 0x25, 0x48, 0x34, 0x0c, 0x66, 0x81, 0xc0, 0xc4, 0xd7, 0xa8, 0x12, 0xec,
+	0x7f, 0x59, 0x00, 0x68, 0xea, 0xe0, 0x5c, 0xfa, 0x2b, 0xaf, 0x39, 0x1f, 0x8d, 0x54, 0x69, 0x25,
+	0xc0, 0xfc, 0x8a, 0x60, 0xa6, 0xd8, 0x53, 0xa5, 0x38, 0xce, 0x8d, 0x56, 0x8b, 0x46, 0xcd, 0x4e,
+	0xac, 0x16, 0x76, 0xe2, 0x5d, 0xa8, 0xcf, 0x98, 0x98, 0xc6, 0xbe, 0xbe, 0x79, 0xb5, 0x54, 0x08,
+	0xa7, 0x5e, 0x0a, 0x27, 0x2f, 0x0a, 0x6b, 0xa5, 0xa2, 0xf0, 0x29, 0xac, 0x79, 0x53, 0x37, 0x9a,
+	0x30, 0x73, 0xdb, 0xf6, 0x70, 0xa7, 0xbe, 0x0c, 0x58, 0xe8, 0x0f, 0x71, 0x82, 0x1a, 0x05, 0xfb,
+	0xb7, 0xd0, 0x2a, 0xe0, 0xd2, 0xd9, 0x53, 0x29, 0xea, 0xc4, 0x29, 0x01, 0x2f, 0x0f, 0x55, 0x0a,
+	0x75, 0x2f, 0xa3, 0x24, 0x0c, 0x0d, 0x6b, 0xa0, 0x09, 0x4d, 0x0a, 0xf6, 0x63, 0x58, 0xcf, 0x53,
+	0x84, 0xbc, 0xf9, 0x04, 0xea, 0x4c, 0x0a, 0x86, 0x39, 0xea, 0x95, 0x95, 0x2b, 0x51, 0x3d, 0x6d,
+	0x6f, 0x43, 0x8f, 0xea, 0xe7, 0x8d, 0xe1, 0x4e, 0xbe, 0x11, 0x56, 0x71, 0x23, 0xfe, 0x64, 0x41,
+	0xc3, 0xa8, 0xfe, 0x82, 0x6d, 0xc8, 0x53, 0xbe, 0x7a, 0x4d, 0xca, 0x6b, 0xa5, 0x94, 0x17, 0x0e,
+	0x4b, 0x1d, 0xdd, 0x31, 0xa2, 0xfd, 0x14, 0xda, 0xc6, 0x1f, 0x8c, 0xf9, 0xff, 0xa0, 0x69, 0x5e,
+	0x6a, 0x26, 0x6c, 0x55, 0xfa, 0x8c, 0x16, 0xcd, 0xe7, 0xed, 0x5d, 0x68, 0x3d, 0x0f, 0x4e, 0x4f,
+	0x0b, 0x85, 0xe9, 0x94, 0xc7, 0x33, 0x1d, 0x11, 0x8e, 0x65, 0x8c, 0x22, 0xd6, 0x11, 0x55, 0x44,
+	0x6c, 0x8f, 0x00, 0x9e, 0xa3, 0x69, 0xb9, 0xf0, 0xda, 0xe2, 0x5b, 0xa0, 0x42, 0xe5, 0x26, 0x2a,
+	0x3c, 0xce, 0x23, 0xc0, 0x6f, 0xde, 0xcd, 0x63, 0x2d, 0x6e, 0x5b, 0x6e, 0x35, 0x0f, 0xfe, 0x1e,
+	0x74, 0x69, 0x1c, 0x86, 0xb2, 0x09, 0x31, 0x31, 0x14, 0x5f, 0xae, 0x56, 0xf9, 0xe5, 0x6a, 0x4f,
+	0xa0, 0xbd, 0x9f, 0x24, 0xe1, 0xa2, 0x10, 0xff, 0xf8, 0x46, 0xff, 0x1d, 0xa8, 0x25, 0x3c, 0x8b,
+	0xd4, 0xd6, 0x36, 0xa8, 0x12, 0xc8, 0x6d, 0x58, 0xf3, 0xf9, 0xdc, 0xe1, 0x99, 0x6a, 0x85, 0x1b,
+	0xb4, 0xee, 0xf3, 0x39, 0xcd, 0x22, 0xfb, 0x04, 0x5a, 0xda, 0x10, 0xf6, 0xe9, 0x77, 0xf3, 0x6c,
+	0x5c, 0x17, 0x91, 0x9e, 0x2f, 0xb9, 0x5f, 0x59, 0x72, 0xff, 0x3e, 0x74, 0x0e, 0x2e, 0x12, 0xd9,
+	0x49, 0x6a, 0xff, 0x3f, 0x94, 0xdd, 0x78, 0x9a, 0x26, 0x53, 0xee, 0xa6, 0xa6, 0xd2, 0x17, 0x10,
+	0xfb, 0x0e, 0xac, 0x0f, 0xe3, 0xe8, 0x34, 0x98, 0x3c, 0x8f, 0xbd, 0x6c, 0xa6, 0x0b, 0x9d, 0xef,
+	0x0a, 0xd7, 0x3c, 0xae, 0xe4, 0xd8, 0xfe, 0x0e, 0x3a, 0x87, 0xb3, 0xe2, 0x67, 0xaf, 0x50, 0x5a,
+	0x32, 0x55, 0x59, 0x36, 0x45, 0xde, 0x87, 0x66, 0x7c, 0xce, 0xf8, 0x5b, 0x1e, 0x08, 0xa6, 0x93,
+	0x91, 0x03, 0xc5, 0x44, 0xad, 0x96, 0x12, 0xf5, 0x67, 0x0b, 0xda, 0xc6, 0x38, 0xa6, 0xea, 0x7d,
+	0x68, 0x7a, 0x71, 0x74, 0x1a, 0x06, 0x9e, 0x3e, 0xb5, 0x4d, 0x9a, 0x03, 0xe4, 0xee, 0x32, 0xad,
+	0x7e, 0x30, 0x91, 0x6f, 0x5d, 0x1e, 0x05, 0xd1, 0xc4, 0xdc, 0x9e, 0x0b, 0xb9, 0x94, 0xe4, 0xd5,
+	0xa5, 0x24, 0xff, 0xc3, 0x82, 0xf6, 0x48, 0xfe, 0x58, 0x71, 0x53, 0x7f, 0x71, 0x07, 0xea, 0xd8,
+	0x79, 0xab, 0x87, 0xd6, 0x32, 0x75, 0xf4, 0x9c, 0xfc, 0x11, 0x21, 0x4b, 0x7c, 0x57, 0x30, 0x67,
+	0xe6, 0xa6, 0x67, 0xda, 0x13, 0x50, 0xd0, 0x4b, 0x37, 0x3d, 0x93, 0xa9, 0x71, 0x7d, 0xdf, 0x09,
+	0x12, 0xf5, 0x60, 0x6a, 0xd2, 0xba, 0xeb, 0xfb, 0x87, 0x09, 0xfe, 0x8c, 0xc4, 0xd9, 0x2c, 0x3e,
+	0x67, 0x38, 0x57, 0x53, 0xa9, 0x50, 0xc8, 0x61, 0x52, 0x8e, 0xa1, 0x5e, 0x8e, 0x61, 0xef, 0xdf,
+	0x6d, 0xa8, 0xe1, 0x7b, 0x96, 0xdc, 0x83, 0x9a, 0x7a, 0xd3, 0x6e, 0x5c, 0x7a, 0x93, 0x0c, 0x54,
+	0xee, 0xf2, 0x77, 0xaf, 0xbd, 0x42, 0xee, 0x40, 0x75, 0x94, 0x09, 0x52, 0x0a, 0x65, 0xa0, 0xca,
+	0x87, 0x79, 0x74, 0xda, 0x2b, 0x64, 0x57, 0x67, 0x48, 0x2b, 0xe8, 0x6f, 0x17, 0x93, 0x36, 0x28,
+	0x7d, 0xc1, 0x5e, 0x91, 0x95, 0x98, 0xa2, 0xeb, 0x37, 0x7d, 0xfb, 0x2e, 0xb4, 0x46, 0x99, 0x38,
+	0x7a, 0x73, 0x2c, 0x38, 0x73, 0x67, 0x64, 0x0d, 0xe7, 0x8f, 0xde, 0x5c, 0x52, 0xdc, 0xb6, 0xc8,
+	0x1d, 0x68, 0xbd, 0x60, 0xb9, 0x6a, 0x43, 0xa9, 0xb2, 0xf9, 0xc0, 0x2c, 0xb2, 0x57, 0x1e, 0x58,
+	0xe4, 0x3e, 0xd4, 0xd5, 0x03, 0x87, 0x10, 0x84, 0x4b, 0xaf, 0x9d, 0x41, 0x37, 0xc7, 0xf0, 0xc1,
+	0x63, 0xaf, 0x90, 0x8f, 0xa1, 0xf6, 0x82, 0x89, 0xe1, 0x3e, 0xd1, 0x7d, 0x86, 0x79, 0xd4, 0x0c,
+	0x5a, 0x5a, 0x96, 0x3d, 0xa9, 0xbd, 0x42, 0x3e, 0x87, 0x2e, 0xbe, 0x57, 0x54, 0x23, 0x82, 0x3f,
+	0x5d, 0xa8, 0x44, 0x14, 0x5f, 0x31, 0x83, 0x72, 0xdb, 0x69, 0xaf, 0x90, 0x7b, 0x32, 0x13, 0xb2,
+	0x2b, 0x32, 0xfe, 0x14, 0xdf, 0x33, 0x97, 0xf3, 0xf1, 0x1c, 0x7a, 0xb2, 0xac, 0x17, 0x1f, 0x33,
+	0xa4, 0xbf, 0xdc, 0x25, 0x2f, 0xc2, 0xb9, 0xd4, 0x3f, 0xcb, 0xb5, 0xf6, 0x0a, 0xd9, 0x87, 0xf5,
+	0x17, 0xac, 0xf8, 0x11, 0x72, 0x7b, 0x59, 0xf3, 0xda, 0x4f, 0xe8, 0x70, 0xff, 0x1f, 0x36, 0xb0,
+	0xed, 0x2e, 0x79, 0xb2, 0x60, 0xd5, 0xe4, 0xfa, 0x08, 0x9e, 0xc2, 0xa6, 0x6e, 0xb1, 0x4b, 0x4b,
+	0x37, 0x0d, 0xfb, 0x0a, 0xcd, 0xf7, 0xe5, 0xc5, 0xbf, 0x96, 0x5d, 0x5d, 0x2a, 0x49, 0x5d, 0xe8,
+	0xb3, 0xd5, 0xfb, 0xfa, 0x52, 0x37, 0x3e, 0xe8, 0x2e, 0xe1, 0xf6, 0x0a, 0x79, 0x02, 0x5d, 0xd5,
+	0xac, 0xe5, 0x8d, 0xf1, 0xad, 0xa5, 0xf6, 0x51, 0x2f, 0x5e, 0xea, 0x2a, 0xd1, 0xf6, 0x3a, 0xa6,
+	0xde, 0x40, 0xc6, 0xf0, 0xa5, 0xce, 0x7b, 0x40, 0xca, 0xb8, 0x4e, 0xfa, 0x63, 0xe8, 0xaa, 0xcd,
+	0xbd, 0xd1, 0xf6, 0xa5, 0xb0, 0x9f, 0xc8, 0x7b, 0x4d, 0xfc, 0x3c, 0xb7, 0x9f, 0x42, 0xef, 0x98,
+	0xe5, 0x5e, 0x53, 0xd9, 0xe1, 0xfd, 0xe8, 0xc5, 0x4f, 0xa0, 0xf3, 0x82, 0x89, 0x42, 0xcf, 0xac,
+	0x7f, 0xcb, 0x58, 0xee, 0xac, 0x07, 0xdd, 0x25, 0xdc, 0x5e, 0x21, 0xbf, 0x32, 0x4e, 0x2f, 0xd0,
+	0x9f, 0xba, 0x9a, 0x89, 0x80, 0xff, 0xbc, 0xd5, 0x4f, 0xa1, 0x2b, 0xb3, 0x9e, 0xb7, 0x76, 0x86,
+	0x9b, 0xc5, 0xfe, 0x7c, 0xb0, 0xb9, 0xd4, 0xff, 0xe9, 0x8d, 0x7a, 0x0a, 0x1d, 0x39, 0x5a, 0x74,
+	0x80, 0x3a, 0x5d, 0xcb, 0x1d, 0xe1, 0x60, 0xa3, 0x04, 0xeb, 0xc5, 0x8f, 0xa0, 0xa3, 0x5a, 0x28,
+	0xb3, 0x58, 0x75, 0x3a, 0x85, 0xb6, 0x6a, 0x69, 0x9d, 0x9c, 0xc1, 0x22, 0xda, 0x30, 0xad, 0x0b,
+	0x51, 0x67, 0x6e, 0xa9, 0x93, 0xb9, 0xcc, 0x8a, 0x1d, 0xa8, 0x61, 0x57, 0x61, 0x42, 0x2b, 0xb4,
+	0x32, 0x83, 0x5e, 0x11, 0xd2, 0xfa, 0x0f, 0xa1, 0xae, 0xfa, 0x05, 0x5d, 0x6a, 0x4a, 0xcd, 0x83,
+	0x4e, 0x46, 0xb9, 0x3f, 0x40, 0xbf, 0xea, 0x87, 0xb3, 0xc2, 0xa2, 0x52, 0x6b, 0x30, 0xd8, 0x28,
+	0x61, 0xca, 0xce, 0xb3, 0x87, 0xbf, 0xdb, 0x9d, 0x04, 0x62, 0x9a, 0x8d, 0x77, 0xbc, 0x78, 0x76,
+	0xdf, 0x8d, 0x2e, 0x82, 0x38, 0x4b, 0x67, 0xb1, 0xcf, 0x78, 0x34, 0x73, 0xa3, 0xfb, 0x5e, 0x7c,
+	0xcf, 0x9b, 0xba, 0x01, 0xbf, 0xaf, 0xfe, 0x17, 0xa6, 0x5e, 0x92, 0xe3, 0x3a, 0x4a, 0x0f, 0xff,
+	0x33, 0x00, 0x11, 0x09, 0xdf, 0x22, 0x22, 0x1b, 0x00, 0x00,
 }
@@ -8,7 +8,20 @@ import './App.css';
 
 
 
-class AddBackendButton extends React.Component {
+// grpc-web puts a failed call's status code and message in its trailers;
+// show them, rather than failing silently.
+function describeError(status: grpc.Code, statusMessage: string): string {
+  return `${grpc.Code[status]}: ${statusMessage}`;
+}
+
+class AddBackendButton extends React.Component<{}, { error: string }> {
+  public state = { error: "" };
+
+  constructor(props: {}) {
+    super(props);
+    this.clicker = this.clicker.bind(this);
+  }
+
   clicker() {
     // we connect to the Web UI port
     // CORS PROBS...
@@ -22,15 +35,19 @@ class AddBackendButton extends React.Component {
         const { status, statusMessage, headers, message, trailers } = res;
         if (status === grpc.Code.OK && message) {
           console.log("props of message", message.toObject())
+          this.setState({ error: "" });
         } else {
-          console.log("not okay", res)
+          this.setState({ error: describeError(status, statusMessage) });
         }
       }
     });
   }
   public render() {
     return (
-      <button onClick={this.clicker}>Hello</button>
+      <div>
+        <button onClick={this.clicker}>Hello</button>
+        {this.state.error && <div className="error">{this.state.error}</div>}
+      </div>
     )
   }
 }
@@ -12,6 +12,7 @@ import (
 	"path/filepath"
 	"strings"
 
+	"github.com/anxiousmodernman/co-chair/backend"
 	"github.com/anxiousmodernman/co-chair/config"
 	"github.com/anxiousmodernman/co-chair/frontend/bundle"
 	"github.com/codegangsta/negroni"
@@ -257,6 +258,20 @@ func IsAuthenticated(w http.ResponseWriter, r *http.Request, next http.HandlerFu
 	}
 }
 
+// withWebUser passes the signed-in user's email to our gRPC handlers, which
+// authorize them by role. A header sent by the browser is dropped.
+func withWebUser(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	r.Header.Del(backend.WebUserHeader)
+	if session, err := Store.Get(r, "auth-session"); err == nil {
+		if profile, ok := session.Values["profile"].(map[string]interface{}); ok {
+			if email, ok := profile["email"].(string); ok {
+				r.Header.Set(backend.WebUserHeader, email)
+			}
+		}
+	}
+	next(w, r)
+}
+
 func withLog(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
 	logger.Debug("path:", r.URL.Path)
 	next(w, r)